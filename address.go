@@ -1,17 +1,66 @@
 package cohabitaters
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
-	"github.com/lithammer/fuzzysearch/fuzzy"
-	"google.golang.org/api/people/v1"
+	"github.com/bfallik/cohabitaters/normalize"
 )
 
 type XmasCard struct {
-	Names   []string
-	Address Address
+	Names []string
+	// Addressee is the line the card is addressed to, e.g.
+	// "John & Jane Smith", in the style chosen by Options.Salutation.
+	Addressee string
+	Address   Address
+	// AddressLines is Address formatted for a mailing label sent from
+	// Options.SenderCountry.
+	AddressLines []string
+
+	// Contacts lists the contacts the card was built from.
+	Contacts []CardContact
+	// Matches explains why those contacts were grouped together.
+	Matches []Match
+	// NearMisses are pairs involving this card's contacts that scored just
+	// below the merge threshold.
+	NearMisses []Match
+}
+
+// NeedsReview reports whether any of the card's merges, or near misses, were
+// close enough to the threshold that a person should double check them.
+func (c XmasCard) NeedsReview() bool {
+	if len(c.NearMisses) > 0 {
+		return true
+	}
+	for _, m := range c.Matches {
+		if m.Review {
+			return true
+		}
+	}
+	return false
+}
+
+// CardContact is a contact that contributed to an XmasCard.
+type CardContact struct {
+	ResourceName string
+	Name         string
+	GivenName    string
+	FamilyName   string
+	Address      Address
+	// Account is the account the contact came from, see Contact.Account.
+	Account string
+}
+
+// Match records how two contacts' addresses compared.
+type Match struct {
+	A, B   string // contact resource names
+	Score  Score
+	Reason string
+	Review bool // the score is within the review margin of the threshold
+	Manual bool // the user merged the pair by hand
 }
 
 type Address struct {
@@ -20,96 +69,142 @@ type Address struct {
 	City           string
 	Region         string
 	Country        string
+	CountryCode    string // ISO 3166-1 alpha-2, when known
 	PostalCode     string
 }
 
-func NewAddress(in *people.Address) Address {
+// Normalized returns a copy of a with every field in canonical form, suitable
+// both for comparison and for printing on an envelope.
+func (a Address) Normalized() Address {
 	return Address{
-		StreetAddress:  in.StreetAddress,
-		StreetAddress2: in.ExtendedAddress,
-		City:           in.City,
-		Region:         in.Region,
-		Country:        in.Country,
-		PostalCode:     in.PostalCode,
+		StreetAddress:  normalize.Street(a.StreetAddress),
+		StreetAddress2: normalize.Secondary(a.StreetAddress2),
+		City:           normalize.City(a.City),
+		Region:         normalize.Region(a.Region),
+		Country:        normalize.Text(a.Country),
+		CountryCode:    a.countryCode(),
+		PostalCode:     normalize.Text(a.PostalCode),
 	}
 }
 
-func PickHomeAddress(in []*people.Address) (*people.Address, error) {
-	switch {
-	case len(in) == 0:
-		return nil, nil
-	case len(in) == 1:
-		return in[0], nil
-	default:
-		for _, addr := range in {
-			if strings.ToLower(addr.Type) == "home" {
-				return addr, nil
-			}
-		}
-		return nil, fmt.Errorf("no home address")
+// countryCode returns the ISO code for a, deriving it from the country name
+// when the source didn't supply one.
+func (a Address) countryCode() string {
+	if len(a.CountryCode) > 0 {
+		return strings.ToUpper(a.CountryCode)
 	}
+	return normalize.CountryCode(a.Country)
 }
 
-func FuzzyTrimMatch(a, b string) bool {
-	return fuzzy.Match(strings.TrimSpace(a), strings.TrimSpace(b))
+var (
+	ErrEmptyGroup = errors.New("group is empty")
+)
+
+// Options tunes how GetXmasCards groups contacts into households.
+type Options struct {
+	Matcher Matcher
+	// Policy picks each contact's address; nil means a SelectionPolicy with
+	// no type order or overrides.
+	Policy AddressPolicy
+	// Salutation selects how each card's Addressee is written; the zero
+	// value means SalutationNames.
+	Salutation SalutationStyle
+	// Households are the user's manual corrections to matching.
+	Households HouseholdOverrides
+	// SenderCountry is the ISO 3166-1 alpha-2 code of the country cards are
+	// mailed from; see Address.Lines.
+	SenderCountry string
+	// Sort orders Result.Cards; the zero value keeps the order the
+	// households were found in.
+	Sort SortOrder
 }
 
-func FuzzyAddressMatch(a *people.Address, b Address) bool {
-	//	fmt.Println(a.City, b.City, fuzzyTrimMatch(a.City, b.City))
-	return FuzzyTrimMatch(a.City, b.City) &&
-		FuzzyTrimMatch(a.StreetAddress, b.StreetAddress)
+func (o Options) policy() AddressPolicy {
+	if o.Policy == nil {
+		return SelectionPolicy{}
+	}
+	return o.Policy
 }
 
-var (
-	ErrEmptyGroup = errors.New("group is empty")
+// SkipReason explains why a contact is missing from every card.
+type SkipReason string
+
+const (
+	SkipNoName           SkipReason = "no name"
+	SkipNoAddress        SkipReason = "no address"
+	SkipAmbiguousAddress SkipReason = "ambiguous address"
+	SkipAPIError         SkipReason = "API error"
 )
 
-func GetXmasCards(svc *people.Service, contactGroupResourceName string) ([]XmasCard, error) {
-	cgResp, err := svc.ContactGroups.Get(contactGroupResourceName).MaxMembers(1000).Do()
+// SkippedContact is a contact that was left off the cards.
+type SkippedContact struct {
+	ResourceName string
+	Name         string
+	Reason       SkipReason
+	// Detail elaborates on Reason, e.g. the error the source returned.
+	Detail string
+	// Addresses are the contact's candidate addresses, if any.
+	Addresses []ContactAddress
+}
+
+// Result is the outcome of coalescing a group of contacts.
+type Result struct {
+	Cards   []XmasCard
+	Skipped []SkippedContact
+	// Subtotals counts Cards by destination country.
+	Subtotals Subtotals
+}
+
+func GetXmasCards(ctx context.Context, src ContactSource, contactGroupResourceName string, opts Options) (Result, error) {
+	members, err := src.GroupMembers(ctx, contactGroupResourceName)
 	if err != nil {
-		return nil, fmt.Errorf("unable to retrieve contactGroup members: %w", err)
-	}
-	if len(cgResp.MemberResourceNames) == 0 {
-		return nil, ErrEmptyGroup
+		return Result{}, fmt.Errorf("unable to retrieve contactGroup members: %w", err)
 	}
+	return xmasCards(ctx, src, members, []string{contactGroupResourceName}, opts)
+}
 
-	pplResp, err := svc.People.GetBatchGet().ResourceNames(cgResp.MemberResourceNames...).PersonFields("names,addresses").Do()
+// GetXmasCardsForExpr coalesces the contacts a group expression selects.
+func GetXmasCardsForExpr(ctx context.Context, src ContactSource, expr *GroupExpr, opts Options) (Result, error) {
+	members, groups, err := expr.Members(ctx, src)
 	if err != nil {
-		return nil, fmt.Errorf("unable to retrieve people: %w", err)
+		return Result{}, err
 	}
-	if len(pplResp.Responses) == 0 {
-		return nil, fmt.Errorf("empty people responses")
+	return xmasCards(ctx, src, members, groups, opts)
+}
+
+// xmasCards fetches and coalesces the members of the given groups.
+func xmasCards(ctx context.Context, src ContactSource, members, groups []string, opts Options) (Result, error) {
+	if len(members) == 0 {
+		return Result{}, ErrEmptyGroup
 	}
 
-	var cards []XmasCard
+	contacts, err := src.Contacts(ctx, members)
+	if err != nil {
+		return Result{}, fmt.Errorf("unable to retrieve people: %w", err)
+	}
+	if len(contacts) == 0 {
+		return Result{}, fmt.Errorf("empty people responses")
+	}
 
-	for _, pr := range pplResp.Responses {
-		if len(pr.Person.Names) == 0 {
-			continue // ignore contacts without names
-		}
-		name := pr.Person.Names[0].DisplayName
-		found := false
-		homeAddr, err := PickHomeAddress(pr.Person.Addresses)
-		if err != nil {
-			return nil, fmt.Errorf("error picking home address for %s: %w", name, err)
-		}
-		if homeAddr == nil {
-			continue // unable to pick home address
-		}
+	returned := make(map[string]bool, len(contacts))
+	for i, c := range contacts {
+		returned[c.ResourceName] = true
 
-		for idx, card := range cards {
-			if FuzzyAddressMatch(homeAddr, card.Address) {
-				cards[idx].Names = append(cards[idx].Names, name)
-				found = true
+		// the groups being coalesced are how the contacts were picked, so
+		// they say nothing about who lives together
+		var memberships []string
+		for _, g := range c.Memberships {
+			if !slices.Contains(groups, g) {
+				memberships = append(memberships, g)
 			}
 		}
-		if !found {
-			cards = append(cards, XmasCard{
-				Names:   []string{name},
-				Address: NewAddress(homeAddr),
-			})
+		contacts[i].Memberships = memberships
+	}
+	for _, rn := range members {
+		if !returned[rn] {
+			contacts = append(contacts, Contact{ResourceName: rn, FetchError: "not returned by the contact source"})
 		}
 	}
 
-	return cards, nil
+	return Coalesce(contacts, opts)
 }
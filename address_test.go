@@ -0,0 +1,125 @@
+package cohabitaters
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type fakeSource struct {
+	groups  map[string][]string
+	people  map[string]Contact
+	listErr error
+}
+
+func (fs fakeSource) ContactGroups(ctx context.Context) ([]ContactGroup, error) {
+	var groups []ContactGroup
+	for rn, members := range fs.groups {
+		groups = append(groups, ContactGroup{ResourceName: rn, Name: rn, MemberCount: len(members)})
+	}
+	return groups, fs.listErr
+}
+
+func (fs fakeSource) GroupMembers(ctx context.Context, groupResourceName string) ([]string, error) {
+	if fs.listErr != nil {
+		return nil, fs.listErr
+	}
+	return fs.groups[groupResourceName], nil
+}
+
+func (fs fakeSource) Contacts(ctx context.Context, resourceNames []string) ([]Contact, error) {
+	var contacts []Contact
+	for _, rn := range resourceNames {
+		if c, ok := fs.people[rn]; ok {
+			contacts = append(contacts, c)
+		}
+	}
+	return contacts, nil
+}
+
+func homeContact(resourceName, name string, addr Address) Contact {
+	return Contact{
+		ResourceName: resourceName,
+		Names:        []Name{{DisplayName: name}},
+		Addresses:    []ContactAddress{{Address: addr, Type: "home"}},
+	}
+}
+
+var (
+	mainStreet = Address{StreetAddress: "123 Main Street", City: "Springfield", Region: "IL", PostalCode: "62701"}
+	elmStreet  = Address{StreetAddress: "9 Elm Street", City: "Shelbyville", Region: "IL", PostalCode: "62565"}
+)
+
+func TestAddressNormalized(t *testing.T) {
+	in := Address{
+		StreetAddress:  "123 North Main Street",
+		StreetAddress2: "Apartment #4",
+		City:           "Saint Louis",
+		Region:         "Missouri",
+		Country:        "USA",
+		PostalCode:     "63101-1234",
+	}
+	want := Address{
+		StreetAddress:  "123 N MAIN ST",
+		StreetAddress2: "APT 4",
+		City:           "ST LOUIS",
+		Region:         "MO",
+		Country:        "USA",
+		CountryCode:    "US",
+		PostalCode:     "63101-1234",
+	}
+	if diff := cmp.Diff(want, in.Normalized()); diff != "" {
+		t.Errorf("Normalized() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetXmasCards(t *testing.T) {
+	ctx := context.Background()
+	src := fakeSource{
+		groups: map[string][]string{
+			"contactGroups/xmas":  {"people/1", "people/2"},
+			"contactGroups/gone":  {"people/1", "people/404"},
+			"contactGroups/empty": nil,
+		},
+		people: map[string]Contact{
+			"people/1": homeContact("people/1", "Homer Simpson", mainStreet),
+			"people/2": homeContact("people/2", "Marge Simpson", mainStreet),
+		},
+	}
+
+	t.Run("group", func(t *testing.T) {
+		res, err := GetXmasCards(ctx, src, "contactGroups/xmas", Options{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(res.Cards) != 1 {
+			t.Errorf("unexpected card count, got: %v, want: 1", len(res.Cards))
+		}
+	})
+
+	t.Run("missing contact", func(t *testing.T) {
+		res, err := GetXmasCards(ctx, src, "contactGroups/gone", Options{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []SkippedContact{{ResourceName: "people/404", Reason: SkipAPIError, Detail: "not returned by the contact source"}}
+		if diff := cmp.Diff(want, res.Skipped); diff != "" {
+			t.Errorf("Skipped mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("empty group", func(t *testing.T) {
+		if _, err := GetXmasCards(ctx, src, "contactGroups/empty", Options{}); !errors.Is(err, ErrEmptyGroup) {
+			t.Errorf("unexpected error, got: %v, want: %v", err, ErrEmptyGroup)
+		}
+	})
+
+	t.Run("source error", func(t *testing.T) {
+		errSrc := fakeSource{listErr: errors.New("boom")}
+		if _, err := GetXmasCards(ctx, errSrc, "contactGroups/xmas", Options{}); err == nil {
+			t.Errorf("missing expected error")
+		}
+	})
+}
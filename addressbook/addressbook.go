@@ -0,0 +1,158 @@
+// Package addressbook is a cohabitaters.ContactSource over contacts read from
+// a file, such as a vCard or CSV export, rather than from an online address
+// book.
+package addressbook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/bfallik/cohabitaters"
+)
+
+const (
+	// ResourcePrefix starts the resource name of every group and contact in
+	// a Book, which tells them apart from those of other sources.
+	ResourcePrefix = "addressbook/"
+
+	// AllContacts is the resource name of the group holding every contact
+	// in a Book.
+	AllContacts = ResourcePrefix + "all"
+
+	groupPrefix   = ResourcePrefix + "groups/"
+	contactPrefix = ResourcePrefix + "contacts/"
+)
+
+// IsResourceName reports whether name is the resource name of a group or
+// contact in a Book.
+func IsResourceName(name string) bool {
+	return strings.HasPrefix(name, ResourcePrefix)
+}
+
+// GroupResourceName returns the resource name of the group called name,
+// e.g. a vCard category.
+func GroupResourceName(name string) string {
+	return groupPrefix + name
+}
+
+// ContactResourceName returns the resource name of a contact from its ID in
+// the file, e.g. a vCard UID.
+func ContactResourceName(id string) string {
+	return contactPrefix + id
+}
+
+// contentIDs gives contacts without an ID of their own one derived from
+// what identifies them, such as their name, email addresses and phone
+// numbers. Unlike their position in the file, it doesn't change when other
+// contacts are added or removed, so overrides saved against the contact
+// still find it after the file is edited and imported again.
+type contentIDs map[string]int
+
+// next returns the ID of a contact identified by fields. Contacts alike in
+// every field are told apart by a count, in the order they're read.
+func (ids contentIDs) next(prefix string, fields ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	id := prefix + hex.EncodeToString(sum[:6])
+	ids[id]++
+	if n := ids[id]; n > 1 {
+		id = fmt.Sprintf("%s-%d", id, n)
+	}
+	return id
+}
+
+// Read reads a file of contacts, choosing the format from the file name: a
+// ".csv" file is read by ReadCSV and anything else by ReadVCard.
+func Read(filename string, r io.Reader) (*Book, error) {
+	if strings.EqualFold(path.Ext(filename), ".csv") {
+		return ReadCSV(r)
+	}
+	return ReadVCard(r)
+}
+
+// Book is an address book held in memory.
+type Book struct {
+	contacts []cohabitaters.Contact
+	byName   map[string]int
+	groups   []cohabitaters.ContactGroup
+	members  map[string][]string
+}
+
+var _ cohabitaters.AllContactsSource = (*Book)(nil)
+
+// New returns a Book of contacts. Their Memberships are resource names made
+// by GroupResourceName, and every group one of them names is in the Book. A
+// contact whose resource name is already taken is left out.
+func New(contacts []cohabitaters.Contact) *Book {
+	b := &Book{
+		byName:  map[string]int{},
+		members: map[string][]string{},
+	}
+	for _, c := range contacts {
+		if _, ok := b.byName[c.ResourceName]; ok {
+			continue
+		}
+		b.byName[c.ResourceName] = len(b.contacts)
+		b.contacts = append(b.contacts, c)
+		b.members[AllContacts] = append(b.members[AllContacts], c.ResourceName)
+		seen := map[string]bool{}
+		for _, g := range c.Memberships {
+			if !seen[g] {
+				seen[g] = true
+				b.members[g] = append(b.members[g], c.ResourceName)
+			}
+		}
+	}
+
+	for rn, members := range b.members {
+		if rn == AllContacts {
+			continue
+		}
+		name := strings.TrimPrefix(rn, groupPrefix)
+		b.groups = append(b.groups, cohabitaters.ContactGroup{ResourceName: rn, Name: name, FormattedName: name, MemberCount: len(members)})
+	}
+	sort.Slice(b.groups, func(i, j int) bool { return b.groups[i].Name < b.groups[j].Name })
+	all := cohabitaters.ContactGroup{ResourceName: AllContacts, Name: "All contacts", FormattedName: "All contacts", MemberCount: len(b.contacts)}
+	b.groups = append([]cohabitaters.ContactGroup{all}, b.groups...)
+	return b
+}
+
+// Len returns the number of contacts in the book.
+func (b *Book) Len() int {
+	return len(b.contacts)
+}
+
+// ContactGroups lists "All contacts" and then the book's groups by name.
+func (b *Book) ContactGroups(ctx context.Context) ([]cohabitaters.ContactGroup, error) {
+	return b.groups, nil
+}
+
+// AllContactsGroup returns AllContacts.
+func (b *Book) AllContactsGroup() string {
+	return AllContacts
+}
+
+func (b *Book) GroupMembers(ctx context.Context, groupResourceName string) ([]string, error) {
+	members, ok := b.members[groupResourceName]
+	if !ok {
+		return nil, fmt.Errorf("no contact group %q in the address book", groupResourceName)
+	}
+	return members, nil
+}
+
+// Contacts returns the named contacts in the order asked for, leaving out
+// those not in the book.
+func (b *Book) Contacts(ctx context.Context, resourceNames []string) ([]cohabitaters.Contact, error) {
+	out := make([]cohabitaters.Contact, 0, len(resourceNames))
+	for _, rn := range resourceNames {
+		if i, ok := b.byName[rn]; ok {
+			out = append(out, b.contacts[i])
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,65 @@
+package addressbook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBookGroups(t *testing.T) {
+	b := New([]cohabitaters.Contact{
+		{ResourceName: ContactResourceName("a"), Memberships: []string{GroupResourceName("Xmas Card"), GroupResourceName("Book Club")}},
+		{ResourceName: ContactResourceName("b"), Memberships: []string{GroupResourceName("Xmas Card")}},
+		{ResourceName: ContactResourceName("a")},
+	})
+
+	ctx := context.Background()
+	groups, err := b.ContactGroups(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []cohabitaters.ContactGroup{
+		{ResourceName: AllContacts, Name: "All contacts", FormattedName: "All contacts", MemberCount: 2},
+		{ResourceName: "addressbook/groups/Book Club", Name: "Book Club", FormattedName: "Book Club", MemberCount: 1},
+		{ResourceName: "addressbook/groups/Xmas Card", Name: "Xmas Card", FormattedName: "Xmas Card", MemberCount: 2},
+	}
+	if diff := cmp.Diff(want, groups); diff != "" {
+		t.Errorf("ContactGroups() mismatch (-want +got):\n%s", diff)
+	}
+
+	members, err := b.GroupMembers(ctx, GroupResourceName("Book Club"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"addressbook/contacts/a"}, members); diff != "" {
+		t.Errorf("GroupMembers() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := b.GroupMembers(ctx, GroupResourceName("Bowling")); err == nil {
+		t.Errorf("expected an error for an unknown group")
+	}
+
+	if !IsResourceName(AllContacts) || IsResourceName("contactGroups/all") {
+		t.Errorf("IsResourceName() doesn't tell the book's resource names from Google's")
+	}
+}
+
+func TestGetXmasCardsFromVCard(t *testing.T) {
+	b, err := ReadVCard(strings.NewReader(simpsonsVCF))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res, err := cohabitaters.GetXmasCards(context.Background(), b, GroupResourceName("Xmas Card"), cohabitaters.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Cards) != 1 {
+		t.Fatalf("unexpected card count, got: %v, want: %v", len(res.Cards), 1)
+	}
+	if want := "Marge & Homer Simpson"; res.Cards[0].Addressee != want {
+		t.Errorf("unexpected addressee, got: %v, want: %v", res.Cards[0].Addressee, want)
+	}
+}
@@ -0,0 +1,272 @@
+package addressbook
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bfallik/cohabitaters"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// maxCSVAddresses is how many numbered "Address N - ..." columns of a Google
+// export are read.
+const maxCSVAddresses = 10
+
+// ReadCSV reads contacts exported as CSV by Google Contacts, in either its
+// older "Google CSV" or its current layout, or by Outlook. The header row
+// tells them apart.
+//
+// Google's "Labels" or "Group Membership" column and Outlook's "Categories"
+// column give each contact's groups, leaving out Google's built-in ones like
+// "* myContacts". Google's relations and Outlook's "Spouse" and "Children"
+// columns become relations. Contacts have no ID in either layout, so a
+// contact's resource name comes from a hash of its name, email addresses and
+// phone numbers.
+func ReadCSV(r io.Reader) (*Book, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = decodeCSV(data); err != nil {
+		return nil, err
+	}
+
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("the CSV file is empty")
+	}
+
+	cols := newCSVColumns(records[0])
+	var read func(row csvRow) cohabitaters.Contact
+	switch {
+	case cols.has("address 1 - street", "labels", "group membership"):
+		read = googleCSVContact
+	case cols.has("home street", "business street", "categories"):
+		read = outlookCSVContact
+	default:
+		return nil, errors.New("unrecognized CSV header: expected contacts exported from Google Contacts or Outlook")
+	}
+
+	var contacts []cohabitaters.Contact
+	ids := contentIDs{}
+	for _, record := range records[1:] {
+		c := read(csvRow{cols: cols, record: record})
+		c.ResourceName = ContactResourceName(ids.next("row-", csvIdentity(records[0], record, c)...))
+		contacts = append(contacts, c)
+	}
+	return New(contacts), nil
+}
+
+// csvIdentity returns what identifies the contact read from record: its name
+// and the values of its email and phone columns.
+func csvIdentity(header, record []string, c cohabitaters.Contact) []string {
+	var fields []string
+	for _, n := range c.Names {
+		fields = append(fields, n.DisplayName, n.GivenName, n.FamilyName)
+	}
+	for i, name := range header {
+		name = strings.ToLower(name)
+		if i >= len(record) || strings.Contains(name, "type") || strings.Contains(name, "label") {
+			continue
+		}
+		if strings.Contains(name, "mail") || strings.Contains(name, "phone") {
+			fields = append(fields, strings.TrimSpace(record[i]))
+		}
+	}
+	return fields
+}
+
+// decodeCSV returns data as UTF-8. Outlook writes the Windows code page and
+// some exports are UTF-16 with a byte order mark.
+func decodeCSV(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}):
+		return data[3:], nil
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}), bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		return unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder().Bytes(data)
+	case !utf8.Valid(data):
+		return charmap.Windows1252.NewDecoder().Bytes(data)
+	}
+	return data, nil
+}
+
+// csvColumns maps lower case column names to their index.
+type csvColumns map[string]int
+
+func newCSVColumns(header []string) csvColumns {
+	cols := csvColumns{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := cols[name]; !ok {
+			cols[name] = i
+		}
+	}
+	return cols
+}
+
+// has reports whether any of names is a column.
+func (c csvColumns) has(names ...string) bool {
+	for _, n := range names {
+		if _, ok := c[n]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+type csvRow struct {
+	cols   csvColumns
+	record []string
+}
+
+// get returns the trimmed value of the first of names that is a column and
+// is set in the row.
+func (r csvRow) get(names ...string) string {
+	for _, n := range names {
+		if i, ok := r.cols[n]; ok && i < len(r.record) {
+			if v := strings.TrimSpace(r.record[i]); len(v) > 0 {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// csvName makes a contact's name, joining the given, middle and family names
+// when the row has no full name.
+func csvName(full, given, middle, family string) []cohabitaters.Name {
+	n := cohabitaters.Name{DisplayName: full, GivenName: given, FamilyName: family}
+	if len(n.DisplayName) == 0 {
+		n.DisplayName = strings.Join(nonEmpty(given, middle, family), " ")
+	}
+	if len(n.DisplayName) == 0 {
+		return nil
+	}
+	return []cohabitaters.Name{n}
+}
+
+// googleCSVContact reads a row of a Google Contacts export.
+func googleCSVContact(row csvRow) cohabitaters.Contact {
+	var c cohabitaters.Contact
+	c.Names = csvName(row.get("name"),
+		row.get("first name", "given name"),
+		row.get("middle name", "additional name"),
+		row.get("last name", "family name"))
+
+	for n := 1; n <= maxCSVAddresses; n++ {
+		col := func(field string) string { return row.get(fmt.Sprintf("address %d - %s", n, field)) }
+		addr := cohabitaters.ContactAddress{Address: cohabitaters.Address{
+			StreetAddress:  col("street"),
+			StreetAddress2: strings.Join(nonEmpty(col("extended address"), col("po box")), "\n"),
+			City:           col("city"),
+			Region:         col("region"),
+			PostalCode:     col("postal code"),
+			Country:        col("country"),
+		}}
+		if addr.Address == (cohabitaters.Address{}) {
+			// an address Google couldn't split up is only formatted
+			addr.StreetAddress = col("formatted")
+		}
+		if addr.Address == (cohabitaters.Address{}) {
+			continue
+		}
+		// Google marks the primary value with "* "
+		typ := col("label")
+		if len(typ) == 0 {
+			typ = col("type")
+		}
+		typ, addr.Primary = strings.CutPrefix(typ, "* ")
+		addr.Type = strings.ToLower(strings.TrimSpace(typ))
+		c.Addresses = append(c.Addresses, addr)
+	}
+
+	for n := 1; ; n++ {
+		prefix := fmt.Sprintf("relation %d - ", n)
+		if !row.cols.has(prefix + "value") {
+			break
+		}
+		person := row.get(prefix + "value")
+		if len(person) == 0 {
+			continue
+		}
+		typ := row.get(prefix+"label", prefix+"type")
+		c.Relations = append(c.Relations, cohabitaters.Relation{Person: person, Type: relationType(typ)})
+	}
+
+	for _, g := range strings.Split(row.get("labels", "group membership"), ":::") {
+		// "* myContacts" and its like are Google's own groups
+		if g = strings.TrimSpace(g); len(g) > 0 && !strings.HasPrefix(g, "*") {
+			c.Memberships = append(c.Memberships, GroupResourceName(g))
+		}
+	}
+	return c
+}
+
+// outlookAddressTypes maps the prefixes of Outlook's address columns to
+// address types.
+var outlookAddressTypes = []struct{ prefix, typ string }{
+	{"home", "home"},
+	{"business", "work"},
+	{"other", "other"},
+}
+
+// outlookCSVContact reads a row of an Outlook export.
+func outlookCSVContact(row csvRow) cohabitaters.Contact {
+	var c cohabitaters.Contact
+	c.Names = csvName(row.get("display name", "name"), row.get("first name"), row.get("middle name"), row.get("last name"))
+
+	for _, t := range outlookAddressTypes {
+		col := func(field string) string { return row.get(t.prefix + " " + field) }
+		addr := cohabitaters.ContactAddress{Type: t.typ, Address: cohabitaters.Address{
+			StreetAddress:  col("street"),
+			StreetAddress2: strings.Join(nonEmpty(col("street 2"), col("street 3"), col("po box")), "\n"),
+			City:           col("city"),
+			Region:         col("state"),
+			PostalCode:     col("postal code"),
+			Country:        row.get(t.prefix+" country/region", t.prefix+" country"),
+		}}
+		if addr.Address != (cohabitaters.Address{}) {
+			c.Addresses = append(c.Addresses, addr)
+		}
+	}
+
+	if spouse := row.get("spouse"); len(spouse) > 0 {
+		c.Relations = append(c.Relations, cohabitaters.Relation{Person: spouse, Type: "spouse"})
+	}
+	for _, child := range strings.FieldsFunc(row.get("children"), isListSeparator) {
+		if child = strings.TrimSpace(child); len(child) > 0 {
+			c.Relations = append(c.Relations, cohabitaters.Relation{Person: child, Type: "child"})
+		}
+	}
+
+	for _, g := range strings.FieldsFunc(row.get("categories"), isListSeparator) {
+		if g = strings.TrimSpace(g); len(g) > 0 {
+			c.Memberships = append(c.Memberships, GroupResourceName(g))
+		}
+	}
+	return c
+}
+
+// isListSeparator splits Outlook's lists, which use semicolons or commas
+// depending on the version.
+func isListSeparator(r rune) bool {
+	return r == ';' || r == ','
+}
+
+// relationType turns a relation label like "Domestic Partner" into the
+// lower case, unspaced form the People API uses.
+func relationType(label string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(label), " ", ""))
+}
@@ -0,0 +1,155 @@
+package addressbook
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadCSV(t *testing.T) {
+	evergreen := cohabitaters.Address{StreetAddress: "742 Evergreen Terrace", City: "Springfield", Region: "IL", PostalCode: "62701", Country: "United States"}
+
+	tests := []struct {
+		Name string
+		In   string
+		Want []cohabitaters.Contact
+	}{
+		{
+			Name: "google csv",
+			In: "\xef\xbb\xbfName,Given Name,Additional Name,Family Name,Group Membership,Address 1 - Type,Address 1 - Formatted,Address 1 - Street,Address 1 - City,Address 1 - PO Box,Address 1 - Region,Address 1 - Postal Code,Address 1 - Country,Address 1 - Extended Address,Address 2 - Type,Address 2 - Formatted,Relation 1 - Type,Relation 1 - Value\n" +
+				"Homer Simpson,Homer,Jay,Simpson,* myContacts ::: Xmas Card ::: Family,* Home,,742 Evergreen Terrace,Springfield,,IL,62701,United States,,Work,\"Sector 7G\nSpringfield\",Spouse,Marge\n",
+			Want: []cohabitaters.Contact{{
+				ResourceName: "addressbook/contacts/row-abdef0545b89",
+				Names:        []cohabitaters.Name{{DisplayName: "Homer Simpson", GivenName: "Homer", FamilyName: "Simpson"}},
+				Addresses: []cohabitaters.ContactAddress{
+					{Address: evergreen, Type: "home", Primary: true},
+					{Address: cohabitaters.Address{StreetAddress: "Sector 7G\nSpringfield"}, Type: "work"},
+				},
+				Relations:   []cohabitaters.Relation{{Person: "Marge", Type: "spouse"}},
+				Memberships: []string{"addressbook/groups/Xmas Card", "addressbook/groups/Family"},
+			}},
+		},
+		{
+			Name: "google contacts",
+			In: "First Name,Middle Name,Last Name,Labels,Address 1 - Label,Address 1 - Street,Address 1 - City,Address 1 - Region,Address 1 - Postal Code,Address 1 - Country,Address 1 - Extended Address,Relation 1 - Label,Relation 1 - Value\n" +
+				"Marge,,Simpson,Xmas Card ::: * starred,Home,742 Evergreen Terrace,Springfield,IL,62701,United States,,Domestic Partner,Homer Simpson\n" +
+				",,,Xmas Card,,,,,,,,,\n",
+			Want: []cohabitaters.Contact{
+				{
+					ResourceName: "addressbook/contacts/row-78a7eb0ae832",
+					Names:        []cohabitaters.Name{{DisplayName: "Marge Simpson", GivenName: "Marge", FamilyName: "Simpson"}},
+					Addresses:    []cohabitaters.ContactAddress{{Address: evergreen, Type: "home"}},
+					Relations:    []cohabitaters.Relation{{Person: "Homer Simpson", Type: "domesticpartner"}},
+					Memberships:  []string{"addressbook/groups/Xmas Card"},
+				},
+				{
+					ResourceName: "addressbook/contacts/row-e3b0c44298fc",
+					Memberships:  []string{"addressbook/groups/Xmas Card"},
+				},
+			},
+		},
+		{
+			Name: "outlook",
+			In: "First Name,Middle Name,Last Name,Home Street,Home Street 2,Home City,Home State,Home Postal Code,Home Country/Region,Business Street,Business City,Categories,Spouse,Children\r\n" +
+				"Ned,,Fl\xe4nders,740 Evergreen Terrace,Apt 2,Springfield,IL,62701,United States,1 Leftorium Way,Springfield,Xmas Card;Church,Maude,\"Rod, Todd\"\r\n",
+			Want: []cohabitaters.Contact{{
+				ResourceName: "addressbook/contacts/row-fdbcc1872552",
+				Names:        []cohabitaters.Name{{DisplayName: "Ned Fländers", GivenName: "Ned", FamilyName: "Fländers"}},
+				Addresses: []cohabitaters.ContactAddress{
+					{
+						Address: cohabitaters.Address{StreetAddress: "740 Evergreen Terrace", StreetAddress2: "Apt 2", City: "Springfield", Region: "IL", PostalCode: "62701", Country: "United States"},
+						Type:    "home",
+					},
+					{Address: cohabitaters.Address{StreetAddress: "1 Leftorium Way", City: "Springfield"}, Type: "work"},
+				},
+				Relations: []cohabitaters.Relation{
+					{Person: "Maude", Type: "spouse"},
+					{Person: "Rod", Type: "child"},
+					{Person: "Todd", Type: "child"},
+				},
+				Memberships: []string{"addressbook/groups/Xmas Card", "addressbook/groups/Church"},
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			b, err := ReadCSV(strings.NewReader(test.In))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			members, err := b.GroupMembers(context.Background(), AllContacts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := b.Contacts(context.Background(), members)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.Want, got); diff != "" {
+				t.Errorf("ReadCSV() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadCSVStableNames(t *testing.T) {
+	const header = "First Name,Last Name,E-mail 1 - Type,E-mail 1 - Value,Phone 1 - Value,Labels\n"
+	homer := "Homer,Simpson,* Home,homer@example.com,555-7334,Xmas Card\n"
+	marge := "Marge,Simpson,,,,Xmas Card\n"
+	blank := ",,,,,Xmas Card\n"
+	names := func(in string) []string {
+		b, err := ReadCSV(strings.NewReader(in))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		members, err := b.GroupMembers(context.Background(), AllContacts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return members
+	}
+
+	before := names(header + homer + marge + blank + blank)
+	// Homer moves down a row, changes groups and gains a neighbour
+	after := names(header + marge + "Ned,Flanders,,,,\n" + strings.Replace(homer, "Xmas Card", "Family", 1) + blank + blank)
+
+	for _, rn := range before {
+		if !slices.Contains(after, rn) {
+			t.Errorf("resource name %s changed, got: %v", rn, after)
+		}
+	}
+	seen := map[string]bool{}
+	for _, rn := range before {
+		if seen[rn] {
+			t.Errorf("unexpected duplicate resource name %s", rn)
+		}
+		seen[rn] = true
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	for _, in := range []string{"", "Email,Phone\nhomer@example.com,555-7334\n"} {
+		if _, err := ReadCSV(strings.NewReader(in)); err == nil {
+			t.Errorf("ReadCSV(%q) expected an error", in)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	b, err := Read("contacts.CSV", strings.NewReader("First Name,Last Name,Labels\nHomer,Simpson,Xmas Card\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.Len() != 1 {
+		t.Errorf("unexpected contact count, got: %v, want: %v", b.Len(), 1)
+	}
+
+	if _, err := Read("contacts.vcf", strings.NewReader("First Name,Last Name\n")); err == nil {
+		t.Errorf("expected an error reading CSV as a vCard")
+	}
+}
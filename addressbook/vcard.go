@@ -0,0 +1,207 @@
+package addressbook
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/bfallik/cohabitaters/vcard"
+)
+
+// ReadVCard reads a vCard 2.1, 3.0 or 4.0 file of any number of contacts;
+// see VCardContacts. A contact's resource name comes from its UID or, when
+// it has none, from a hash of its names, email addresses and phone numbers.
+func ReadVCard(r io.Reader) (*Book, error) {
+	var cards []vcard.Card
+	dec := vcard.NewDecoder(r)
+	for {
+		card, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("card %d: %w", len(cards)+1, err)
+		}
+		cards = append(cards, card)
+	}
+
+	resourceNames := make([]string, len(cards))
+	uids := map[string]bool{}
+	hashed := contentIDs{}
+	for i, card := range cards {
+		id := card.Value("UID")
+		if len(id) == 0 || uids[id] {
+			id = hashed.next("card-", cardIdentity(card)...)
+		}
+		uids[id] = true
+		resourceNames[i] = ContactResourceName(id)
+	}
+	return New(VCardContacts(cards, resourceNames)), nil
+}
+
+// VCardContacts turns vCards into contacts, the i'th card's called
+// resourceNames[i].
+//
+// FN and N make each contact's name, ADR its addresses and CATEGORIES the
+// groups it belongs to. RELATED, and the X-ABRELATEDNAMES that Google and
+// Apple write, become relations. Group cards, such as those
+// export.WriteVCard writes for households, are left out.
+func VCardContacts(cards []vcard.Card, resourceNames []string) []cohabitaters.Contact {
+	// RELATED may point at another card by its UID
+	names := map[string]string{}
+	for _, card := range cards {
+		if uid := card.Value("UID"); len(uid) > 0 {
+			names[uid] = cardName(card).DisplayName
+		}
+	}
+
+	var contacts []cohabitaters.Contact
+	for i, card := range cards {
+		if strings.EqualFold(card.Value("KIND"), "group") {
+			continue
+		}
+		contacts = append(contacts, vcardContact(card, resourceNames[i], names))
+	}
+	return contacts
+}
+
+func vcardContact(card vcard.Card, resourceName string, names map[string]string) cohabitaters.Contact {
+	c := cohabitaters.Contact{ResourceName: resourceName}
+	if n := cardName(card); len(n.DisplayName) > 0 {
+		c.Names = []cohabitaters.Name{n}
+	}
+
+	for _, p := range card.All("ADR") {
+		parts := append(p.Components(), make([]string, 7)...)
+		pobox, ext, street := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), strings.TrimSpace(parts[2])
+		if len(street) == 0 {
+			street, pobox = pobox, ""
+		}
+		addr := cohabitaters.ContactAddress{
+			Address: cohabitaters.Address{
+				StreetAddress:  street,
+				StreetAddress2: strings.Join(nonEmpty(ext, pobox), "\n"),
+				City:           strings.TrimSpace(parts[3]),
+				Region:         strings.TrimSpace(parts[4]),
+				PostalCode:     strings.TrimSpace(parts[5]),
+				Country:        strings.TrimSpace(parts[6]),
+			},
+			Primary: len(p.Param("PREF")) > 0,
+		}
+		if addr.Address == (cohabitaters.Address{}) {
+			continue
+		}
+		for _, t := range paramList(p, "TYPE") {
+			switch t = strings.ToLower(t); t {
+			case "pref":
+				addr.Primary = true
+			case "dom", "intl", "postal", "parcel":
+				// vCard 3.0 delivery types say nothing about whose address it is
+			default:
+				if len(addr.Type) == 0 {
+					addr.Type = t
+				}
+			}
+		}
+		c.Addresses = append(c.Addresses, addr)
+	}
+
+	seen := map[string]bool{}
+	for _, p := range card.All("CATEGORIES") {
+		for _, g := range p.List() {
+			if g = strings.TrimSpace(g); len(g) > 0 && !seen[g] {
+				seen[g] = true
+				c.Memberships = append(c.Memberships, GroupResourceName(g))
+			}
+		}
+	}
+
+	for _, p := range card.All("RELATED") {
+		person := p.Text()
+		if name, ok := names[person]; ok {
+			person = name
+		} else if !strings.EqualFold(p.Param("VALUE"), "text") && strings.Contains(person, ":") {
+			continue // a URI we can't follow
+		}
+		var typ string
+		if types := paramList(p, "TYPE"); len(types) > 0 {
+			typ = strings.ToLower(types[0])
+		}
+		c.Relations = append(c.Relations, cohabitaters.Relation{Person: person, Type: typ})
+	}
+	for _, p := range card.All("X-ABRELATEDNAMES") {
+		c.Relations = append(c.Relations, cohabitaters.Relation{Person: p.Text(), Type: abLabel(card, p.Group)})
+	}
+	return c
+}
+
+// cardIdentity returns the properties that identify the contact on a card
+// without a UID.
+func cardIdentity(card vcard.Card) []string {
+	var fields []string
+	for _, name := range []string{"FN", "N", "EMAIL", "TEL"} {
+		for _, p := range card.All(name) {
+			fields = append(fields, name+":"+strings.TrimSpace(p.Value))
+		}
+	}
+	return fields
+}
+
+// cardName returns the name on a card from FN, or from N when FN is
+// missing.
+func cardName(card vcard.Card) cohabitaters.Name {
+	var n cohabitaters.Name
+	if p := card.Get("N"); p != nil {
+		parts := append(p.Components(), "", "")
+		n.FamilyName, n.GivenName = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	}
+	n.DisplayName = strings.TrimSpace(card.Value("FN"))
+	if len(n.DisplayName) == 0 {
+		n.DisplayName = strings.Join(nonEmpty(n.GivenName, n.FamilyName), " ")
+	}
+	return n
+}
+
+// abLabel returns the X-ABLABEL grouped with a property, turning Apple's
+// "_$!<Spouse>!$_" for its built-in labels into "spouse".
+func abLabel(card vcard.Card, group string) string {
+	if len(group) == 0 {
+		return ""
+	}
+	for _, p := range card.All("X-ABLABEL") {
+		if strings.EqualFold(p.Group, group) {
+			label := p.Text()
+			if inner, ok := strings.CutPrefix(label, "_$!<"); ok {
+				label = strings.TrimSuffix(inner, ">!$_")
+			}
+			return strings.ToLower(strings.TrimSpace(label))
+		}
+	}
+	return ""
+}
+
+// paramList returns the values of a parameter, splitting any that hold a
+// comma separated list as vCard 4.0 allows.
+func paramList(p vcard.Property, name string) []string {
+	var out []string
+	for _, v := range p.Params[name] {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); len(s) > 0 {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+func nonEmpty(values ...string) []string {
+	var out []string
+	for _, v := range values {
+		if len(v) > 0 {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,129 @@
+package addressbook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/google/go-cmp/cmp"
+)
+
+const simpsonsVCF = "BEGIN:VCARD\r\n" +
+	"VERSION:3.0\r\n" +
+	"UID:homer\r\n" +
+	"FN:Homer Simpson\r\n" +
+	"N:Simpson;Homer;Jay;;\r\n" +
+	"ADR;TYPE=HOME,PREF:;;742 Evergreen Terrace;Springfield;IL;62701;USA\r\n" +
+	"ADR;TYPE=WORK:;;Sector 7G;Springfield;IL;;\r\n" +
+	"CATEGORIES:Xmas Card,Family\r\n" +
+	"item1.X-ABRELATEDNAMES:Marge\r\n" +
+	"item1.X-ABLabel:_$!<Spouse>!$_\r\n" +
+	"END:VCARD\r\n" +
+	"BEGIN:VCARD\r\n" +
+	"VERSION:4.0\r\n" +
+	"FN:Marge Simpson\r\n" +
+	"N:Simpson;Marge;;;\r\n" +
+	"ADR;TYPE=home:;;742 Evergreen \r\n" +
+	" Terrace;Springfield;IL;62701;USA\r\n" +
+	"CATEGORIES:Xmas Card\r\n" +
+	"RELATED;TYPE=spouse:homer\r\n" +
+	"END:VCARD\r\n" +
+	"BEGIN:VCARD\r\n" +
+	"VERSION:2.1\r\n" +
+	"N;CHARSET=UTF-8;ENCODING=QUOTED-PRINTABLE:Fl=C3=A4nders;Ned\r\n" +
+	"ADR;HOME;ENCODING=QUOTED-PRINTABLE:;Apt 2;740 Evergreen Terrace;Springfield;IL;=\r\n" +
+	"62701;USA\r\n" +
+	"END:VCARD\r\n" +
+	"BEGIN:VCARD\r\n" +
+	"VERSION:4.0\r\n" +
+	"KIND:group\r\n" +
+	"FN:The Simpsons\r\n" +
+	"MEMBER:urn:uuid:homer\r\n" +
+	"END:VCARD\r\n"
+
+func TestReadVCard(t *testing.T) {
+	b, err := ReadVCard(strings.NewReader(simpsonsVCF))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.Len() != 3 {
+		t.Fatalf("unexpected contact count, got: %v, want: %v", b.Len(), 3)
+	}
+
+	ctx := context.Background()
+	got, err := b.Contacts(ctx, []string{"addressbook/contacts/homer", "addressbook/contacts/card-b7931e8642f4", "addressbook/contacts/card-8ff249a1375a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	evergreen := cohabitaters.Address{StreetAddress: "742 Evergreen Terrace", City: "Springfield", Region: "IL", PostalCode: "62701", Country: "USA"}
+	want := []cohabitaters.Contact{
+		{
+			ResourceName: "addressbook/contacts/homer",
+			Names:        []cohabitaters.Name{{DisplayName: "Homer Simpson", GivenName: "Homer", FamilyName: "Simpson"}},
+			Addresses: []cohabitaters.ContactAddress{
+				{Address: evergreen, Type: "home", Primary: true},
+				{Address: cohabitaters.Address{StreetAddress: "Sector 7G", City: "Springfield", Region: "IL"}, Type: "work"},
+			},
+			Relations:   []cohabitaters.Relation{{Person: "Marge", Type: "spouse"}},
+			Memberships: []string{"addressbook/groups/Xmas Card", "addressbook/groups/Family"},
+		},
+		{
+			ResourceName: "addressbook/contacts/card-b7931e8642f4",
+			Names:        []cohabitaters.Name{{DisplayName: "Marge Simpson", GivenName: "Marge", FamilyName: "Simpson"}},
+			Addresses:    []cohabitaters.ContactAddress{{Address: evergreen, Type: "home"}},
+			Relations:    []cohabitaters.Relation{{Person: "Homer Simpson", Type: "spouse"}},
+			Memberships:  []string{"addressbook/groups/Xmas Card"},
+		},
+		{
+			ResourceName: "addressbook/contacts/card-8ff249a1375a",
+			Names:        []cohabitaters.Name{{DisplayName: "Ned Fländers", GivenName: "Ned", FamilyName: "Fländers"}},
+			Addresses: []cohabitaters.ContactAddress{{
+				Address: cohabitaters.Address{StreetAddress: "740 Evergreen Terrace", StreetAddress2: "Apt 2", City: "Springfield", Region: "IL", PostalCode: "62701", Country: "USA"},
+				Type:    "home",
+			}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Contacts() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadVCardStableNames(t *testing.T) {
+	ctx := context.Background()
+	names := func(vcf string) map[string]string {
+		b, err := ReadVCard(strings.NewReader(vcf))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		rns, err := b.GroupMembers(ctx, AllContacts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		contacts, err := b.Contacts(ctx, rns)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out := map[string]string{}
+		for _, c := range contacts {
+			out[c.Names[0].DisplayName] = c.ResourceName
+		}
+		return out
+	}
+
+	// a card without a UID keeps its name when another is added before it
+	want := names(simpsonsVCF)
+	got := names("BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Bart Simpson\r\nEND:VCARD\r\n" + simpsonsVCF)
+	for name, rn := range want {
+		if got[name] != rn {
+			t.Errorf("unexpected resource name for %s, got: %v, want: %v", name, got[name], rn)
+		}
+	}
+}
+
+func TestReadVCardErrors(t *testing.T) {
+	_, err := ReadVCard(strings.NewReader("BEGIN:VCARD\nFN:Homer\nEND:VCARD\nBEGIN:VCARD\nFN:Marge\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "card 2: ") {
+		t.Errorf("unexpected error, got: %v, want: an error about card 2", err)
+	}
+}
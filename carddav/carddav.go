@@ -0,0 +1,361 @@
+// Package carddav reads contacts from a CardDAV server (RFC 6352), such as
+// Nextcloud or Fastmail, and adapts it to a cohabitaters.ContactSource.
+package carddav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bfallik/cohabitaters/vcard"
+)
+
+const (
+	nsDAV     = "DAV:"
+	nsCardDAV = "urn:ietf:params:xml:ns:carddav"
+)
+
+// ErrInvalidSyncToken is returned by SyncAddressBook when the server no
+// longer accepts the sync token, after which the address book has to be read
+// in full again.
+var ErrInvalidSyncToken = errors.New("carddav: invalid sync token")
+
+// Client talks to a CardDAV server.
+type Client struct {
+	// Endpoint is where discovery starts: the server's root, its
+	// /.well-known/carddav, a principal or an address book home set.
+	Endpoint *url.URL
+	// Username and Password, when set, are sent with every request using
+	// basic authentication, which is how both Nextcloud and Fastmail take an
+	// app password.
+	Username string
+	Password string
+	// HTTPClient makes the requests; nil means http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client for the server at endpoint.
+func NewClient(endpoint, username, password string) (*Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("carddav: endpoint %q is not an http or https URL", endpoint)
+	}
+	return &Client{Endpoint: u, Username: username, Password: password}, nil
+}
+
+// AddressBook is a collection of vCards on the server.
+type AddressBook struct {
+	Href        string
+	Name        string
+	Description string
+	// SyncToken, when the server supports RFC 6578, is where a later
+	// SyncAddressBook picks up from.
+	SyncToken string
+}
+
+// Object is a vCard stored on the server.
+type Object struct {
+	Href string
+	ETag string
+	Card vcard.Card
+}
+
+// SyncResult is what changed in an address book since a sync token.
+type SyncResult struct {
+	Updated   []Object
+	Deleted   []string // hrefs
+	SyncToken string
+}
+
+type multistatus struct {
+	Responses []response `xml:"DAV: response"`
+	SyncToken string     `xml:"DAV: sync-token"`
+}
+
+type response struct {
+	Href      string     `xml:"DAV: href"`
+	Status    string     `xml:"DAV: status"`
+	Propstats []propstat `xml:"DAV: propstat"`
+}
+
+type propstat struct {
+	Prop   prop   `xml:"DAV: prop"`
+	Status string `xml:"DAV: status"`
+}
+
+type prop struct {
+	ResourceType struct {
+		AddressBook *struct{} `xml:"urn:ietf:params:xml:ns:carddav addressbook"`
+	} `xml:"DAV: resourcetype"`
+	DisplayName          string   `xml:"DAV: displayname"`
+	Description          string   `xml:"urn:ietf:params:xml:ns:carddav addressbook-description"`
+	SyncToken            string   `xml:"DAV: sync-token"`
+	ETag                 string   `xml:"DAV: getetag"`
+	AddressData          string   `xml:"urn:ietf:params:xml:ns:carddav address-data"`
+	CurrentUserPrincipal hrefProp `xml:"DAV: current-user-principal"`
+	AddressBookHomeSet   hrefProp `xml:"urn:ietf:params:xml:ns:carddav addressbook-home-set"`
+}
+
+type hrefProp struct {
+	Href string `xml:"DAV: href"`
+}
+
+// ok returns the properties the server returned successfully, merged from
+// every propstat with a 2xx status.
+func (r response) ok() prop {
+	var out prop
+	for _, ps := range r.Propstats {
+		if !isSuccess(ps.Status) {
+			continue
+		}
+		p := ps.Prop
+		if p.ResourceType.AddressBook != nil {
+			out.ResourceType = p.ResourceType
+		}
+		for _, f := range []struct{ dst, src *string }{
+			{&out.DisplayName, &p.DisplayName},
+			{&out.Description, &p.Description},
+			{&out.SyncToken, &p.SyncToken},
+			{&out.ETag, &p.ETag},
+			{&out.AddressData, &p.AddressData},
+			{&out.CurrentUserPrincipal.Href, &p.CurrentUserPrincipal.Href},
+			{&out.AddressBookHomeSet.Href, &p.AddressBookHomeSet.Href},
+		} {
+			if len(*f.src) > 0 {
+				*f.dst = *f.src
+			}
+		}
+	}
+	return out
+}
+
+// isSuccess reports whether a status line like "HTTP/1.1 200 OK" is 2xx. A
+// missing status counts as success.
+func isSuccess(status string) bool {
+	fields := strings.Fields(status)
+	return len(fields) < 2 || strings.HasPrefix(fields[1], "2")
+}
+
+// do sends a WebDAV request with an XML body and decodes the multistatus
+// reply.
+func (c *Client) do(ctx context.Context, method, href, depth, body string) (*multistatus, error) {
+	u, err := c.resolve(href)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `application/xml; charset="utf-8"`)
+	req.Header.Set("Depth", depth)
+	if len(c.Username) > 0 {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		// RFC 6578 section 3.2: an unusable token fails a precondition
+		if bytes.Contains(data, []byte("valid-sync-token")) {
+			return nil, ErrInvalidSyncToken
+		}
+		return nil, fmt.Errorf("carddav: %s %s: %s", method, u, resp.Status)
+	}
+	var ms multistatus
+	if err := xml.Unmarshal(data, &ms); err != nil {
+		return nil, fmt.Errorf("carddav: %s %s: %w", method, u, err)
+	}
+	return &ms, nil
+}
+
+func (c *Client) resolve(href string) (*url.URL, error) {
+	ref, err := url.Parse(href)
+	if err != nil {
+		return nil, err
+	}
+	return c.Endpoint.ResolveReference(ref), nil
+}
+
+// sameHref reports whether two hrefs name the same resource, which servers
+// don't always write the same way.
+func (c *Client) sameHref(a, b string) bool {
+	ua, errA := c.resolve(a)
+	ub, errB := c.resolve(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return strings.TrimSuffix(ua.Path, "/") == strings.TrimSuffix(ub.Path, "/")
+}
+
+const propfindPrincipal = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:current-user-principal/></d:prop></d:propfind>`
+
+const propfindHomeSet = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:card="urn:ietf:params:xml:ns:carddav"><d:prop><card:addressbook-home-set/></d:prop></d:propfind>`
+
+const propfindAddressBooks = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:card="urn:ietf:params:xml:ns:carddav">
+<d:prop><d:resourcetype/><d:displayname/><card:addressbook-description/><d:sync-token/></d:prop>
+</d:propfind>`
+
+// FindAddressBooks discovers the user's address books: it follows the
+// endpoint to the current user's principal, from there to their address book
+// home set, and lists the address books in it. A step the server doesn't
+// answer is skipped, so the endpoint may point at any of them.
+func (c *Client) FindAddressBooks(ctx context.Context) ([]AddressBook, error) {
+	principal := c.Endpoint.String()
+	if ms, err := c.do(ctx, "PROPFIND", principal, "0", propfindPrincipal); err == nil {
+		for _, r := range ms.Responses {
+			if p := r.ok().CurrentUserPrincipal.Href; len(p) > 0 {
+				principal = p
+			}
+		}
+	}
+
+	home := principal
+	if ms, err := c.do(ctx, "PROPFIND", principal, "0", propfindHomeSet); err == nil {
+		for _, r := range ms.Responses {
+			if h := r.ok().AddressBookHomeSet.Href; len(h) > 0 {
+				home = h
+			}
+		}
+	}
+
+	ms, err := c.do(ctx, "PROPFIND", home, "1", propfindAddressBooks)
+	if err != nil {
+		return nil, err
+	}
+	var books []AddressBook
+	for _, r := range ms.Responses {
+		p := r.ok()
+		if p.ResourceType.AddressBook == nil {
+			continue
+		}
+		name := p.DisplayName
+		if len(name) == 0 {
+			name = strings.TrimSuffix(r.Href, "/")
+			name = name[strings.LastIndexByte(name, '/')+1:]
+		}
+		books = append(books, AddressBook{Href: r.Href, Name: name, Description: p.Description, SyncToken: p.SyncToken})
+	}
+	return books, nil
+}
+
+const reportQuery = `<?xml version="1.0" encoding="utf-8"?>
+<card:addressbook-query xmlns:d="DAV:" xmlns:card="urn:ietf:params:xml:ns:carddav">
+<d:prop><d:getetag/><card:address-data/></d:prop>
+</card:addressbook-query>`
+
+// QueryAddressBook returns every vCard in the address book.
+func (c *Client) QueryAddressBook(ctx context.Context, href string) ([]Object, error) {
+	ms, err := c.do(ctx, "REPORT", href, "1", reportQuery)
+	if err != nil {
+		return nil, err
+	}
+	objects, _, err := c.objects(ms, href)
+	return objects, err
+}
+
+// MultiGet returns the vCards at hrefs in the address book.
+func (c *Client) MultiGet(ctx context.Context, bookHref string, hrefs []string) ([]Object, error) {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>
+<card:addressbook-multiget xmlns:d="DAV:" xmlns:card="urn:ietf:params:xml:ns:carddav">
+<d:prop><d:getetag/><card:address-data/></d:prop>`)
+	for _, h := range hrefs {
+		b.WriteString("<d:href>")
+		_ = xml.EscapeText(&b, []byte(h))
+		b.WriteString("</d:href>")
+	}
+	b.WriteString("</card:addressbook-multiget>")
+
+	ms, err := c.do(ctx, "REPORT", bookHref, "1", b.String())
+	if err != nil {
+		return nil, err
+	}
+	objects, _, err := c.objects(ms, bookHref)
+	return objects, err
+}
+
+// SyncAddressBook returns what changed in the address book since token, or
+// everything in it when token is empty (RFC 6578). Servers may leave the
+// vCards out of the changes, in which case they're fetched with MultiGet.
+func (c *Client) SyncAddressBook(ctx context.Context, href, token string) (SyncResult, error) {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>
+<d:sync-collection xmlns:d="DAV:" xmlns:card="urn:ietf:params:xml:ns:carddav"><d:sync-token>`)
+	_ = xml.EscapeText(&b, []byte(token))
+	b.WriteString(`</d:sync-token><d:sync-level>1</d:sync-level><d:prop><d:getetag/><card:address-data/></d:prop></d:sync-collection>`)
+
+	ms, err := c.do(ctx, "REPORT", href, "0", b.String())
+	if err != nil {
+		return SyncResult{}, err
+	}
+	res := SyncResult{SyncToken: ms.SyncToken}
+
+	var missing []string
+	if res.Updated, missing, err = c.objects(ms, href); err != nil {
+		return SyncResult{}, err
+	}
+	for _, r := range ms.Responses {
+		if strings.Contains(r.Status, " 404 ") {
+			res.Deleted = append(res.Deleted, r.Href)
+		}
+	}
+	if len(missing) > 0 {
+		fetched, err := c.MultiGet(ctx, href, missing)
+		if err != nil {
+			return SyncResult{}, err
+		}
+		res.Updated = append(res.Updated, fetched...)
+	}
+	return res, nil
+}
+
+// objects decodes the vCards in a REPORT's responses, skipping the address
+// book itself. It also returns the hrefs of changed vCards that came without
+// their address data.
+func (c *Client) objects(ms *multistatus, bookHref string) ([]Object, []string, error) {
+	var (
+		objects []Object
+		missing []string
+	)
+	for _, r := range ms.Responses {
+		if c.sameHref(r.Href, bookHref) || !isSuccess(r.Status) {
+			continue
+		}
+		p := r.ok()
+		if len(p.AddressData) == 0 {
+			missing = append(missing, r.Href)
+			continue
+		}
+		card, err := vcard.NewDecoder(strings.NewReader(p.AddressData)).Decode()
+		if err != nil {
+			return nil, nil, fmt.Errorf("carddav: %s: %w", r.Href, err)
+		}
+		objects = append(objects, Object{Href: r.Href, ETag: p.ETag, Card: card})
+	}
+	return objects, missing, nil
+}
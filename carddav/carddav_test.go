@@ -0,0 +1,396 @@
+package carddav
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	fakePrincipal = "/dav/principals/homer/"
+	fakeHome      = "/dav/addressbooks/homer/"
+)
+
+// fakeCardDAV is a stand-in for the subset of a CardDAV server the Client
+// uses: discovery, addressbook-query, addressbook-multiget and
+// sync-collection, behind basic authentication.
+type fakeCardDAV struct {
+	mu      sync.Mutex
+	version int
+	books   []*fakeBook
+	// omitSyncData leaves the vCards out of sync-collection replies, as
+	// some servers do.
+	omitSyncData bool
+	// rejectTokens fails every sync-collection as if its token expired.
+	rejectTokens bool
+	reports      []string
+}
+
+type fakeBook struct {
+	path    string
+	name    string
+	version int                 // of the book's latest change, its sync token
+	cards   map[string]fakeCard // by href
+	deleted map[string]int      // href -> version it was deleted at
+}
+
+type fakeCard struct {
+	data    string
+	version int
+}
+
+func newFakeCardDAV() *fakeCardDAV {
+	return &fakeCardDAV{}
+}
+
+func (f *fakeCardDAV) addBook(name string) *fakeBook {
+	return f.addBookAt(strings.ToLower(name), name)
+}
+
+// addBookAt adds a book called name under the home set at dir.
+func (f *fakeCardDAV) addBookAt(dir, name string) *fakeBook {
+	b := &fakeBook{path: fakeHome + dir + "/", name: name, cards: map[string]fakeCard{}, deleted: map[string]int{}}
+	f.books = append(f.books, b)
+	return b
+}
+
+// put adds or replaces the vCard called id in the book.
+func (f *fakeCardDAV) put(b *fakeBook, id, vcf string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.version++
+	b.version = f.version
+	b.cards[b.path+id+".vcf"] = fakeCard{data: vcf, version: f.version}
+	delete(b.deleted, b.path+id+".vcf")
+}
+
+func (f *fakeCardDAV) remove(b *fakeBook, id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.version++
+	b.version = f.version
+	delete(b.cards, b.path+id+".vcf")
+	b.deleted[b.path+id+".vcf"] = f.version
+}
+
+func (f *fakeCardDAV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if user, pass, ok := r.BasicAuth(); !ok || user != "homer" || pass != "donuts" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("Content-Type", `application/xml; charset="utf-8"`)
+	switch {
+	case r.Method == "PROPFIND" && r.URL.Path == "/":
+		writeMultistatus(w, "", fmt.Sprintf(`<d:response><d:href>/</d:href><d:propstat><d:prop><d:current-user-principal><d:href>%s</d:href></d:current-user-principal></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, fakePrincipal))
+	case r.Method == "PROPFIND" && r.URL.Path == fakePrincipal:
+		writeMultistatus(w, "", fmt.Sprintf(`<d:response><d:href>%s</d:href><d:propstat><d:prop><card:addressbook-home-set><d:href>%s</d:href></card:addressbook-home-set></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, fakePrincipal, fakeHome))
+	case r.Method == "PROPFIND" && r.URL.Path == fakeHome:
+		responses := fmt.Sprintf(`<d:response><d:href>%s</d:href><d:propstat><d:prop><d:resourcetype><d:collection/></d:resourcetype></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>`+
+			`<d:propstat><d:prop><d:displayname/></d:prop><d:status>HTTP/1.1 404 Not Found</d:status></d:propstat></d:response>`, fakeHome)
+		for _, b := range f.books {
+			responses += fmt.Sprintf(`<d:response><d:href>%s</d:href><d:propstat><d:prop><d:resourcetype><d:collection/><card:addressbook/></d:resourcetype><d:displayname>%s</d:displayname><d:sync-token>token-%d</d:sync-token></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, b.path, b.name, b.version)
+		}
+		writeMultistatus(w, "", responses)
+	case r.Method == "REPORT":
+		f.report(w, r.URL.Path, string(body))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+var hrefPattern = regexp.MustCompile(`<d:href>([^<]*)</d:href>`)
+
+func (f *fakeCardDAV) report(w http.ResponseWriter, path, body string) {
+	var book *fakeBook
+	for _, b := range f.books {
+		if b.path == path {
+			book = b
+		}
+	}
+	if book == nil {
+		http.NotFound(w, nil)
+		return
+	}
+
+	cardResponse := func(href string, withData bool) string {
+		c := book.cards[href]
+		data := ""
+		if withData {
+			data = "<card:address-data>" + xmlEscape(c.data) + "</card:address-data>"
+		}
+		return fmt.Sprintf(`<d:response><d:href>%s</d:href><d:propstat><d:prop><d:getetag>"%d"</d:getetag>%s</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, href, c.version, data)
+	}
+
+	var responses string
+	switch {
+	case strings.Contains(body, "addressbook-query"):
+		f.reports = append(f.reports, book.name+" query")
+		for _, href := range sortedKeys(book.cards) {
+			responses += cardResponse(href, true)
+		}
+		writeMultistatus(w, "", responses)
+	case strings.Contains(body, "addressbook-multiget"):
+		f.reports = append(f.reports, book.name+" multiget")
+		for _, m := range hrefPattern.FindAllStringSubmatch(body, -1) {
+			if _, ok := book.cards[m[1]]; ok {
+				responses += cardResponse(m[1], true)
+			}
+		}
+		writeMultistatus(w, "", responses)
+	case strings.Contains(body, "sync-collection"):
+		f.reports = append(f.reports, book.name+" sync")
+		token := regexp.MustCompile(`<d:sync-token>token-(\d+)</d:sync-token>`).FindStringSubmatch(body)
+		if f.rejectTokens || token == nil {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><d:error xmlns:d="DAV:"><d:valid-sync-token/></d:error>`)
+			return
+		}
+		since, _ := strconv.Atoi(token[1])
+		for _, href := range sortedKeys(book.cards) {
+			if book.cards[href].version > since {
+				responses += cardResponse(href, !f.omitSyncData)
+			}
+		}
+		for href, v := range book.deleted {
+			if v > since {
+				responses += fmt.Sprintf(`<d:response><d:href>%s</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>`, href)
+			}
+		}
+		writeMultistatus(w, fmt.Sprintf("token-%d", book.version), responses)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func writeMultistatus(w http.ResponseWriter, syncToken, responses string) {
+	w.WriteHeader(http.StatusMultiStatus)
+	fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><d:multistatus xmlns:d="DAV:" xmlns:card="urn:ietf:params:xml:ns:carddav">`, responses)
+	if len(syncToken) > 0 {
+		fmt.Fprintf(w, "<d:sync-token>%s</d:sync-token>", syncToken)
+	}
+	fmt.Fprint(w, "</d:multistatus>")
+}
+
+func xmlEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func sortedKeys(m map[string]fakeCard) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func fakeVCard(fn, street, categories string) string {
+	return "BEGIN:VCARD\r\nVERSION:3.0\r\nFN:" + fn + "\r\nN:" + strings.Join(strings.Fields(fn)[1:], " ") + ";" + strings.Fields(fn)[0] + ";;;\r\n" +
+		"ADR;TYPE=home:;;" + street + ";Springfield;IL;62701;\r\nCATEGORIES:" + categories + "\r\nEND:VCARD\r\n"
+}
+
+func newTestServer(t *testing.T) (*fakeCardDAV, *fakeBook, *Client) {
+	t.Helper()
+	f := newFakeCardDAV()
+	family := f.addBook("Family")
+	f.addBook("Work")
+	f.put(family, "homer", fakeVCard("Homer Simpson", "742 Evergreen Terrace", "Xmas Card"))
+	f.put(family, "marge", fakeVCard("Marge Simpson", "742 Evergreen Terrace", "Xmas Card"))
+	f.put(family, "ned", fakeVCard("Ned Flanders", "740 Evergreen Terrace", "Xmas Card,Church"))
+
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	c, err := NewClient(srv.URL+"/", "homer", "donuts")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.HTTPClient = srv.Client()
+	return f, family, c
+}
+
+func TestFindAddressBooks(t *testing.T) {
+	_, _, c := newTestServer(t)
+
+	got, err := c.FindAddressBooks(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []AddressBook{
+		{Href: fakeHome + "family/", Name: "Family", SyncToken: "token-3"},
+		{Href: fakeHome + "work/", Name: "Work", SyncToken: "token-0"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("FindAddressBooks() mismatch (-want +got):\n%s", diff)
+	}
+
+	c.Password = "duff"
+	if _, err := c.FindAddressBooks(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("unexpected error, got: %v, want: a 401", err)
+	}
+}
+
+func TestQueryAddressBook(t *testing.T) {
+	_, family, c := newTestServer(t)
+
+	objects, err := c.QueryAddressBook(context.Background(), family.path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, o := range objects {
+		got = append(got, o.Href+" "+o.ETag+" "+o.Card.Value("FN"))
+	}
+	want := []string{
+		family.path + `homer.vcf "1" Homer Simpson`,
+		family.path + `marge.vcf "2" Marge Simpson`,
+		family.path + `ned.vcf "3" Ned Flanders`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("QueryAddressBook() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		Name         string
+		OmitSyncData bool
+		RejectTokens bool
+		WantReports  []string
+	}{
+		{"sync", false, false, []string{"Family query", "Work query", "Family sync"}},
+		{"sync without address data", true, false, []string{"Family query", "Work query", "Family sync", "Family multiget"}},
+		{"expired sync token", false, true, []string{"Family query", "Work query", "Family sync", "Family query"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			f, family, c := newTestServer(t)
+			f.omitSyncData, f.rejectTokens = test.OmitSyncData, test.RejectTokens
+			src := NewSource(c)
+			ctx := context.Background()
+
+			groups, err := src.ContactGroups(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var names []string
+			for _, g := range groups {
+				names = append(names, fmt.Sprintf("%s (%d)", g.Name, g.MemberCount))
+			}
+			if diff := cmp.Diff([]string{"All contacts (3)", "Church (1)", "Family (3)", "Xmas Card (3)"}, names); diff != "" {
+				t.Errorf("ContactGroups() mismatch (-want +got):\n%s", diff)
+			}
+
+			// Marge moves out and Ned leaves the address book
+			f.put(family, "marge", fakeVCard("Marge Simpson", "1 Spalding Way", "Xmas Card"))
+			f.remove(family, "ned")
+			// the Work address book hasn't changed, so isn't read again
+			if _, err := src.ContactGroups(ctx); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			res, err := cohabitaters.GetXmasCards(ctx, src, "addressbook/groups/Xmas Card", cohabitaters.Options{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, card := range res.Cards {
+				got = append(got, card.Addressee+", "+card.Address.StreetAddress)
+			}
+			if diff := cmp.Diff([]string{"Homer Simpson, 742 Evergreen Terrace", "Marge Simpson, 1 Spalding Way"}, got); diff != "" {
+				t.Errorf("GetXmasCards() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.WantReports, f.reports); diff != "" {
+				t.Errorf("REPORT requests mismatch (-want +got):\n%s", diff)
+			}
+
+			contacts, err := src.Contacts(ctx, []string{family.path + "homer.vcf"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff([]string{"addressbook/groups/Xmas Card"}, contacts[0].Memberships); diff != "" {
+				t.Errorf("Memberships mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSourceRestore(t *testing.T) {
+	f, family, c := newTestServer(t)
+	ctx := context.Background()
+
+	first := NewSource(c)
+	if err := first.Refresh(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// as a later run would read it back
+	bs, err := json.Marshal(first.State())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var st State
+	if err := json.Unmarshal(bs, &st); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	f.put(family, "marge", fakeVCard("Marge Simpson", "1 Spalding Way", "Xmas Card"))
+	f.reports = nil
+	second := NewSource(c)
+	second.Restore(st)
+	res, err := cohabitaters.GetXmasCards(ctx, second, "addressbook/groups/Xmas Card", cohabitaters.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Cards) != 3 {
+		t.Errorf("unexpected card count, got: %v, want: 3", len(res.Cards))
+	}
+	if diff := cmp.Diff([]string{"Family sync"}, f.reports); diff != "" {
+		t.Errorf("REPORT requests mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSourceSameName(t *testing.T) {
+	f, _, c := newTestServer(t)
+	inlaws := f.addBookAt("inlaws", "Family")
+	f.put(inlaws, "patty", fakeVCard("Patty Bouvier", "1 Spinster Lane", "Xmas Card"))
+	ctx := context.Background()
+
+	src := NewSource(c)
+	groups, err := src.ContactGroups(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, g := range groups {
+		names = append(names, fmt.Sprintf("%s (%d)", g.Name, g.MemberCount))
+	}
+	want := []string{"All contacts (4)", "Church (1)", "Family (family) (3)", "Family (inlaws) (1)", "Xmas Card (4)"}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Errorf("ContactGroups() mismatch (-want +got):\n%s", diff)
+	}
+
+	members, err := src.GroupMembers(ctx, "addressbook/groups/"+inlaws.path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{inlaws.path + "patty.vcf"}, members); diff != "" {
+		t.Errorf("GroupMembers() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,288 @@
+package carddav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/bfallik/cohabitaters/addressbook"
+	"github.com/bfallik/cohabitaters/vcard"
+)
+
+// Source is a cohabitaters.ContactSource over every address book on a
+// CardDAV server. Each address book is a contact group named after it, as
+// is each vCard category, along with "All contacts". Contacts are named by
+// their href, and so are the groups of address books, so two address books
+// with the same name stay apart.
+//
+// The vCards are kept between calls and brought up to date with each
+// address book's sync token, so reading the groups again only transfers what
+// changed. State and Restore carry them over to a later process.
+type Source struct {
+	client *Client
+
+	mu         sync.Mutex
+	books      map[string]*bookState // by href
+	book       *addressbook.Book
+	bookGroups map[string]string // resource names of the groups for address books -> their names
+}
+
+type bookState struct {
+	AddressBook
+	objects map[string]Object // by href
+}
+
+// State is what a Source has read of the address books on a server: each
+// one's sync token and vCards.
+type State struct {
+	Books []BookState
+}
+
+type BookState struct {
+	AddressBook
+	Objects []Object
+}
+
+var _ cohabitaters.AllContactsSource = (*Source)(nil)
+
+func NewSource(client *Client) *Source {
+	return &Source{client: client, books: map[string]*bookState{}}
+}
+
+// bookGroup returns the resource name of the group of an address book.
+func bookGroup(ab AddressBook) string {
+	return addressbook.GroupResourceName(ab.Href)
+}
+
+// State returns the address books as of the last Refresh, for Restore to
+// pick up from.
+func (s *Source) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hrefs := make([]string, 0, len(s.books))
+	for href := range s.books {
+		hrefs = append(hrefs, href)
+	}
+	sort.Strings(hrefs)
+
+	var st State
+	for _, href := range hrefs {
+		b := s.books[href]
+		bs := BookState{AddressBook: b.AddressBook}
+		for _, o := range b.objects {
+			bs.Objects = append(bs.Objects, o)
+		}
+		sort.Slice(bs.Objects, func(i, j int) bool { return bs.Objects[i].Href < bs.Objects[j].Href })
+		st.Books = append(st.Books, bs)
+	}
+	return st
+}
+
+// Restore takes up the address books as a State saved them, so the next
+// Refresh only fetches what changed since.
+func (s *Source) Restore(st State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.books = make(map[string]*bookState, len(st.Books))
+	for _, bs := range st.Books {
+		b := &bookState{AddressBook: bs.AddressBook, objects: make(map[string]Object, len(bs.Objects))}
+		for _, o := range bs.Objects {
+			b.objects[o.Href] = o
+		}
+		s.books[bs.Href] = b
+	}
+	s.book = nil
+}
+
+// Refresh fetches the address books and what changed in them since the last
+// Refresh. An address book without a sync token, or whose token the server
+// has stopped accepting, is read in full.
+func (s *Source) Refresh(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found, err := s.client.FindAddressBooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	books := make(map[string]*bookState, len(found))
+	for _, ab := range found {
+		st, ok := s.books[ab.Href]
+		if !ok {
+			st = &bookState{}
+		}
+		if err := s.refreshBook(ctx, st, ab); err != nil {
+			return err
+		}
+		books[ab.Href] = st
+	}
+	s.books = books
+	s.book = s.addressBook(found)
+
+	named := map[string]int{}
+	for _, ab := range found {
+		named[ab.Name]++
+	}
+	s.bookGroups = map[string]string{}
+	for _, ab := range found {
+		name := ab.Name
+		if named[name] > 1 {
+			// tell address books of the same name apart by their paths
+			name = fmt.Sprintf("%s (%s)", name, path.Base(ab.Href))
+		}
+		s.bookGroups[bookGroup(ab)] = name
+	}
+	return nil
+}
+
+func (s *Source) refreshBook(ctx context.Context, st *bookState, ab AddressBook) error {
+	prevToken := st.SyncToken
+	st.AddressBook = ab
+	if st.objects != nil && len(prevToken) > 0 && prevToken == ab.SyncToken {
+		return nil // nothing changed
+	}
+
+	if st.objects != nil && len(prevToken) > 0 {
+		res, err := s.client.SyncAddressBook(ctx, ab.Href, prevToken)
+		if err == nil {
+			for _, href := range res.Deleted {
+				delete(st.objects, href)
+			}
+			for _, o := range res.Updated {
+				st.objects[o.Href] = o
+			}
+			if len(res.SyncToken) > 0 {
+				st.SyncToken = res.SyncToken
+			}
+			return nil
+		}
+		if !errors.Is(err, ErrInvalidSyncToken) {
+			return err
+		}
+	}
+
+	objects, err := s.client.QueryAddressBook(ctx, ab.Href)
+	if err != nil {
+		return err
+	}
+	st.objects = make(map[string]Object, len(objects))
+	for _, o := range objects {
+		st.objects[o.Href] = o
+	}
+	return nil
+}
+
+// addressBook gathers the vCards of every address book into one Book, in
+// the order the server listed the address books.
+func (s *Source) addressBook(found []AddressBook) *addressbook.Book {
+	var contacts []cohabitaters.Contact
+	for _, ab := range found {
+		st := s.books[ab.Href]
+		names := make([]string, 0, len(st.objects))
+		for href := range st.objects {
+			names = append(names, href)
+		}
+		sort.Strings(names)
+		cards := make([]vcard.Card, len(names))
+		for i, href := range names {
+			cards[i] = st.objects[href].Card
+		}
+		for _, c := range addressbook.VCardContacts(cards, names) {
+			c.Memberships = append(c.Memberships, bookGroup(ab))
+			contacts = append(contacts, c)
+		}
+	}
+	return addressbook.New(contacts)
+}
+
+// current returns the contacts as of the last Refresh, refreshing first if
+// there hasn't been one.
+func (s *Source) current(ctx context.Context) (*addressbook.Book, error) {
+	s.mu.Lock()
+	book := s.book
+	s.mu.Unlock()
+	if book != nil {
+		return book, nil
+	}
+	if err := s.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.book, nil
+}
+
+// ContactGroups refreshes the contacts and lists their groups: "All
+// contacts" and then the others by name.
+func (s *Source) ContactGroups(ctx context.Context) ([]cohabitaters.ContactGroup, error) {
+	if err := s.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	book, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := book.ContactGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]cohabitaters.ContactGroup, len(groups))
+	for i, g := range groups {
+		if name, ok := s.bookGroups[g.ResourceName]; ok {
+			g.Name, g.FormattedName = name, name
+		}
+		out[i] = g
+	}
+	sort.SliceStable(out[1:], func(i, j int) bool { return out[i+1].Name < out[j+1].Name })
+	return out, nil
+}
+
+// AllContactsGroup returns the group of every contact in every address book.
+func (s *Source) AllContactsGroup() string {
+	return addressbook.AllContacts
+}
+
+func (s *Source) GroupMembers(ctx context.Context, groupResourceName string) ([]string, error) {
+	book, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return book.GroupMembers(ctx, groupResourceName)
+}
+
+// Contacts returns the named contacts. Like Google's built-in groups, an
+// address book says nothing about who lives together, so they're left out
+// of Memberships.
+func (s *Source) Contacts(ctx context.Context, resourceNames []string) ([]cohabitaters.Contact, error) {
+	book, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	contacts, err := book.Contacts(ctx, resourceNames)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, c := range contacts {
+		var memberships []string
+		for _, g := range c.Memberships {
+			if _, ok := s.bookGroups[g]; !ok {
+				memberships = append(memberships, g)
+			}
+		}
+		contacts[i].Memberships = memberships
+	}
+	return contacts, nil
+}
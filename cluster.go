@@ -0,0 +1,369 @@
+package cohabitaters
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bfallik/cohabitaters/normalize"
+)
+
+// unionFind is a disjoint-set forest over the integers [0, n).
+type unionFind struct {
+	parent []int
+	rank   []int
+}
+
+func newUnionFind(n int) *unionFind {
+	uf := &unionFind{parent: make([]int, n), rank: make([]int, n)}
+	for i := range uf.parent {
+		uf.parent[i] = i
+	}
+	return uf
+}
+
+func (uf *unionFind) find(x int) int {
+	for uf.parent[x] != x {
+		uf.parent[x] = uf.parent[uf.parent[x]]
+		x = uf.parent[x]
+	}
+	return x
+}
+
+func (uf *unionFind) union(x, y int) {
+	rx, ry := uf.find(x), uf.find(y)
+	switch {
+	case rx == ry:
+		return
+	case uf.rank[rx] < uf.rank[ry]:
+		rx, ry = ry, rx
+	case uf.rank[rx] == uf.rank[ry]:
+		uf.rank[rx]++
+	}
+	uf.parent[ry] = rx
+}
+
+// pairIndex narrows down the pairs of contacts Coalesce scores. Scoring
+// every pair is quadratic in the size of the address book, so a contact is
+// only compared with those sharing its postal code, city or street address,
+// and with those its household relations name or that name it. A contact
+// with neither a city nor a postal code can't be placed that way and is
+// compared with every other. Only addresses that differ in the spelling of
+// both their street and their city, with no postal code in common, go
+// uncompared.
+type pairIndex struct {
+	blocks  map[string][]int // block key -> members, in order
+	keys    [][]string       // each member's block keys
+	loose   []int            // members without a city or postal code, in order
+	isLoose []bool
+	related [][]int // members each member may be related to
+	mark    []int   // one more than the member each was last a candidate of
+}
+
+// blockKeys returns the keys of the blocks an address belongs to, and
+// whether it has a city or postal code to narrow it down. A ZIP code and its
+// ZIP+4 share a block, as do addresses in the same city and those with the
+// same house number and street; the region is left out since one of a pair
+// often lacks it, and conflict tells regions apart anyway.
+func blockKeys(p preparedAddress) ([]string, bool) {
+	var keys []string
+	if len(p.postalCode) > 0 {
+		keys = append(keys, "postal code "+p.postalCode[:min(5, len(p.postalCode))])
+	}
+	if len(p.city) > 0 {
+		keys = append(keys, "city "+strings.Join(p.city, " "))
+	}
+	located := len(keys) > 0
+	if len(p.street) > 0 {
+		keys = append(keys, "street "+strings.TrimSpace(p.houseNumber+" "+strings.Join(p.street, " ")))
+	}
+	return keys, located
+}
+
+func newPairIndex(prepared []preparedAddress, sources []Contact) *pairIndex {
+	x := &pairIndex{
+		blocks:  map[string][]int{},
+		keys:    make([][]string, len(prepared)),
+		isLoose: make([]bool, len(prepared)),
+		related: make([][]int, len(prepared)),
+		mark:    make([]int, len(prepared)),
+	}
+	for i, p := range prepared {
+		var located bool
+		x.keys[i], located = blockKeys(p)
+		for _, key := range x.keys[i] {
+			x.blocks[key] = append(x.blocks[key], i)
+		}
+		if !located {
+			x.loose = append(x.loose, i)
+			x.isLoose[i] = true
+		}
+	}
+
+	// every way refersTo may match a relation to a contact
+	byName := map[string][]int{}
+	for i, c := range sources {
+		for _, n := range c.Names {
+			for _, name := range []string{n.DisplayName, n.GivenName + " " + n.FamilyName, n.GivenName} {
+				if key := normalize.Text(name); len(key) > 0 {
+					byName[key] = append(byName[key], i)
+				}
+			}
+		}
+	}
+	for i, c := range sources {
+		for _, r := range c.Relations {
+			if !householdRelations[strings.ToLower(strings.TrimSpace(r.Type))] {
+				continue
+			}
+			for _, j := range byName[normalize.Text(r.Person)] {
+				if j != i {
+					x.related[i] = append(x.related[i], j)
+					x.related[j] = append(x.related[j], i)
+				}
+			}
+		}
+	}
+	return x
+}
+
+// candidates returns, in order, the members after i to score against it,
+// reusing buf.
+func (x *pairIndex) candidates(i int, buf []int) []int {
+	out := buf[:0]
+	add := func(j int) {
+		if j > i && x.mark[j] != i+1 {
+			x.mark[j] = i + 1
+			out = append(out, j)
+		}
+	}
+	if x.isLoose[i] {
+		for j := i + 1; j < len(x.mark); j++ {
+			add(j)
+		}
+		return out
+	}
+	for _, key := range x.keys[i] {
+		block := x.blocks[key]
+		k, _ := slices.BinarySearch(block, i+1)
+		for _, j := range block[k:] {
+			add(j)
+		}
+	}
+	k, _ := slices.BinarySearch(x.loose, i+1)
+	for _, j := range x.loose[k:] {
+		add(j)
+	}
+	for _, j := range x.related[i] {
+		add(j)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// HouseholdOverrides correct the households Coalesce would otherwise find.
+type HouseholdOverrides struct {
+	// Merges are pairs of contact resource names that share a card no
+	// matter how their addresses compare.
+	Merges [][2]string
+	// Splits are contact resource names that are never merged with
+	// another contact by address; only Merges can add them to a card.
+	Splits map[string]bool
+}
+
+// Coalesce groups contacts that share a home address into XmasCards.
+//
+// Contacts sharing a postal code, city or street address, or related to each
+// other, are scored in pairs with opts.Matcher, as are contacts with neither
+// a city nor a postal code; other pairs can hardly match and aren't compared,
+// which keeps large address books fast. Pairs merge when their addresses
+// match, when one declares a household relation such as spouse or partner to
+// the other, or when they share a contact group and score within the review
+// margin. Relations merged despite differing addresses are
+// flagged for review, but never outweigh a different house number, unit,
+// postal code or country. Merges are transitive, so a contact lands on exactly
+// one card. Contacts are ordered by resource name first which makes the
+// output independent of input order. Contacts that can't be placed on a card
+// are reported in Result.Skipped along with the reason. opts.Households is
+// applied on top of the matching.
+func Coalesce(contacts []Contact, opts Options) (Result, error) {
+	sorted := make([]Contact, len(contacts))
+	copy(sorted, contacts)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ResourceName < sorted[j].ResourceName })
+
+	var (
+		members []CardContact
+		sources []Contact // the Contact behind each member
+		skipped []SkippedContact
+	)
+	policy := opts.policy()
+	seen := map[string]bool{}
+	for _, c := range sorted {
+		if len(c.ResourceName) > 0 && seen[c.ResourceName] {
+			continue // ignore duplicates
+		}
+		seen[c.ResourceName] = true
+
+		skip := SkippedContact{ResourceName: c.ResourceName, Addresses: c.Addresses}
+		if len(c.FetchError) > 0 {
+			skip.Reason, skip.Detail = SkipAPIError, c.FetchError
+			skipped = append(skipped, skip)
+			continue
+		}
+		if len(c.Names) == 0 {
+			skip.Reason = SkipNoName
+			skipped = append(skipped, skip)
+			continue
+		}
+
+		name := c.Names[0].DisplayName
+		skip.Name = name
+		homeAddr, err := policy.PickAddress(c)
+		if errors.Is(err, ErrAmbiguousAddress) {
+			skip.Reason = SkipAmbiguousAddress
+			skipped = append(skipped, skip)
+			continue
+		}
+		if err != nil {
+			return Result{}, fmt.Errorf("error picking home address for %s: %w", name, err)
+		}
+		if homeAddr == nil {
+			skip.Reason = SkipNoAddress
+			skipped = append(skipped, skip)
+			continue
+		}
+
+		members = append(members, CardContact{
+			ResourceName: c.ResourceName,
+			Name:         name,
+			GivenName:    strings.TrimSpace(c.Names[0].GivenName),
+			FamilyName:   strings.TrimSpace(c.Names[0].FamilyName),
+			Address:      homeAddr.Address,
+			Account:      c.Account,
+		})
+		sources = append(sources, c)
+	}
+
+	prepared := make([]preparedAddress, len(members))
+	for i, m := range members {
+		prepared[i] = prepare(m.Address)
+	}
+
+	type edge struct {
+		i, j  int
+		match Match
+	}
+
+	splits := opts.Households.Splits
+	uf := newUnionFind(len(members))
+	index := newPairIndex(prepared, sources)
+	var merges, nearMisses []edge
+	var later []int
+	for i := range members {
+		if splits[members[i].ResourceName] {
+			continue
+		}
+		later = index.candidates(i, later)
+		for _, j := range later {
+			if splits[members[j].ResourceName] {
+				continue
+			}
+			score := compare(prepared[i], prepared[j])
+			merge, review := opts.Matcher.decide(score)
+			reason := score.String()
+			if !merge {
+				if rel := relatedBy(sources[i], sources[j]); len(rel) > 0 && relationOutweighs(score.Conflict) {
+					// a declared household keeps together even when one
+					// address is stale, but someone should check which
+					merge, review = true, true
+					reason = fmt.Sprintf("%s with different addresses, %s", rel, reason)
+				} else if review && sharesGroup(sources[i], sources[j]) {
+					merge = true
+					reason = "shared group, " + reason
+				}
+			}
+			if !merge && !review {
+				continue
+			}
+
+			e := edge{i: i, j: j, match: Match{
+				A:      members[i].ResourceName,
+				B:      members[j].ResourceName,
+				Score:  score,
+				Reason: reason,
+				Review: review,
+			}}
+			if merge {
+				uf.union(i, j)
+				merges = append(merges, e)
+			} else {
+				nearMisses = append(nearMisses, e)
+			}
+		}
+	}
+
+	memberIdx := make(map[string]int, len(members))
+	for i, m := range members {
+		memberIdx[m.ResourceName] = i
+	}
+	for _, pair := range opts.Households.Merges {
+		i, okI := memberIdx[pair[0]]
+		j, okJ := memberIdx[pair[1]]
+		if !okI || !okJ || uf.find(i) == uf.find(j) {
+			continue // already together, or a contact isn't on any card
+		}
+		if i > j {
+			i, j = j, i
+		}
+		score := compare(prepared[i], prepared[j])
+		uf.union(i, j)
+		merges = append(merges, edge{i: i, j: j, match: Match{
+			A:      members[i].ResourceName,
+			B:      members[j].ResourceName,
+			Score:  score,
+			Reason: "merged by hand",
+			Manual: true,
+		}})
+	}
+
+	var cards []XmasCard
+	cardIdx := map[int]int{} // union-find root -> index into cards
+	for i, m := range members {
+		root := uf.find(i)
+		idx, ok := cardIdx[root]
+		if !ok {
+			idx = len(cards)
+			cardIdx[root] = idx
+			cards = append(cards, XmasCard{Address: m.Address})
+		}
+		if !slices.Contains(cards[idx].Names, m.Name) {
+			// e.g. the same person kept in two linked accounts
+			cards[idx].Names = append(cards[idx].Names, m.Name)
+		}
+		cards[idx].Contacts = append(cards[idx].Contacts, m)
+	}
+
+	for idx := range cards {
+		cards[idx].Addressee = Salutation(opts.Salutation, cards[idx].Contacts)
+		cards[idx].AddressLines = cards[idx].Address.Lines(opts.SenderCountry)
+	}
+
+	for _, e := range merges {
+		idx := cardIdx[uf.find(e.i)]
+		cards[idx].Matches = append(cards[idx].Matches, e.match)
+	}
+
+	for _, e := range nearMisses {
+		idxI, idxJ := cardIdx[uf.find(e.i)], cardIdx[uf.find(e.j)]
+		if idxI == idxJ {
+			continue // merged anyway through other contacts
+		}
+		cards[idxI].NearMisses = append(cards[idxI].NearMisses, e.match)
+		cards[idxJ].NearMisses = append(cards[idxJ].NearMisses, e.match)
+	}
+
+	SortCards(cards, opts.Sort, opts.SenderCountry)
+	return Result{Cards: cards, Skipped: skipped, Subtotals: Subtotal(cards, opts.SenderCountry)}, nil
+}
@@ -0,0 +1,403 @@
+package cohabitaters
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnionFind(t *testing.T) {
+	uf := newUnionFind(5)
+	uf.union(0, 1)
+	uf.union(3, 4)
+	uf.union(1, 4)
+
+	for _, i := range []int{1, 3, 4} {
+		if uf.find(i) != uf.find(0) {
+			t.Errorf("expected %d in the same set as 0", i)
+		}
+	}
+	if uf.find(2) == uf.find(0) {
+		t.Errorf("unexpected 2 in the same set as 0")
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	contacts := []Contact{
+		homeContact("people/1", "Homer Simpson", mainStreet),
+		homeContact("people/2", "Marge Simpson", mainStreet),
+		homeContact("people/3", "Ned Flanders", elmStreet),
+		{ResourceName: "people/4", Addresses: []ContactAddress{{Address: mainStreet}}},
+		{ResourceName: "people/5", Names: []Name{{DisplayName: "No Address"}}},
+		{ResourceName: "people/6", FetchError: "not found"},
+	}
+
+	res, err := Coalesce(contacts, Options{SenderCountry: "US"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := res.Cards
+
+	want := []XmasCard{
+		{
+			Names:        []string{"Homer Simpson", "Marge Simpson"},
+			Addressee:    "Homer Simpson & Marge Simpson",
+			Address:      mainStreet,
+			AddressLines: []string{"123 Main Street", "SPRINGFIELD, IL 62701"},
+			Contacts: []CardContact{
+				{ResourceName: "people/1", Name: "Homer Simpson", Address: mainStreet},
+				{ResourceName: "people/2", Name: "Marge Simpson", Address: mainStreet},
+			},
+			Matches: []Match{{A: "people/1", B: "people/2", Score: Score{Street: 1, City: 1}, Reason: "street 1.00, city 1.00"}},
+		},
+		{
+			Names:        []string{"Ned Flanders"},
+			Addressee:    "Ned Flanders",
+			Address:      elmStreet,
+			AddressLines: []string{"9 Elm Street", "SHELBYVILLE, IL 62565"},
+			Contacts:     []CardContact{{ResourceName: "people/3", Name: "Ned Flanders", Address: elmStreet}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Coalesce() mismatch (-want +got):\n%s", diff)
+	}
+
+	wantSkipped := []SkippedContact{
+		{ResourceName: "people/4", Reason: SkipNoName, Addresses: []ContactAddress{{Address: mainStreet}}},
+		{ResourceName: "people/5", Name: "No Address", Reason: SkipNoAddress},
+		{ResourceName: "people/6", Reason: SkipAPIError, Detail: "not found"},
+	}
+	if diff := cmp.Diff(wantSkipped, res.Skipped); diff != "" {
+		t.Errorf("Skipped mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCoalesceTransitive(t *testing.T) {
+	// a and c only match each other through b
+	a := Address{StreetAddress: "1 Brookhaven", City: "Springfield"}
+	b := Address{StreetAddress: "1 Brookhavan", City: "Springfield"}
+	c := Address{StreetAddress: "1 Brookhovan", City: "Springfield"}
+
+	res, err := Coalesce([]Contact{
+		homeContact("people/a", "A", a),
+		homeContact("people/c", "C", c),
+		homeContact("people/b", "B", b),
+	}, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := res.Cards
+
+	if len(got) != 1 {
+		t.Fatalf("unexpected card count, got: %v, want: 1", len(got))
+	}
+	if diff := cmp.Diff([]string{"A", "B", "C"}, got[0].Names); diff != "" {
+		t.Errorf("Names mismatch (-want +got):\n%s", diff)
+	}
+	if len(got[0].Matches) != 2 {
+		t.Errorf("unexpected match count, got: %v, want: 2", len(got[0].Matches))
+	}
+	if !got[0].NeedsReview() {
+		t.Errorf("expected card to need review")
+	}
+}
+
+func TestCoalesceStreetless(t *testing.T) {
+	// a contact known only by city mustn't chain the households there
+	res, err := Coalesce([]Contact{
+		homeContact("people/1", "A", Address{StreetAddress: "123 Main St", City: "Boston"}),
+		homeContact("people/2", "B", Address{StreetAddress: "9 Elm St", City: "Boston"}),
+		homeContact("people/3", "C", Address{StreetAddress: "14 Oak Ave", City: "Boston", PostalCode: "02101"}),
+		homeContact("people/4", "D", Address{City: "Boston"}),
+	}, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got [][]string
+	for _, card := range res.Cards {
+		got = append(got, card.Names)
+	}
+	want := [][]string{{"A"}, {"B"}, {"C"}, {"D"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Coalesce() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCoalesceMatchesPairwise(t *testing.T) {
+	addrs := []Address{
+		{StreetAddress: "123 Main St", City: "Springfield"},
+		{StreetAddress: "123 Main Street", PostalCode: "12345"},
+		{StreetAddress: "9 Elm St", City: "Springfield", Region: "IL"},
+		{StreetAddress: "9 Elm St", City: "Springfeld", Region: "IL"},
+		{StreetAddress: "57 Walnut Street"},
+		{StreetAddress: "57 Walnut St"},
+		{StreetAddress: "14 Oak Ave", City: "Shelbyville", PostalCode: "62565"},
+		{StreetAddress: "14 Oak Avenue", City: "Shelbyville"},
+		{StreetAddress: "14 Oak Ave", City: "Capital City", PostalCode: "62701"},
+	}
+	var contacts []Contact
+	for i, a := range addrs {
+		contacts = append(contacts, homeContact(fmt.Sprintf("people/%d", i), fmt.Sprintf("Contact %d", i), a))
+	}
+
+	var m Matcher
+	uf := newUnionFind(len(addrs))
+	for i := range addrs {
+		for j := i + 1; j < len(addrs); j++ {
+			if merge, _ := m.Match(addrs[i], addrs[j]); merge {
+				uf.union(i, j)
+			}
+		}
+	}
+	res, err := Coalesce(contacts, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	card := map[string]int{} // resource name -> index into res.Cards
+	for idx, c := range res.Cards {
+		for _, m := range c.Contacts {
+			card[m.ResourceName] = idx
+		}
+	}
+	for i := range contacts {
+		for j := i + 1; j < len(contacts); j++ {
+			want := uf.find(i) == uf.find(j)
+			got := card[contacts[i].ResourceName] == card[contacts[j].ResourceName]
+			if got != want {
+				t.Errorf("%q and %q on one card, got: %v, want: %v", addrs[i].StreetAddress, addrs[j].StreetAddress, got, want)
+			}
+		}
+	}
+	if len(res.Cards) != 5 {
+		t.Errorf("unexpected card count, got: %v, want: 5", len(res.Cards))
+	}
+}
+
+func TestCoalesceNearMiss(t *testing.T) {
+	a := Address{StreetAddress: "1 Brookhaven", City: "Springfield"}
+	b := Address{StreetAddress: "1 Brookhoven", City: "Springfield"}
+
+	res, err := Coalesce([]Contact{
+		homeContact("people/a", "A", a),
+		homeContact("people/b", "B", b),
+	}, Options{Matcher: Matcher{Threshold: 0.95}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := res.Cards
+
+	if len(got) != 2 {
+		t.Fatalf("unexpected card count, got: %v, want: 2", len(got))
+	}
+	for _, card := range got {
+		if len(card.NearMisses) != 1 || !card.NeedsReview() {
+			t.Errorf("expected card %v to record a near miss", card.Names)
+		}
+	}
+}
+
+func TestCoalesceHouseholdOverrides(t *testing.T) {
+	contacts := []Contact{
+		homeContact("people/1", "Homer Simpson", mainStreet),
+		homeContact("people/2", "Marge Simpson", mainStreet),
+		homeContact("people/3", "Bart Simpson", mainStreet),
+		homeContact("people/4", "Ned Flanders", elmStreet),
+	}
+
+	tests := []struct {
+		Desc       string
+		Households HouseholdOverrides
+		Want       [][]string
+	}{
+		{
+			Desc: "none",
+			Want: [][]string{{"Homer Simpson", "Marge Simpson", "Bart Simpson"}, {"Ned Flanders"}},
+		},
+		{
+			Desc:       "split",
+			Households: HouseholdOverrides{Splits: map[string]bool{"people/3": true}},
+			Want:       [][]string{{"Homer Simpson", "Marge Simpson"}, {"Bart Simpson"}, {"Ned Flanders"}},
+		},
+		{
+			Desc:       "merge",
+			Households: HouseholdOverrides{Merges: [][2]string{{"people/4", "people/1"}}},
+			Want:       [][]string{{"Homer Simpson", "Marge Simpson", "Bart Simpson", "Ned Flanders"}},
+		},
+		{
+			Desc: "merge beats split",
+			Households: HouseholdOverrides{
+				Merges: [][2]string{{"people/3", "people/4"}},
+				Splits: map[string]bool{"people/3": true},
+			},
+			Want: [][]string{{"Homer Simpson", "Marge Simpson"}, {"Bart Simpson", "Ned Flanders"}},
+		},
+		{
+			Desc:       "merge with a missing contact",
+			Households: HouseholdOverrides{Merges: [][2]string{{"people/4", "people/404"}}},
+			Want:       [][]string{{"Homer Simpson", "Marge Simpson", "Bart Simpson"}, {"Ned Flanders"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			res, err := Coalesce(contacts, Options{Households: test.Households})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got [][]string
+			for _, card := range res.Cards {
+				got = append(got, card.Names)
+			}
+			if diff := cmp.Diff(test.Want, got); diff != "" {
+				t.Errorf("Coalesce() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCoalesceRelations(t *testing.T) {
+	stale := Address{StreetAddress: "Evergreen Terrace", City: "Springfield", Region: "IL"}
+	nextDoor := Address{StreetAddress: "11 Elm Street", City: "Shelbyville", Region: "IL"}
+	ownPlace := Address{StreetAddress: "5 Oak Avenue", City: "Capital City", Region: "IL"}
+	nearby := Address{StreetAddress: "1 Brookhaven", City: "Springfield"}
+	nearbyTypo := Address{StreetAddress: "1 Brookhoven", City: "Springfield"}
+
+	homer := homeContact("people/1", "Homer Simpson", mainStreet)
+	marge := homeContact("people/2", "Marge Simpson", stale)
+	marge.Relations = []Relation{{Person: "Homer Simpson", Type: "spouse"}}
+	ned := homeContact("people/3", "Ned Flanders", elmStreet)
+	ned.Relations = []Relation{{Person: "Homer Simpson", Type: "friend"}}
+	lenny := homeContact("people/4", "Lenny Leonard", nearby)
+	lenny.Memberships = []string{"contactGroups/plant"}
+	carl := homeContact("people/5", "Carl Carlson", nearbyTypo)
+	carl.Memberships = []string{"contactGroups/plant"}
+	maude := homeContact("people/6", "Maude Flanders", nextDoor)
+	maude.Relations = []Relation{{Person: "Ned Flanders", Type: "spouse"}}
+	rod := homeContact("people/7", "Rod Flanders", ownPlace)
+	rod.Relations = []Relation{{Person: "Ned Flanders", Type: "child"}}
+	kirk := homeContact("people/8", "Kirk Van Houten", Address{StreetAddress: "10 Oak Avenue Apt 4", City: "Springfield", Region: "IL"})
+	luann := homeContact("people/9", "Luann Van Houten", Address{StreetAddress: "10 Oak Avenue Apt 5", City: "Springfield", Region: "IL"})
+	luann.Relations = []Relation{{Person: "Kirk Van Houten", Type: "spouse"}}
+
+	res, err := Coalesce([]Contact{homer, marge, ned, lenny, carl, maude, rod, kirk, luann}, Options{Matcher: Matcher{Threshold: 0.95}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got [][]string
+	for _, card := range res.Cards {
+		got = append(got, card.Names)
+		if len(card.Names) > 1 && !card.NeedsReview() {
+			t.Errorf("expected card %v to need review", card.Names)
+		}
+	}
+	// Maude's house number differs from Ned's, Rod has his own place, and
+	// Kirk and Luann live in different units of one building
+	want := [][]string{{"Homer Simpson", "Marge Simpson"}, {"Ned Flanders"}, {"Lenny Leonard", "Carl Carlson"}, {"Maude Flanders"}, {"Rod Flanders"}, {"Kirk Van Houten"}, {"Luann Van Houten"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Coalesce() mismatch (-want +got):\n%s", diff)
+	}
+
+	if reason := res.Cards[0].Matches[0].Reason; !strings.HasPrefix(reason, "spouse with different addresses") {
+		t.Errorf("unexpected reason: %q", reason)
+	}
+}
+
+func TestCoalesceOrderIndependent(t *testing.T) {
+	contacts := []Contact{
+		homeContact("people/1", "Homer Simpson", mainStreet),
+		homeContact("people/2", "Marge Simpson", Address{StreetAddress: "123 Main", City: "Springfield"}),
+		homeContact("people/3", "Ned Flanders", elmStreet),
+		homeContact("people/4", "Maude Flanders", elmStreet),
+		homeContact("people/5", "Moe Szyslak", Address{StreetAddress: "57 Walnut Street", City: "Springfield"}),
+	}
+
+	want, err := Coalesce(contacts, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		shuffled := make([]Contact, len(contacts))
+		copy(shuffled, contacts)
+		rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+		got, err := Coalesce(shuffled, Options{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Fatalf("Coalesce() depends on input order (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestCoalesceSkipsAmbiguous(t *testing.T) {
+	ambiguous := Contact{
+		ResourceName: "people/2",
+		Names:        []Name{{DisplayName: "Marge Simpson"}},
+		Addresses: []ContactAddress{
+			{Address: mainStreet, Type: "work"},
+			{Address: elmStreet, Type: "other"},
+		},
+	}
+
+	res, err := Coalesce([]Contact{homeContact("people/1", "Homer Simpson", mainStreet), ambiguous}, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(res.Cards) != 1 {
+		t.Errorf("unexpected card count, got: %v, want: 1", len(res.Cards))
+	}
+	want := []SkippedContact{{
+		ResourceName: "people/2",
+		Name:         "Marge Simpson",
+		Reason:       SkipAmbiguousAddress,
+		Addresses:    ambiguous.Addresses,
+	}}
+	if diff := cmp.Diff(want, res.Skipped); diff != "" {
+		t.Errorf("Skipped mismatch (-want +got):\n%s", diff)
+	}
+
+	res, err = Coalesce([]Contact{ambiguous}, Options{Policy: SelectionPolicy{TypeOrder: []string{"other"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Skipped) != 0 || len(res.Cards) != 1 || res.Cards[0].Address != elmStreet {
+		t.Errorf("expected type order to pick the other address, got: %+v", res)
+	}
+}
+
+// benchmarkContacts returns n contacts living two to a house, spread over
+// streets in a few dozen towns.
+func benchmarkContacts(n int) []Contact {
+	streets := []string{"Main Street", "Elm Street", "Oak Avenue", "Maple Drive", "Cedar Lane"}
+	contacts := make([]Contact, n)
+	for i := range contacts {
+		house := i / 2
+		town := house % 40
+		contacts[i] = homeContact(fmt.Sprintf("people/%d", i), fmt.Sprintf("Person %d", i), Address{
+			StreetAddress: fmt.Sprintf("%d %s", house/40+1, streets[house%len(streets)]),
+			City:          fmt.Sprintf("Town %d", town),
+			Region:        "MA",
+			PostalCode:    fmt.Sprintf("%05d", 1000+town),
+		})
+	}
+	return contacts
+}
+
+func BenchmarkCoalesce(b *testing.B) {
+	contacts := benchmarkContacts(3000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Coalesce(contacts, Options{}); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/bfallik/cohabitaters"
 	"github.com/bfallik/cohabitaters/cohabdb"
@@ -28,8 +29,24 @@ func main() {
 		listenAddress = defaultListenAddress
 	}
 
+	var matcher cohabitaters.Matcher
+	if threshold, ok := os.LookupEnv("MATCH_THRESHOLD"); ok {
+		f, err := strconv.ParseFloat(threshold, 64)
+		if err != nil {
+			log.Fatalf("unable to parse MATCH_THRESHOLD: %v", err)
+		}
+		matcher.Threshold = f
+	}
+	if margin, ok := os.LookupEnv("MATCH_REVIEW_MARGIN"); ok {
+		f, err := strconv.ParseFloat(margin, 64)
+		if err != nil {
+			log.Fatalf("unable to parse MATCH_REVIEW_MARGIN: %v", err)
+		}
+		matcher.ReviewMargin = f
+	}
+
 	googleAppCredentials := os.Getenv("GOOGLE_APP_CREDENTIALS")
-	oauthConfig, err := google.ConfigFromJSON([]byte(googleAppCredentials), people.ContactsReadonlyScope, people.UserinfoEmailScope)
+	oauthConfig, err := google.ConfigFromJSON([]byte(googleAppCredentials), people.ContactsReadonlyScope, people.UserinfoEmailScope, people.UserinfoProfileScope, people.UserAddressesReadScope)
 	if err != nil {
 		log.Fatalf("unable to create Google oauth2 config: %v", err)
 	}
@@ -73,13 +90,36 @@ func main() {
 	webUIHandler := handlers.WebUI{
 		OauthConfig: oauthConfig,
 		Queries:     queries,
+		Matcher:     matcher,
 	}
 
 	e.GET("/static/fontawesome/*", handlers.FontAwesome)
 	e.GET("/static/tailwindcss/*", handlers.Tailwind)
 
-	e.GET("/", webUIHandler.Root)
+	csrf := handlers.CSRF()
+
+	e.GET("/", webUIHandler.Root, csrf)
 	e.GET("/partial/tableResults", webUIHandler.PartialTableResults)
+	e.GET("/print", webUIHandler.PrintCards)
+	e.GET("/export/csv", webUIHandler.ExportCSV)
+	e.GET("/export/vcf", webUIHandler.ExportVCard)
+	e.GET("/export/xlsx", webUIHandler.ExportXLSX)
+	e.GET("/export/labels", webUIHandler.ExportLabels)
+	e.GET("/export/envelopes", webUIHandler.ExportEnvelopes)
+	e.POST("/overrides/address", webUIHandler.AddressOverride, csrf)
+	e.POST("/preferences/address-types", webUIHandler.AddressTypeOrder, csrf)
+	e.POST("/preferences/salutation", webUIHandler.SalutationStyle, csrf)
+	e.POST("/preferences/sender-country", webUIHandler.SenderCountry, csrf)
+	e.POST("/preferences/return-address", webUIHandler.ReturnAddress, csrf)
+	e.POST("/preferences/sort", webUIHandler.SortOrder, csrf)
+	e.POST("/layouts", webUIHandler.UploadLabelLayout, csrf)
+	e.POST("/layouts/delete", webUIHandler.DeleteLabelLayout, csrf)
+	e.POST("/imports", webUIHandler.UploadContactImport, csrf)
+	e.POST("/imports/delete", webUIHandler.DeleteContactImport, csrf)
+	e.POST("/accounts/unlink", webUIHandler.UnlinkAccount, csrf)
+	e.POST("/groups/expression", webUIHandler.GroupExpression, csrf)
+	e.POST("/overrides/merge", webUIHandler.MergeContacts, csrf)
+	e.POST("/overrides/split", webUIHandler.SplitContact, csrf)
 	e.GET("/about", handlers.About)
 	e.GET("/error", handlers.Error)
 	e.GET("/logout", webUIHandler.Logout)
@@ -87,6 +127,7 @@ func main() {
 	e.GET("/auth/google/callback", oauthHandler.GoogleCallbackAuthz).Name = handlers.RedirectURLAuthz
 	e.GET("/auth/google/login", oauthHandler.GoogleLoginAuthz).Name = handlers.RedirectURLAuthzLogin
 	e.GET("/auth/google/force-approval", oauthHandler.GoogleForceApproval)
+	e.GET("/auth/google/link", oauthHandler.GoogleLinkAuthz)
 
 	e.POST("/authn/google/callback", oauthHandler.GoogleCallbackAuthn).Name = handlers.RedirectURLAuthn
 
@@ -3,12 +3,24 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/bfallik/cohabitaters"
+	"github.com/bfallik/cohabitaters/addressbook"
+	"github.com/bfallik/cohabitaters/carddav"
+	"github.com/bfallik/cohabitaters/envelopes"
+	"github.com/bfallik/cohabitaters/export"
+	"github.com/bfallik/cohabitaters/gpeople"
+	"github.com/bfallik/cohabitaters/labels"
+	"github.com/bfallik/cohabitaters/normalize"
+	"github.com/bfallik/cohabitaters/pdf"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/option"
@@ -72,13 +84,279 @@ func saveToken(path string, token *oauth2.Token) {
 	}
 }
 
+// optionFlags holds the flags that tune how contacts are coalesced, shared
+// by every command.
+type optionFlags struct {
+	matcher       cohabitaters.Matcher
+	addressTypes  string
+	salutation    string
+	senderCountry string
+	sort          string
+}
+
+func (o *optionFlags) register(fs *flag.FlagSet) {
+	fs.Float64Var(&o.matcher.Threshold, "threshold", cohabitaters.DefaultThreshold, "minimum address similarity, between 0 and 1, to merge two contacts")
+	fs.Float64Var(&o.matcher.ReviewMargin, "review-margin", cohabitaters.DefaultReviewMargin, "flag merges scoring within this distance of the threshold for review")
+	fs.StringVar(&o.addressTypes, "address-types", "", "comma separated address types, most preferred first, for contacts without a home or primary address")
+	fs.StringVar(&o.salutation, "salutation", string(cohabitaters.SalutationNames), "how to address each card: names, family or full")
+	fs.StringVar(&o.senderCountry, "sender-country", "US", "country cards are mailed from; other countries are added to the address")
+	fs.StringVar(&o.sort, "sort", "", "card order: addressee, or postal to group by country, region, postal code and street")
+}
+
+func (o *optionFlags) options() (cohabitaters.Options, error) {
+	style, err := cohabitaters.ParseSalutationStyle(o.salutation)
+	if err != nil {
+		return cohabitaters.Options{}, err
+	}
+	sender := normalize.CountryCode(o.senderCountry)
+	if len(sender) == 0 {
+		return cohabitaters.Options{}, fmt.Errorf("unrecognized sender country %q", o.senderCountry)
+	}
+	order, err := cohabitaters.ParseSortOrder(o.sort)
+	if err != nil {
+		return cohabitaters.Options{}, err
+	}
+	return cohabitaters.Options{
+		Matcher:       o.matcher,
+		Policy:        cohabitaters.SelectionPolicy{TypeOrder: cohabitaters.ParseTypeOrder(o.addressTypes)},
+		Salutation:    style,
+		SenderCountry: sender,
+		Sort:          order,
+	}, nil
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "labels":
+			labelsMain(os.Args[2:])
+			return
+		case "envelopes":
+			envelopesMain(os.Args[2:])
+			return
+		case "export":
+			exportMain(os.Args[2:])
+			return
+		}
+	}
+
+	var optFlags optionFlags
+	optFlags.register(flag.CommandLine)
+	var srcFlags sourceFlags
+	srcFlags.register(flag.CommandLine)
+	format := flag.String("format", "text", "output format: text, csv, vcf or xlsx")
+	bom := flag.Bool("bom", false, "start CSV output with a UTF-8 byte order mark for Excel")
+	flag.Parse()
+
+	if *format != "text" && !exportFormats[*format] {
+		log.Fatalf("unknown format %q", *format)
+	}
+	opts, err := optFlags.options()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	ctx := context.Background()
+	res := srcFlags.xmasCards(ctx, srcFlags.source(ctx), opts)
+
+	if *format == "text" {
+		printText(res, opts)
+		return
+	}
+	if err := writeExport(os.Stdout, *format, *bom, res); err != nil {
+		log.Fatalf("unable to write %s: %v", *format, err)
+	}
+}
+
+// exportFormats are the file formats writeExport knows.
+var exportFormats = map[string]bool{"csv": true, "vcf": true, "xlsx": true}
+
+func writeExport(w io.Writer, format string, bom bool, res cohabitaters.Result) error {
+	switch format {
+	case "csv":
+		return export.WriteCSV(w, res.Cards, export.CSVOptions{BOM: bom})
+	case "vcf":
+		return export.WriteVCard(w, res.Cards)
+	case "xlsx":
+		return export.WriteXLSX(w, res)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// exportMain implements "cohabcli export", which writes the cards to stdout in
+// a format other programs can import.
+func exportMain(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	var optFlags optionFlags
+	optFlags.register(fs)
+	var srcFlags sourceFlags
+	srcFlags.register(fs)
+	format := fs.String("format", "csv", "file format: csv, vcf or xlsx")
+	bom := fs.Bool("bom", false, "start CSV output with a UTF-8 byte order mark for Excel")
+	_ = fs.Parse(args)
+
+	if !exportFormats[*format] {
+		log.Fatalf("unknown format %q", *format)
+	}
+	opts, err := optFlags.options()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	ctx := context.Background()
+	res := srcFlags.xmasCards(ctx, srcFlags.source(ctx), opts)
+	if err := writeExport(os.Stdout, *format, *bom, res); err != nil {
+		log.Fatalf("unable to write %s: %v", *format, err)
+	}
+}
+
+// labelsMain implements "cohabcli labels", which prints the cards onto a PDF
+// of mailing labels.
+func labelsMain(args []string) {
+	fs := flag.NewFlagSet("labels", flag.ExitOnError)
+	var optFlags optionFlags
+	optFlags.register(fs)
+	var srcFlags sourceFlags
+	srcFlags.register(fs)
+	sheetName := fs.String("sheet", labels.Avery5160.Name, "label sheet: 5160, 5163 or L7160")
+	fontName := fs.String("font", string(pdf.Helvetica), "font: Helvetica, Helvetica-Bold, Times-Roman, Times-Bold or Courier")
+	fontSize := fs.Float64("font-size", labels.DefaultFontSize, "largest font size in points; long addresses are set smaller")
+	padding := fs.Float64("padding", labels.DefaultPadding, "blank margin inside each label edge, in points")
+	offsetX := fs.Float64("offset-x", 0, "shift everything right by this many points to correct printer alignment")
+	offsetY := fs.Float64("offset-y", 0, "shift everything up by this many points to correct printer alignment")
+	skip := fs.Int("skip", 0, "number of labels already used on the first sheet")
+	layoutFile := fs.String("layout", "", "JSON label layout file to use instead of -sheet, -font, -font-size and -padding")
+	out := fs.String("o", "labels.pdf", "PDF file to write")
+	_ = fs.Parse(args)
+
+	opts, err := optFlags.options()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	labelOpts := labels.Options{
+		FontSize: *fontSize,
+		Padding:  *padding,
+		OffsetX:  *offsetX,
+		OffsetY:  *offsetY,
+		Skip:     *skip,
+	}
+	if labelOpts.Sheet, err = labels.LookupSheet(*sheetName); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if labelOpts.Font, err = pdf.ParseFont(*fontName); err != nil {
+		log.Fatalf("%v", err)
+	}
+	sheet := labelOpts.Sheet
+	if len(*layoutFile) > 0 {
+		if labelOpts.Layout, err = readLayout(*layoutFile); err != nil {
+			log.Fatalf("%v", err)
+		}
+		sheet = labelOpts.Layout.Sheet()
+	}
+	if labelOpts.Skip < 0 || labelOpts.Skip >= sheet.PerPage() {
+		log.Fatalf("%v", labels.ErrSkip)
+	}
+
+	ctx := context.Background()
+	res := srcFlags.xmasCards(ctx, srcFlags.source(ctx), opts)
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("unable to create %s: %v", *out, err)
+	}
+	if err := labels.Write(f, res.Cards, labelOpts); err != nil {
+		log.Fatalf("unable to write labels: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("unable to write %s: %v", *out, err)
+	}
+	log.Printf("wrote %d labels to %s", len(res.Cards), *out)
+}
+
+// readLayout reads a JSON label layout, listing every problem with it.
+func readLayout(filename string) (*labels.Layout, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := labels.ParseLayout(data)
+	var verr *labels.ValidationError
+	if errors.As(err, &verr) {
+		return nil, fmt.Errorf("%s is not a valid layout:\n\t%s", filename, strings.Join(verr.Problems, "\n\t"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &layout, nil
+}
+
+// envelopesMain implements "cohabcli envelopes", which prints one envelope
+// per card to a PDF.
+func envelopesMain(args []string) {
+	fs := flag.NewFlagSet("envelopes", flag.ExitOnError)
+	var optFlags optionFlags
+	optFlags.register(fs)
+	var srcFlags sourceFlags
+	srcFlags.register(fs)
+	envelopeName := fs.String("envelope", envelopes.Number10.Name, "envelope size: 10 or A7")
+	fontName := fs.String("font", string(pdf.Helvetica), "font: Helvetica, Helvetica-Bold, Times-Roman, Times-Bold or Courier")
+	fontSize := fs.Float64("font-size", envelopes.DefaultFontSize, "largest font size of the recipient in points")
+	returnAddress := fs.String("return-address", "", "return address lines separated by '|'; defaults to the address in your Google profile")
+	out := fs.String("o", "envelopes.pdf", "PDF file to write")
+	_ = fs.Parse(args)
+
+	opts, err := optFlags.options()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	envOpts := envelopes.Options{
+		FontSize:      *fontSize,
+		SenderCountry: opts.SenderCountry,
+	}
+	if envOpts.Envelope, err = envelopes.LookupEnvelope(*envelopeName); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if envOpts.Font, err = pdf.ParseFont(*fontName); err != nil {
+		log.Fatalf("%v", err)
+	}
+
 	ctx := context.Background()
+	src := srcFlags.source(ctx)
+	if lines := envelopes.ParseLines(strings.ReplaceAll(*returnAddress, "|", "\n")); len(lines) > 0 {
+		envOpts.ReturnAddress.Lines = lines
+	} else {
+		google, ok := src.(*gpeople.Source)
+		if !ok {
+			log.Fatalf("pass -return-address when reading contacts from a file or CardDAV")
+		}
+		me, err := google.Me(ctx)
+		if err != nil {
+			log.Fatalf("unable to read your profile for a return address; delete token.json to grant access, or pass -return-address: %v", err)
+		}
+		envOpts.ReturnAddress = envelopes.FromContact(me, opts.Policy)
+	}
+
+	res := srcFlags.xmasCards(ctx, src, opts)
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("unable to create %s: %v", *out, err)
+	}
+	if err := envelopes.Write(f, res.Cards, envOpts); err != nil {
+		log.Fatalf("unable to write envelopes: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("unable to write %s: %v", *out, err)
+	}
+	log.Printf("wrote %d envelopes to %s", len(res.Cards), *out)
+}
 
+// googleSource connects to the People API as the authorized Google account.
+func googleSource(ctx context.Context) *gpeople.Source {
 	log.Printf("%s", cohabitaters.BuildInfo())
 
 	googleAppCredentials := os.Getenv("GOOGLE_APP_CREDENTIALS")
-	config, err := google.ConfigFromJSON([]byte(googleAppCredentials), people.ContactsReadonlyScope)
+	config, err := google.ConfigFromJSON([]byte(googleAppCredentials), people.ContactsReadonlyScope, people.UserinfoProfileScope, people.UserAddressesReadScope)
 	if err != nil {
 		log.Fatalf("unable to create Google oauth2 config: %v", err)
 	}
@@ -89,27 +367,176 @@ func main() {
 		log.Fatalf("Unable to create people Client %v", err)
 	}
 
-	var resourceNameXmasCard string
+	return gpeople.New(srv)
+}
+
+// carddavStateFile keeps what was read from a CardDAV server between runs,
+// so the next run only fetches the vCards that changed.
+const carddavStateFile = "carddav.json"
+
+// carddavState is the content of carddavStateFile.
+type carddavState struct {
+	Endpoint string
+	Username string
+	carddav.State
+}
+
+// carddavSource reads every address book on the CardDAV server at endpoint,
+// picking up from carddavStateFile when the last run read the same server.
+func carddavSource(ctx context.Context, endpoint string) *carddav.Source {
+	username := os.Getenv("CARDDAV_USERNAME")
+	client, err := carddav.NewClient(endpoint, username, os.Getenv("CARDDAV_PASSWORD"))
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	src := carddav.NewSource(client)
+
+	var saved carddavState
+	if data, err := os.ReadFile(carddavStateFile); err == nil {
+		if err := json.Unmarshal(data, &saved); err != nil {
+			log.Printf("ignoring %s: %v", carddavStateFile, err)
+		} else if saved.Endpoint == endpoint && saved.Username == username {
+			src.Restore(saved.State)
+		}
+	}
+
+	if err := src.Refresh(ctx); err != nil {
+		log.Fatalf("unable to read contacts from %s: %v", endpoint, err)
+	}
 
-	r1, err := srv.ContactGroups.List().Do()
+	data, err := json.Marshal(carddavState{Endpoint: endpoint, Username: username, State: src.State()})
+	if err != nil {
+		log.Fatalf("unable to save %s: %v", carddavStateFile, err)
+	}
+	if err := os.WriteFile(carddavStateFile, data, 0600); err != nil {
+		log.Printf("unable to save %s: %v", carddavStateFile, err)
+	}
+	return src
+}
+
+// sourceFlags holds the flags that pick the address book to read and the
+// group in it to coalesce, shared by every command.
+type sourceFlags struct {
+	vcf     string
+	csv     string
+	carddav string
+	group   string
+}
+
+func (f *sourceFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.vcf, "vcf", "", "read contacts from this vCard file instead of Google Contacts")
+	fs.StringVar(&f.csv, "csv", "", "read contacts from this Google Contacts or Outlook CSV export instead of Google Contacts")
+	fs.StringVar(&f.carddav, "carddav", "", "read contacts from the address books on this CardDAV server instead of Google Contacts, logging in as $CARDDAV_USERNAME with $CARDDAV_PASSWORD; "+carddavStateFile+" keeps them so the next run only fetches what changed")
+	fs.StringVar(&f.group, "group", "Xmas Card", "contact group to coalesce, or groups combined like \"Family + Friends - Sent\"; a file's categories or labels are its groups, along with \"All contacts\"")
+}
+
+// source returns the file named by -vcf or -csv, the server named by
+// -carddav, or else Google Contacts.
+func (f *sourceFlags) source(ctx context.Context) cohabitaters.ContactSource {
+	if len(f.carddav) > 0 {
+		return carddavSource(ctx, f.carddav)
+	}
+
+	name, read := f.vcf, addressbook.ReadVCard
+	if len(f.csv) > 0 {
+		name, read = f.csv, addressbook.ReadCSV
+	}
+	if len(name) == 0 {
+		return googleSource(ctx)
+	}
+
+	r, err := os.Open(name)
+	if err != nil {
+		log.Fatalf("unable to open contacts: %v", err)
+	}
+	defer r.Close()
+	book, err := read(r)
+	if err != nil {
+		log.Fatalf("unable to read %s: %v", name, err)
+	}
+	log.Printf("read %d contacts from %s", book.Len(), name)
+	return book
+}
+
+// xmasCards coalesces the contact group named by -group, or the contacts of
+// the group expression it holds.
+func (f *sourceFlags) xmasCards(ctx context.Context, src cohabitaters.ContactSource, opts cohabitaters.Options) cohabitaters.Result {
+	var resourceName string
+
+	groups, err := src.ContactGroups(ctx)
 	if err != nil {
 		log.Fatalf("Unable to retrieve contactGroups. %v", err)
 	}
-	for _, contactGroup := range r1.ContactGroups {
-		if contactGroup.Name == "Xmas Card" {
-			resourceNameXmasCard = contactGroup.ResourceName
+	for _, contactGroup := range groups {
+		if contactGroup.Name == f.group {
+			resourceName = contactGroup.ResourceName
 		}
 	}
-	if len(resourceNameXmasCard) == 0 {
-		log.Fatalf("No 'Xmas Card' contact group found.")
+	if len(resourceName) > 0 {
+		res, err := cohabitaters.GetXmasCards(ctx, src, resourceName, opts)
+		if err != nil {
+			log.Fatalf("getXmasCards: %v", err)
+		}
+		return res
 	}
 
-	cards, err := cohabitaters.GetXmasCards(srv, resourceNameXmasCard)
+	// not a group of its own, so perhaps several combined
+	expr, err := cohabitaters.ParseGroupExpr(f.group)
+	if err != nil {
+		log.Fatalf("No '%s' contact group found, and it isn't a group expression: %v", f.group, err)
+	}
+	res, err := cohabitaters.GetXmasCardsForExpr(ctx, src, expr, opts)
 	if err != nil {
 		log.Fatalf("getXmasCards: %v", err)
 	}
+	return res
+}
+
+// printText writes the cards, and the contacts that were skipped, for a
+// person to read. Postal sorted cards are headed by their country.
+func printText(res cohabitaters.Result, opts cohabitaters.Options) {
+	var last *cohabitaters.Destination
+	for _, card := range res.Cards {
+		if opts.Sort == cohabitaters.SortPostal {
+			if d := card.Address.Destination(opts.SenderCountry); last == nil || *last != d {
+				fmt.Printf("== %s ==\n", d.Country)
+				last = &d
+			}
+		}
+		fmt.Printf("%s\n\t%s\n", card.Addressee, strings.Join(card.AddressLines, "\n\t"))
+		if card.NeedsReview() {
+			for _, m := range append(card.Matches, card.NearMisses...) {
+				if m.Review {
+					fmt.Printf("\treview: %s and %s (%s)\n", m.A, m.B, m.Reason)
+				}
+			}
+		}
+	}
+
+	if len(res.Cards) > 0 {
+		fmt.Printf("\n%d domestic, %d international cards:\n", res.Subtotals.Domestic, res.Subtotals.International)
+	}
+	for _, c := range res.Subtotals.Countries {
+		fmt.Printf("\t%s: %d\n", c.Country, c.Cards)
+	}
 
-	for _, card := range cards {
-		fmt.Printf("%v\n", card)
+	if len(res.Skipped) > 0 {
+		fmt.Printf("\nskipped %d contacts:\n", len(res.Skipped))
+	}
+	for _, skipped := range res.Skipped {
+		name := skipped.Name
+		if len(name) == 0 {
+			name = skipped.ResourceName
+		}
+		if len(skipped.Detail) > 0 {
+			fmt.Printf("\t%s: %s (%s)\n", name, skipped.Reason, skipped.Detail)
+		} else {
+			fmt.Printf("\t%s: %s\n", name, skipped.Reason)
+		}
+		if skipped.Reason == cohabitaters.SkipAmbiguousAddress {
+			for _, addr := range skipped.Addresses {
+				fmt.Printf("\t\t%s: %v\n", addr.Type, addr.Address)
+			}
+		}
 	}
 }
@@ -68,3 +68,263 @@ func TestQueries(t *testing.T) {
 		t.Errorf("GetToken() = %+v, want %+v", fetchedTok, insertedTok)
 	}
 }
+
+func TestAddressOverridesAndPreferences(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer db.Close()
+
+	if err := CreateTables(ctx, db); err != nil {
+		t.Fatalf("%v", err)
+	}
+	queries := New(db)
+
+	user, err := queries.UpsertUser(ctx, UpsertUserParams{Sub: "Test Sub"})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, key := range []string{"first", "second"} {
+		err := queries.UpsertAddressOverride(ctx, UpsertAddressOverrideParams{UserID: user.ID, ResourceName: "people/1", AddressKey: key})
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+	}
+
+	overrides, err := queries.ListAddressOverrides(ctx, user.ID)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	want := []AddressOverride{{UserID: user.ID, ResourceName: "people/1", AddressKey: "second"}}
+	if diff := cmp.Diff(want, overrides); diff != "" {
+		t.Errorf("ListAddressOverrides() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := queries.GetUserPreference(ctx, GetUserPreferenceParams{UserID: user.ID, Name: "missing"}); err != sql.ErrNoRows {
+		t.Errorf("unexpected error, got: %v, want: %v", err, sql.ErrNoRows)
+	}
+
+	if err := queries.UpsertUserPreference(ctx, UpsertUserPreferenceParams{UserID: user.ID, Name: "pref", Value: "work,other"}); err != nil {
+		t.Errorf("%v", err)
+	}
+	value, err := queries.GetUserPreference(ctx, GetUserPreferenceParams{UserID: user.ID, Name: "pref"})
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if value != "work,other" {
+		t.Errorf("GetUserPreference() got: %q, want: %q", value, "work,other")
+	}
+}
+
+func TestHouseholdOverrides(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer db.Close()
+
+	if err := CreateTables(ctx, db); err != nil {
+		t.Fatalf("%v", err)
+	}
+	queries := New(db)
+
+	user, err := queries.UpsertUser(ctx, UpsertUserParams{Sub: "Test Sub"})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, pair := range [][2]string{{"people/1", "people/2"}, {"people/1", "people/2"}, {"people/2", "people/3"}} {
+		err := queries.InsertHouseholdMerge(ctx, InsertHouseholdMergeParams{UserID: user.ID, ResourceNameA: pair[0], ResourceNameB: pair[1]})
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+	}
+	if err := queries.DeleteHouseholdMerges(ctx, DeleteHouseholdMergesParams{UserID: user.ID, ResourceNameA: "people/3", ResourceNameB: "people/3"}); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	merges, err := queries.ListHouseholdMerges(ctx, user.ID)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	wantMerges := []HouseholdMerge{{UserID: user.ID, ResourceNameA: "people/1", ResourceNameB: "people/2"}}
+	if diff := cmp.Diff(wantMerges, merges); diff != "" {
+		t.Errorf("ListHouseholdMerges() mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, rn := range []string{"people/4", "people/4", "people/5"} {
+		if err := queries.InsertHouseholdSplit(ctx, InsertHouseholdSplitParams{UserID: user.ID, ResourceName: rn}); err != nil {
+			t.Errorf("%v", err)
+		}
+	}
+	if err := queries.DeleteHouseholdSplit(ctx, DeleteHouseholdSplitParams{UserID: user.ID, ResourceName: "people/5"}); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	splits, err := queries.ListHouseholdSplits(ctx, user.ID)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	wantSplits := []HouseholdSplit{{UserID: user.ID, ResourceName: "people/4"}}
+	if diff := cmp.Diff(wantSplits, splits); diff != "" {
+		t.Errorf("ListHouseholdSplits() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLabelLayouts(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer db.Close()
+
+	if err := CreateTables(ctx, db); err != nil {
+		t.Fatalf("%v", err)
+	}
+	queries := New(db)
+
+	user, err := queries.UpsertUser(ctx, UpsertUserParams{Sub: "Test Sub"})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, l := range []UpsertLabelLayoutParams{
+		{UserID: user.ID, Name: "kraft", Layout: "{}"},
+		{UserID: user.ID, Name: "clear", Layout: "{}"},
+		{UserID: user.ID, Name: "kraft", Layout: `{"name":"kraft"}`},
+	} {
+		if err := queries.UpsertLabelLayout(ctx, l); err != nil {
+			t.Errorf("%v", err)
+		}
+	}
+
+	layout, err := queries.GetLabelLayout(ctx, GetLabelLayoutParams{UserID: user.ID, Name: "kraft"})
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if want := `{"name":"kraft"}`; layout != want {
+		t.Errorf("GetLabelLayout() got: %q, want: %q", layout, want)
+	}
+
+	if err := queries.DeleteLabelLayout(ctx, DeleteLabelLayoutParams{UserID: user.ID, Name: "clear"}); err != nil {
+		t.Errorf("%v", err)
+	}
+	if _, err := queries.GetLabelLayout(ctx, GetLabelLayoutParams{UserID: user.ID, Name: "clear"}); err != sql.ErrNoRows {
+		t.Errorf("unexpected error, got: %v, want: %v", err, sql.ErrNoRows)
+	}
+
+	layouts, err := queries.ListLabelLayouts(ctx, user.ID)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	want := []LabelLayout{{UserID: user.ID, Name: "kraft", Layout: `{"name":"kraft"}`}}
+	if diff := cmp.Diff(want, layouts); diff != "" {
+		t.Errorf("ListLabelLayouts() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestContactImports(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer db.Close()
+
+	if err := CreateTables(ctx, db); err != nil {
+		t.Fatalf("%v", err)
+	}
+	queries := New(db)
+
+	user, err := queries.UpsertUser(ctx, UpsertUserParams{Sub: "Test Sub"})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, ci := range []UpsertContactImportParams{
+		{UserID: user.ID, Filename: "old.vcf", Contents: "BEGIN:VCARD"},
+		{UserID: user.ID, Filename: "contacts.vcf", Contents: "BEGIN:VCARD\r\nEND:VCARD\r\n"},
+	} {
+		if err := queries.UpsertContactImport(ctx, ci); err != nil {
+			t.Errorf("%v", err)
+		}
+	}
+
+	got, err := queries.GetContactImport(ctx, user.ID)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	want := ContactImport{UserID: user.ID, Filename: "contacts.vcf", Contents: "BEGIN:VCARD\r\nEND:VCARD\r\n"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetContactImport() mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := queries.DeleteContactImport(ctx, user.ID); err != nil {
+		t.Errorf("%v", err)
+	}
+	if _, err := queries.GetContactImport(ctx, user.ID); err != sql.ErrNoRows {
+		t.Errorf("unexpected error, got: %v, want: %v", err, sql.ErrNoRows)
+	}
+}
+
+func TestLinkedAccounts(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer db.Close()
+
+	if err := CreateTables(ctx, db); err != nil {
+		t.Fatalf("%v", err)
+	}
+	queries := New(db)
+
+	user, err := queries.UpsertUser(ctx, UpsertUserParams{Sub: "Test Sub"})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, la := range []UpsertLinkedAccountParams{
+		{UserID: user.ID, Email: "marge@example.com", Token: "{}"},
+		{UserID: user.ID, Email: "homer@example.com", Token: "{}"},
+		{UserID: user.ID, Email: "marge@example.com", Token: `{"access_token":"x"}`},
+	} {
+		if err := queries.UpsertLinkedAccount(ctx, la); err != nil {
+			t.Errorf("%v", err)
+		}
+	}
+
+	got, err := queries.ListLinkedAccounts(ctx, user.ID)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	want := []LinkedAccount{
+		{UserID: user.ID, Email: "homer@example.com", Token: "{}"},
+		{UserID: user.ID, Email: "marge@example.com", Token: `{"access_token":"x"}`},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ListLinkedAccounts() mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := queries.DeleteLinkedAccount(ctx, DeleteLinkedAccountParams{UserID: user.ID, Email: "homer@example.com"}); err != nil {
+		t.Errorf("%v", err)
+	}
+	got, err = queries.ListLinkedAccounts(ctx, user.ID)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if len(got) != 1 || got[0].Email != "marge@example.com" {
+		t.Errorf("unexpected linked accounts after delete, got: %v", got)
+	}
+}
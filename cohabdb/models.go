@@ -8,6 +8,41 @@ import (
 	"database/sql"
 )
 
+type AddressOverride struct {
+	UserID       int64
+	ResourceName string
+	AddressKey   string
+}
+
+type ContactImport struct {
+	UserID   int64
+	Filename string
+	Contents string
+}
+
+type HouseholdMerge struct {
+	UserID        int64
+	ResourceNameA string
+	ResourceNameB string
+}
+
+type HouseholdSplit struct {
+	UserID       int64
+	ResourceName string
+}
+
+type LabelLayout struct {
+	UserID int64
+	Name   string
+	Layout string
+}
+
+type LinkedAccount struct {
+	UserID int64
+	Email  string
+	Token  string
+}
+
 type Session struct {
 	ID                   int64
 	UserID               int64
@@ -25,3 +60,9 @@ type User struct {
 	Picture sql.NullString
 	Token   sql.NullString
 }
+
+type UserPreference struct {
+	UserID int64
+	Name   string
+	Value  string
+}
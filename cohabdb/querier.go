@@ -10,19 +10,39 @@ import (
 )
 
 type Querier interface {
+	DeleteContactImport(ctx context.Context, userID int64) error
+	DeleteHouseholdMerges(ctx context.Context, arg DeleteHouseholdMergesParams) error
+	DeleteHouseholdSplit(ctx context.Context, arg DeleteHouseholdSplitParams) error
+	DeleteLabelLayout(ctx context.Context, arg DeleteLabelLayoutParams) error
+	DeleteLinkedAccount(ctx context.Context, arg DeleteLinkedAccountParams) error
 	ExpireSession(ctx context.Context, id int64) error
+	GetContactImport(ctx context.Context, userID int64) (ContactImport, error)
+	GetLabelLayout(ctx context.Context, arg GetLabelLayoutParams) (string, error)
 	GetSession(ctx context.Context, id int64) (Session, error)
 	GetToken(ctx context.Context, id int64) (sql.NullString, error)
 	GetUser(ctx context.Context, id int64) (User, error)
 	GetUserBySession(ctx context.Context, id int64) (User, error)
+	GetUserPreference(ctx context.Context, arg GetUserPreferenceParams) (string, error)
+	InsertHouseholdMerge(ctx context.Context, arg InsertHouseholdMergeParams) error
+	InsertHouseholdSplit(ctx context.Context, arg InsertHouseholdSplitParams) error
 	InsertSession(ctx context.Context, arg InsertSessionParams) (Session, error)
 	InsertUser(ctx context.Context, arg InsertUserParams) (User, error)
+	ListAddressOverrides(ctx context.Context, userID int64) ([]AddressOverride, error)
+	ListHouseholdMerges(ctx context.Context, userID int64) ([]HouseholdMerge, error)
+	ListHouseholdSplits(ctx context.Context, userID int64) ([]HouseholdSplit, error)
+	ListLabelLayouts(ctx context.Context, userID int64) ([]LabelLayout, error)
+	ListLinkedAccounts(ctx context.Context, userID int64) ([]LinkedAccount, error)
 	UpdateContactGroupsJSON(ctx context.Context, arg UpdateContactGroupsJSONParams) error
 	UpdateGoogleForceApproval(ctx context.Context, arg UpdateGoogleForceApprovalParams) error
 	UpdateSelectedResourceName(ctx context.Context, arg UpdateSelectedResourceNameParams) error
 	UpdateTokenBySession(ctx context.Context, arg UpdateTokenBySessionParams) error
+	UpsertAddressOverride(ctx context.Context, arg UpsertAddressOverrideParams) error
+	UpsertContactImport(ctx context.Context, arg UpsertContactImportParams) error
+	UpsertLabelLayout(ctx context.Context, arg UpsertLabelLayoutParams) error
+	UpsertLinkedAccount(ctx context.Context, arg UpsertLinkedAccountParams) error
 	UpsertSession(ctx context.Context, arg UpsertSessionParams) (Session, error)
 	UpsertUser(ctx context.Context, arg UpsertUserParams) (User, error)
+	UpsertUserPreference(ctx context.Context, arg UpsertUserPreferenceParams) error
 }
 
 var _ Querier = (*Queries)(nil)
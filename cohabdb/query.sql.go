@@ -10,6 +10,78 @@ import (
 	"database/sql"
 )
 
+const deleteContactImport = `-- name: DeleteContactImport :exec
+DELETE FROM contact_imports
+WHERE user_id = ?
+`
+
+func (q *Queries) DeleteContactImport(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteContactImport, userID)
+	return err
+}
+
+const deleteHouseholdMerges = `-- name: DeleteHouseholdMerges :exec
+DELETE FROM household_merges
+WHERE user_id = ?
+AND (resource_name_a = ? OR resource_name_b = ?)
+`
+
+type DeleteHouseholdMergesParams struct {
+	UserID        int64
+	ResourceNameA string
+	ResourceNameB string
+}
+
+func (q *Queries) DeleteHouseholdMerges(ctx context.Context, arg DeleteHouseholdMergesParams) error {
+	_, err := q.db.ExecContext(ctx, deleteHouseholdMerges, arg.UserID, arg.ResourceNameA, arg.ResourceNameB)
+	return err
+}
+
+const deleteHouseholdSplit = `-- name: DeleteHouseholdSplit :exec
+DELETE FROM household_splits
+WHERE user_id = ? AND resource_name = ?
+`
+
+type DeleteHouseholdSplitParams struct {
+	UserID       int64
+	ResourceName string
+}
+
+func (q *Queries) DeleteHouseholdSplit(ctx context.Context, arg DeleteHouseholdSplitParams) error {
+	_, err := q.db.ExecContext(ctx, deleteHouseholdSplit, arg.UserID, arg.ResourceName)
+	return err
+}
+
+const deleteLabelLayout = `-- name: DeleteLabelLayout :exec
+DELETE FROM label_layouts
+WHERE user_id = ? AND name = ?
+`
+
+type DeleteLabelLayoutParams struct {
+	UserID int64
+	Name   string
+}
+
+func (q *Queries) DeleteLabelLayout(ctx context.Context, arg DeleteLabelLayoutParams) error {
+	_, err := q.db.ExecContext(ctx, deleteLabelLayout, arg.UserID, arg.Name)
+	return err
+}
+
+const deleteLinkedAccount = `-- name: DeleteLinkedAccount :exec
+DELETE FROM linked_accounts
+WHERE user_id = ? AND email = ?
+`
+
+type DeleteLinkedAccountParams struct {
+	UserID int64
+	Email  string
+}
+
+func (q *Queries) DeleteLinkedAccount(ctx context.Context, arg DeleteLinkedAccountParams) error {
+	_, err := q.db.ExecContext(ctx, deleteLinkedAccount, arg.UserID, arg.Email)
+	return err
+}
+
 const expireSession = `-- name: ExpireSession :exec
 UPDATE sessions
 SET is_logged_in = false
@@ -21,6 +93,39 @@ func (q *Queries) ExpireSession(ctx context.Context, id int64) error {
 	return err
 }
 
+const getContactImport = `-- name: GetContactImport :one
+SELECT user_id, filename, contents FROM contact_imports
+WHERE user_id = ? LIMIT 1
+`
+
+func (q *Queries) GetContactImport(ctx context.Context, userID int64) (ContactImport, error) {
+	row := q.db.QueryRowContext(ctx, getContactImport, userID)
+	var i ContactImport
+	err := row.Scan(
+		&i.UserID,
+		&i.Filename,
+		&i.Contents,
+	)
+	return i, err
+}
+
+const getLabelLayout = `-- name: GetLabelLayout :one
+SELECT layout FROM label_layouts
+WHERE user_id = ? AND name = ? LIMIT 1
+`
+
+type GetLabelLayoutParams struct {
+	UserID int64
+	Name   string
+}
+
+func (q *Queries) GetLabelLayout(ctx context.Context, arg GetLabelLayoutParams) (string, error) {
+	row := q.db.QueryRowContext(ctx, getLabelLayout, arg.UserID, arg.Name)
+	var layout string
+	err := row.Scan(&layout)
+	return layout, err
+}
+
 const getSession = `-- name: GetSession :one
 SELECT id, user_id, created_at, is_logged_in, google_force_approval, contact_groups_json, selected_resource_name FROM sessions
 WHERE ID = ? LIMIT 1
@@ -93,6 +198,67 @@ func (q *Queries) GetUserBySession(ctx context.Context, id int64) (User, error)
 	return i, err
 }
 
+const getUserPreference = `-- name: GetUserPreference :one
+SELECT value FROM user_preferences
+WHERE user_id = ? AND name = ? LIMIT 1
+`
+
+type GetUserPreferenceParams struct {
+	UserID int64
+	Name   string
+}
+
+func (q *Queries) GetUserPreference(ctx context.Context, arg GetUserPreferenceParams) (string, error) {
+	row := q.db.QueryRowContext(ctx, getUserPreference, arg.UserID, arg.Name)
+	var value string
+	err := row.Scan(&value)
+	return value, err
+}
+
+const insertHouseholdMerge = `-- name: InsertHouseholdMerge :exec
+INSERT INTO household_merges
+(
+  user_id,
+  resource_name_a,
+  resource_name_b
+) VALUES (
+  ?, ?, ?
+)
+ON CONFLICT DO NOTHING
+`
+
+type InsertHouseholdMergeParams struct {
+	UserID        int64
+	ResourceNameA string
+	ResourceNameB string
+}
+
+func (q *Queries) InsertHouseholdMerge(ctx context.Context, arg InsertHouseholdMergeParams) error {
+	_, err := q.db.ExecContext(ctx, insertHouseholdMerge, arg.UserID, arg.ResourceNameA, arg.ResourceNameB)
+	return err
+}
+
+const insertHouseholdSplit = `-- name: InsertHouseholdSplit :exec
+INSERT INTO household_splits
+(
+  user_id,
+  resource_name
+) VALUES (
+  ?, ?
+)
+ON CONFLICT DO NOTHING
+`
+
+type InsertHouseholdSplitParams struct {
+	UserID       int64
+	ResourceName string
+}
+
+func (q *Queries) InsertHouseholdSplit(ctx context.Context, arg InsertHouseholdSplitParams) error {
+	_, err := q.db.ExecContext(ctx, insertHouseholdSplit, arg.UserID, arg.ResourceName)
+	return err
+}
+
 const insertSession = `-- name: InsertSession :one
 INSERT INTO sessions (
   id, user_id
@@ -153,6 +319,148 @@ func (q *Queries) InsertUser(ctx context.Context, arg InsertUserParams) (User, e
 	return i, err
 }
 
+const listAddressOverrides = `-- name: ListAddressOverrides :many
+SELECT user_id, resource_name, address_key FROM address_overrides
+WHERE user_id = ?
+`
+
+func (q *Queries) ListAddressOverrides(ctx context.Context, userID int64) ([]AddressOverride, error) {
+	rows, err := q.db.QueryContext(ctx, listAddressOverrides, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AddressOverride
+	for rows.Next() {
+		var i AddressOverride
+		if err := rows.Scan(&i.UserID, &i.ResourceName, &i.AddressKey); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listHouseholdMerges = `-- name: ListHouseholdMerges :many
+SELECT user_id, resource_name_a, resource_name_b FROM household_merges
+WHERE user_id = ?
+`
+
+func (q *Queries) ListHouseholdMerges(ctx context.Context, userID int64) ([]HouseholdMerge, error) {
+	rows, err := q.db.QueryContext(ctx, listHouseholdMerges, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []HouseholdMerge
+	for rows.Next() {
+		var i HouseholdMerge
+		if err := rows.Scan(&i.UserID, &i.ResourceNameA, &i.ResourceNameB); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listHouseholdSplits = `-- name: ListHouseholdSplits :many
+SELECT user_id, resource_name FROM household_splits
+WHERE user_id = ?
+`
+
+func (q *Queries) ListHouseholdSplits(ctx context.Context, userID int64) ([]HouseholdSplit, error) {
+	rows, err := q.db.QueryContext(ctx, listHouseholdSplits, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []HouseholdSplit
+	for rows.Next() {
+		var i HouseholdSplit
+		if err := rows.Scan(&i.UserID, &i.ResourceName); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLabelLayouts = `-- name: ListLabelLayouts :many
+SELECT user_id, name, layout FROM label_layouts
+WHERE user_id = ?
+ORDER BY name
+`
+
+func (q *Queries) ListLabelLayouts(ctx context.Context, userID int64) ([]LabelLayout, error) {
+	rows, err := q.db.QueryContext(ctx, listLabelLayouts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LabelLayout
+	for rows.Next() {
+		var i LabelLayout
+		if err := rows.Scan(&i.UserID, &i.Name, &i.Layout); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLinkedAccounts = `-- name: ListLinkedAccounts :many
+SELECT user_id, email, token FROM linked_accounts
+WHERE user_id = ?
+ORDER BY email
+`
+
+func (q *Queries) ListLinkedAccounts(ctx context.Context, userID int64) ([]LinkedAccount, error) {
+	rows, err := q.db.QueryContext(ctx, listLinkedAccounts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LinkedAccount
+	for rows.Next() {
+		var i LinkedAccount
+		if err := rows.Scan(&i.UserID, &i.Email, &i.Token); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateContactGroupsJSON = `-- name: UpdateContactGroupsJSON :exec
 UPDATE sessions
 SET contact_groups_json = ?
@@ -222,6 +530,103 @@ func (q *Queries) UpdateTokenBySession(ctx context.Context, arg UpdateTokenBySes
 	return err
 }
 
+const upsertAddressOverride = `-- name: UpsertAddressOverride :exec
+INSERT INTO address_overrides
+(
+  user_id,
+  resource_name,
+  address_key
+) VALUES (
+  ?, ?, ?
+)
+ON CONFLICT(user_id, resource_name) DO UPDATE SET
+  address_key=excluded.address_key
+`
+
+type UpsertAddressOverrideParams struct {
+	UserID       int64
+	ResourceName string
+	AddressKey   string
+}
+
+func (q *Queries) UpsertAddressOverride(ctx context.Context, arg UpsertAddressOverrideParams) error {
+	_, err := q.db.ExecContext(ctx, upsertAddressOverride, arg.UserID, arg.ResourceName, arg.AddressKey)
+	return err
+}
+
+const upsertContactImport = `-- name: UpsertContactImport :exec
+INSERT INTO contact_imports
+(
+  user_id,
+  filename,
+  contents
+) VALUES (
+  ?, ?, ?
+)
+ON CONFLICT(user_id) DO UPDATE SET
+  filename=excluded.filename,
+  contents=excluded.contents
+`
+
+type UpsertContactImportParams struct {
+	UserID   int64
+	Filename string
+	Contents string
+}
+
+func (q *Queries) UpsertContactImport(ctx context.Context, arg UpsertContactImportParams) error {
+	_, err := q.db.ExecContext(ctx, upsertContactImport, arg.UserID, arg.Filename, arg.Contents)
+	return err
+}
+
+const upsertLabelLayout = `-- name: UpsertLabelLayout :exec
+INSERT INTO label_layouts
+(
+  user_id,
+  name,
+  layout
+) VALUES (
+  ?, ?, ?
+)
+ON CONFLICT(user_id, name) DO UPDATE SET
+  layout=excluded.layout
+`
+
+type UpsertLabelLayoutParams struct {
+	UserID int64
+	Name   string
+	Layout string
+}
+
+func (q *Queries) UpsertLabelLayout(ctx context.Context, arg UpsertLabelLayoutParams) error {
+	_, err := q.db.ExecContext(ctx, upsertLabelLayout, arg.UserID, arg.Name, arg.Layout)
+	return err
+}
+
+const upsertLinkedAccount = `-- name: UpsertLinkedAccount :exec
+INSERT INTO linked_accounts
+(
+  user_id,
+  email,
+  token
+) VALUES (
+  ?, ?, ?
+)
+ON CONFLICT(user_id, email) DO UPDATE SET
+  token=excluded.token
+`
+
+type UpsertLinkedAccountParams struct {
+	UserID int64
+	Email  string
+	Token  string
+}
+
+func (q *Queries) UpsertLinkedAccount(ctx context.Context, arg UpsertLinkedAccountParams) error {
+	_, err := q.db.ExecContext(ctx, upsertLinkedAccount, arg.UserID, arg.Email, arg.Token)
+	return err
+}
+
 const upsertSession = `-- name: UpsertSession :one
 INSERT INTO sessions (
   id, user_id
@@ -288,3 +693,27 @@ func (q *Queries) UpsertUser(ctx context.Context, arg UpsertUserParams) (User, e
 	)
 	return i, err
 }
+
+const upsertUserPreference = `-- name: UpsertUserPreference :exec
+INSERT INTO user_preferences
+(
+  user_id,
+  name,
+  value
+) VALUES (
+  ?, ?, ?
+)
+ON CONFLICT(user_id, name) DO UPDATE SET
+  value=excluded.value
+`
+
+type UpsertUserPreferenceParams struct {
+	UserID int64
+	Name   string
+	Value  string
+}
+
+func (q *Queries) UpsertUserPreference(ctx context.Context, arg UpsertUserPreferenceParams) error {
+	_, err := q.db.ExecContext(ctx, upsertUserPreference, arg.UserID, arg.Name, arg.Value)
+	return err
+}
@@ -93,3 +93,140 @@ ON CONFLICT(sub) DO UPDATE SET
   name=excluded.name,
   picture=excluded.picture
 RETURNING *;
+
+-- name: ListAddressOverrides :many
+SELECT * FROM address_overrides
+WHERE user_id = ?;
+
+-- name: UpsertAddressOverride :exec
+INSERT INTO address_overrides
+(
+  user_id,
+  resource_name,
+  address_key
+) VALUES (
+  ?, ?, ?
+)
+ON CONFLICT(user_id, resource_name) DO UPDATE SET
+  address_key=excluded.address_key;
+
+-- name: GetUserPreference :one
+SELECT value FROM user_preferences
+WHERE user_id = ? AND name = ? LIMIT 1;
+
+-- name: UpsertUserPreference :exec
+INSERT INTO user_preferences
+(
+  user_id,
+  name,
+  value
+) VALUES (
+  ?, ?, ?
+)
+ON CONFLICT(user_id, name) DO UPDATE SET
+  value=excluded.value;
+
+-- name: ListHouseholdMerges :many
+SELECT * FROM household_merges
+WHERE user_id = ?;
+
+-- name: InsertHouseholdMerge :exec
+INSERT INTO household_merges
+(
+  user_id,
+  resource_name_a,
+  resource_name_b
+) VALUES (
+  ?, ?, ?
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteHouseholdMerges :exec
+DELETE FROM household_merges
+WHERE user_id = ?
+AND (resource_name_a = ? OR resource_name_b = ?);
+
+-- name: ListHouseholdSplits :many
+SELECT * FROM household_splits
+WHERE user_id = ?;
+
+-- name: InsertHouseholdSplit :exec
+INSERT INTO household_splits
+(
+  user_id,
+  resource_name
+) VALUES (
+  ?, ?
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteHouseholdSplit :exec
+DELETE FROM household_splits
+WHERE user_id = ? AND resource_name = ?;
+
+-- name: ListLabelLayouts :many
+SELECT * FROM label_layouts
+WHERE user_id = ?
+ORDER BY name;
+
+-- name: GetLabelLayout :one
+SELECT layout FROM label_layouts
+WHERE user_id = ? AND name = ? LIMIT 1;
+
+-- name: UpsertLabelLayout :exec
+INSERT INTO label_layouts
+(
+  user_id,
+  name,
+  layout
+) VALUES (
+  ?, ?, ?
+)
+ON CONFLICT(user_id, name) DO UPDATE SET
+  layout=excluded.layout;
+
+-- name: DeleteLabelLayout :exec
+DELETE FROM label_layouts
+WHERE user_id = ? AND name = ?;
+
+-- name: GetContactImport :one
+SELECT * FROM contact_imports
+WHERE user_id = ? LIMIT 1;
+
+-- name: UpsertContactImport :exec
+INSERT INTO contact_imports
+(
+  user_id,
+  filename,
+  contents
+) VALUES (
+  ?, ?, ?
+)
+ON CONFLICT(user_id) DO UPDATE SET
+  filename=excluded.filename,
+  contents=excluded.contents;
+
+-- name: DeleteContactImport :exec
+DELETE FROM contact_imports
+WHERE user_id = ?;
+
+-- name: ListLinkedAccounts :many
+SELECT * FROM linked_accounts
+WHERE user_id = ?
+ORDER BY email;
+
+-- name: UpsertLinkedAccount :exec
+INSERT INTO linked_accounts
+(
+  user_id,
+  email,
+  token
+) VALUES (
+  ?, ?, ?
+)
+ON CONFLICT(user_id, email) DO UPDATE SET
+  token=excluded.token;
+
+-- name: DeleteLinkedAccount :exec
+DELETE FROM linked_accounts
+WHERE user_id = ? AND email = ?;
@@ -17,3 +17,58 @@ CREATE TABLE IF NOT EXISTS sessions (
   FOREIGN KEY(user_id) REFERENCES users(id),
   CONSTRAINT unique_user_id UNIQUE(id, user_id)
 );
+
+CREATE TABLE IF NOT EXISTS address_overrides (
+  user_id INTEGER NOT NULL,
+  resource_name TEXT NOT NULL,
+  address_key TEXT NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id),
+  PRIMARY KEY(user_id, resource_name)
+);
+
+CREATE TABLE IF NOT EXISTS user_preferences (
+  user_id INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  value TEXT NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id),
+  PRIMARY KEY(user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS household_merges (
+  user_id INTEGER NOT NULL,
+  resource_name_a TEXT NOT NULL,
+  resource_name_b TEXT NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id),
+  PRIMARY KEY(user_id, resource_name_a, resource_name_b)
+);
+
+CREATE TABLE IF NOT EXISTS household_splits (
+  user_id INTEGER NOT NULL,
+  resource_name TEXT NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id),
+  PRIMARY KEY(user_id, resource_name)
+);
+
+CREATE TABLE IF NOT EXISTS label_layouts (
+  user_id INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  layout TEXT NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id),
+  PRIMARY KEY(user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS contact_imports (
+  user_id INTEGER NOT NULL,
+  filename TEXT NOT NULL,
+  contents TEXT NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id),
+  PRIMARY KEY(user_id)
+);
+
+CREATE TABLE IF NOT EXISTS linked_accounts (
+  user_id INTEGER NOT NULL,
+  email TEXT NOT NULL,
+  token TEXT NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id),
+  PRIMARY KEY(user_id, email)
+);
@@ -0,0 +1,199 @@
+// Package envelopes prints XmasCard addresses directly onto envelopes, one
+// household per page, with a return address in the top left corner.
+package envelopes
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/bfallik/cohabitaters/labels"
+	"github.com/bfallik/cohabitaters/pdf"
+)
+
+// Envelope is an envelope size, fed landscape.
+type Envelope struct {
+	Name string
+	Page pdf.Size
+}
+
+var (
+	// Number10 is the common US business envelope, 4 1/8" x 9 1/2".
+	Number10 = Envelope{Name: "10", Page: pdf.Size{Width: 9.5 * pdf.Inch, Height: 4.125 * pdf.Inch}}
+	// A7 is the 5 1/4" x 7 1/4" envelope that fits a 5" x 7" card.
+	A7 = Envelope{Name: "A7", Page: pdf.Size{Width: 7.25 * pdf.Inch, Height: 5.25 * pdf.Inch}}
+)
+
+// Envelopes lists the supported sizes, default first.
+var Envelopes = []Envelope{Number10, A7}
+
+// LookupEnvelope returns the envelope named name, or Number10 if name is
+// empty. A leading '#' is ignored, so "#10" works too.
+func LookupEnvelope(name string) (Envelope, error) {
+	if len(name) == 0 {
+		return Number10, nil
+	}
+	for _, e := range Envelopes {
+		if strings.EqualFold(strings.TrimPrefix(name, "#"), e.Name) {
+			return e, nil
+		}
+	}
+	return Envelope{}, fmt.Errorf("unknown envelope %q", name)
+}
+
+// ReturnAddress is printed in the top left corner of every envelope.
+type ReturnAddress struct {
+	// Lines, when set, are printed exactly as given and the other fields are
+	// ignored.
+	Lines []string
+	Name  string
+	// Address is formatted for each envelope's destination, so mail going
+	// abroad says which country to return it to.
+	Address cohabitaters.Address
+}
+
+// FromContact makes a return address from the user's own contact, choosing
+// among its addresses with policy. A contact without a single clear address
+// gives a return address with just a name.
+func FromContact(c cohabitaters.Contact, policy cohabitaters.AddressPolicy) ReturnAddress {
+	var ra ReturnAddress
+	if len(c.Names) > 0 {
+		ra.Name = c.Names[0].DisplayName
+	}
+	if addr, err := policy.PickAddress(c); err == nil && addr != nil {
+		ra.Address = addr.Address
+	}
+	return ra
+}
+
+// ParseLines splits a return address typed one line per row, dropping blank
+// lines.
+func ParseLines(s string) []string {
+	var out []string
+	for _, l := range strings.Split(s, "\n") {
+		if l = strings.TrimSpace(l); len(l) > 0 {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// lines formats the return address for mail to destCountry. senderCountry
+// stands in for the return address's country when it has none.
+func (r ReturnAddress) lines(destCountry, senderCountry string) []string {
+	if len(r.Lines) > 0 {
+		return r.Lines
+	}
+	var out []string
+	if len(r.Name) > 0 {
+		out = append(out, r.Name)
+	}
+	if addr := r.Address; addr != (cohabitaters.Address{}) {
+		if len(addr.CountryCode) == 0 && len(addr.Country) == 0 {
+			addr.CountryCode = senderCountry
+		}
+		out = append(out, addr.Lines(destCountry)...)
+	}
+	return out
+}
+
+const (
+	// DefaultFontSize is used when Options.FontSize is zero.
+	DefaultFontSize = 12.0
+	// DefaultReturnFontSize is used when Options.ReturnFontSize is zero.
+	DefaultReturnFontSize = 9.0
+	// DefaultMargin is used when Options.Margin is zero.
+	DefaultMargin = 0.375 * pdf.Inch
+	leading       = 1.2
+)
+
+// Options tunes Write. The zero value prints #10 envelopes in Helvetica with
+// no return address.
+type Options struct {
+	// Envelope is the size; a zero Envelope means Number10.
+	Envelope Envelope
+	Font     pdf.Font
+	// FontSize is the largest size of the recipient block, which is set
+	// smaller if it doesn't fit, down to labels.MinFontSize.
+	FontSize float64
+	// ReturnFontSize is the size of the return address.
+	ReturnFontSize float64
+	// Margin is the distance from the envelope's edges to the return
+	// address, and the least distance to the recipient block.
+	Margin        float64
+	ReturnAddress ReturnAddress
+	// SenderCountry is the ISO 3166-1 alpha-2 code of the country the cards
+	// are mailed from, for a return address that doesn't name its country.
+	SenderCountry string
+}
+
+func (o Options) withDefaults() Options {
+	if o.Envelope.Page.Width == 0 {
+		o.Envelope = Number10
+	}
+	if len(o.Font) == 0 {
+		o.Font = pdf.Helvetica
+	}
+	if o.FontSize == 0 {
+		o.FontSize = DefaultFontSize
+	}
+	if o.ReturnFontSize == 0 {
+		o.ReturnFontSize = DefaultReturnFontSize
+	}
+	if o.Margin == 0 {
+		o.Margin = DefaultMargin
+	}
+	return o
+}
+
+// destination returns the country code a card is mailed to.
+func destination(card cohabitaters.XmasCard, senderCountry string) string {
+	if code := card.Address.Normalized().CountryCode; len(code) > 0 {
+		return code
+	}
+	return senderCountry
+}
+
+// Write prints one envelope per card as a PDF. The recipient block is
+// centered on the envelope with its lines left aligned.
+func Write(w io.Writer, cards []cohabitaters.XmasCard, opts Options) error {
+	opts = opts.withDefaults()
+	size := opts.Envelope.Page
+
+	var doc pdf.Document
+	for _, card := range cards {
+		page := doc.AddPage(size)
+
+		returnLines := opts.ReturnAddress.lines(destination(card, opts.SenderCountry), opts.SenderCountry)
+		baseline := size.Height - opts.Margin - opts.ReturnFontSize
+		for _, line := range returnLines {
+			page.Text(opts.Margin, baseline, opts.Font, opts.ReturnFontSize, line)
+			baseline -= leading * opts.ReturnFontSize
+		}
+
+		// keep the recipient block clear of the return address above it
+		lines := labels.Lines(card)
+		returnHeight := leading * opts.ReturnFontSize * float64(len(returnLines))
+		fontSize := math.Max(opts.Font.FitSize(opts.FontSize, lines, size.Width-2*opts.Margin, size.Height-2*(opts.Margin+returnHeight), leading), labels.MinFontSize)
+
+		var width float64
+		for _, line := range lines {
+			width = math.Max(width, opts.Font.Width(line, fontSize))
+		}
+		block := leading * fontSize * float64(len(lines))
+		x := (size.Width - width) / 2
+		baseline = (size.Height+block)/2 - (leading-1)*fontSize/2 - 0.8*fontSize
+		for _, line := range lines {
+			page.Text(x, baseline, opts.Font, fontSize, line)
+			baseline -= leading * fontSize
+		}
+	}
+	if doc.Pages() == 0 {
+		doc.AddPage(size)
+	}
+
+	_, err := doc.WriteTo(w)
+	return err
+}
@@ -0,0 +1,153 @@
+package envelopes
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/google/go-cmp/cmp"
+)
+
+var springfield = cohabitaters.Address{StreetAddress: "742 Evergreen Terrace", City: "Springfield", Region: "IL", PostalCode: "62701"}
+
+func TestReturnAddressLines(t *testing.T) {
+	tests := []struct {
+		Name          string
+		ReturnAddress ReturnAddress
+		Dest          string
+		Want          []string
+	}{
+		{
+			Name: "empty",
+			Dest: "US",
+		},
+		{
+			Name:          "domestic",
+			ReturnAddress: ReturnAddress{Name: "Homer Simpson", Address: springfield},
+			Dest:          "US",
+			Want:          []string{"Homer Simpson", "742 Evergreen Terrace", "SPRINGFIELD, IL 62701"},
+		},
+		{
+			Name:          "abroad",
+			ReturnAddress: ReturnAddress{Name: "Homer Simpson", Address: springfield},
+			Dest:          "DE",
+			Want:          []string{"Homer Simpson", "742 Evergreen Terrace", "SPRINGFIELD, IL 62701", "UNITED STATES"},
+		},
+		{
+			Name:          "name only",
+			ReturnAddress: ReturnAddress{Name: "Homer Simpson"},
+			Dest:          "DE",
+			Want:          []string{"Homer Simpson"},
+		},
+		{
+			Name:          "typed lines",
+			ReturnAddress: ReturnAddress{Lines: []string{"The Simpsons", "Springfield"}, Name: "Homer Simpson", Address: springfield},
+			Dest:          "DE",
+			Want:          []string{"The Simpsons", "Springfield"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			got := test.ReturnAddress.lines(test.Dest, "US")
+			if diff := cmp.Diff(test.Want, got); diff != "" {
+				t.Errorf("lines() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFromContact(t *testing.T) {
+	c := cohabitaters.Contact{
+		Names: []cohabitaters.Name{{DisplayName: "Homer Simpson"}},
+		Addresses: []cohabitaters.ContactAddress{
+			{Address: cohabitaters.Address{StreetAddress: "Sector 7G", City: "Springfield"}, Type: "work"},
+			{Address: springfield, Type: "home"},
+		},
+	}
+	want := ReturnAddress{Name: "Homer Simpson", Address: springfield}
+	if diff := cmp.Diff(want, FromContact(c, cohabitaters.SelectionPolicy{})); diff != "" {
+		t.Errorf("FromContact() mismatch (-want +got):\n%s", diff)
+	}
+
+	c.Addresses[1].Type = "other"
+	want = ReturnAddress{Name: "Homer Simpson"}
+	if diff := cmp.Diff(want, FromContact(c, cohabitaters.SelectionPolicy{})); diff != "" {
+		t.Errorf("FromContact() ambiguous mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseLines(t *testing.T) {
+	want := []string{"Homer Simpson", "742 Evergreen Terrace"}
+	if diff := cmp.Diff(want, ParseLines(" Homer Simpson\r\n\n742 Evergreen Terrace \n")); diff != "" {
+		t.Errorf("ParseLines() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLookupEnvelope(t *testing.T) {
+	tests := []struct {
+		In   string
+		Want string
+	}{
+		{"", "10"},
+		{"#10", "10"},
+		{"a7", "A7"},
+	}
+
+	for _, test := range tests {
+		got, err := LookupEnvelope(test.In)
+		if err != nil {
+			t.Errorf("LookupEnvelope(%q) unexpected error: %v", test.In, err)
+		}
+		if got.Name != test.Want {
+			t.Errorf("LookupEnvelope(%q) got: %v, want: %v", test.In, got.Name, test.Want)
+		}
+	}
+
+	if _, err := LookupEnvelope("C5"); err == nil {
+		t.Errorf("expected an error for an unknown envelope")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	cards := []cohabitaters.XmasCard{
+		{Addressee: "Ned Flanders", AddressLines: []string{"744 Evergreen Terrace", "SPRINGFIELD IL 62701"}},
+		{
+			Addressee:    "Krusty",
+			Address:      cohabitaters.Address{City: "Zürich", CountryCode: "CH"},
+			AddressLines: []string{"8001 Zürich", "SWITZERLAND"},
+		},
+	}
+	opts := Options{
+		Envelope:      A7,
+		ReturnAddress: ReturnAddress{Name: "Homer Simpson", Address: springfield},
+		SenderCountry: "US",
+	}
+
+	var b bytes.Buffer
+	if err := Write(&b, cards, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := b.String()
+
+	if got := strings.Count(out, "/Type /Page "); got != len(cards) {
+		t.Errorf("unexpected page count, got: %v, want: %v", got, len(cards))
+	}
+	if got := strings.Count(out, "/MediaBox [0 0 522 378]"); got != len(cards) {
+		t.Errorf("unexpected A7 page count, got: %v, want: %v", got, len(cards))
+	}
+	// the return address sits in the top left corner, and says which country
+	// to return foreign mail to
+	if want := "BT /F1 9 Tf 27 342 Td (Homer Simpson) Tj ET"; !strings.Contains(out, want) {
+		t.Errorf("output is missing %q", want)
+	}
+	if got := strings.Count(out, "(UNITED STATES) Tj"); got != 1 {
+		t.Errorf("unexpected return address countries, got: %v, want: %v", got, 1)
+	}
+	// the recipient block is centered on its widest line, SPRINGFIELD IL
+	// 62701, which is 130.06pt wide
+	if want := "BT /F1 12 Tf 195.97 "; !strings.Contains(out, want) {
+		t.Errorf("output is missing %q", want)
+	}
+}
@@ -0,0 +1,96 @@
+// Package export writes coalesced XmasCards in formats other programs can
+// read.
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"github.com/bfallik/cohabitaters"
+)
+
+// utf8BOM lets Excel detect that a CSV file is UTF-8.
+const utf8BOM = "\ufeff"
+
+// CSVOptions tunes WriteCSV.
+type CSVOptions struct {
+	// BOM starts the output with a UTF-8 byte order mark.
+	BOM bool
+}
+
+// CSVHeader names the columns written by WriteCSV.
+var CSVHeader = []string{
+	"Addressee",
+	"Names",
+	"Street Address",
+	"Street Address 2",
+	"City",
+	"Region",
+	"Postal Code",
+	"Country",
+	"Country Code",
+	"Mailing Address",
+	"Contact IDs",
+}
+
+// WriteCSV writes a header and then one RFC 4180 record per card. Fields
+// holding several values, such as Names, separate them with "; " and the
+// mailing address keeps its line breaks. Fields a spreadsheet would read as
+// a formula are escaped with a leading quote.
+func WriteCSV(w io.Writer, cards []cohabitaters.XmasCard, opts CSVOptions) error {
+	if opts.BOM {
+		if _, err := io.WriteString(w, utf8BOM); err != nil {
+			return err
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+	if err := cw.Write(CSVHeader); err != nil {
+		return err
+	}
+	for _, card := range cards {
+		if err := cw.Write(csvRecord(card)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvRecord(card cohabitaters.XmasCard) []string {
+	ids := make([]string, len(card.Contacts))
+	for i, c := range card.Contacts {
+		ids[i] = c.ResourceName
+	}
+
+	a := card.Address
+	record := []string{
+		card.Addressee,
+		strings.Join(card.Names, "; "),
+		a.StreetAddress,
+		a.StreetAddress2,
+		a.City,
+		a.Region,
+		a.PostalCode,
+		a.Country,
+		a.CountryCode,
+		strings.Join(card.AddressLines, "\n"),
+		strings.Join(ids, "; "),
+	}
+	for i, field := range record {
+		record[i] = escapeFormula(field)
+	}
+	return record
+}
+
+// escapeFormula keeps a field imported from someone's contacts, such as
+// "=HYPERLINK(...)", from running as a formula when the file is opened in a
+// spreadsheet.
+func escapeFormula(field string) string {
+	if len(field) > 0 && strings.ContainsRune("=+-@\t\r", rune(field[0])) {
+		return "'" + field
+	}
+	return field
+}
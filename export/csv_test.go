@@ -0,0 +1,105 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/google/go-cmp/cmp"
+)
+
+var testCards = []cohabitaters.XmasCard{
+	{
+		Names:        []string{"Homer Simpson", "Marge Simpson"},
+		Addressee:    "Homer & Marge Simpson",
+		Address:      cohabitaters.Address{StreetAddress: "742 Evergreen Terrace", City: "Springfield", Region: "IL", PostalCode: "62701"},
+		AddressLines: []string{"742 Evergreen Terrace", "SPRINGFIELD, IL 62701"},
+		Contacts: []cohabitaters.CardContact{
+			{ResourceName: "people/1", Name: "Homer Simpson"},
+			{ResourceName: "people/2", Name: "Marge Simpson"},
+		},
+	},
+	{
+		Names:        []string{`Krusty "The Clown"`},
+		Addressee:    `Krusty "The Clown"`,
+		Address:      cohabitaters.Address{StreetAddress: "1 Studio Lane, Suite 5", City: "Zürich", PostalCode: "8001", Country: "Switzerland", CountryCode: "CH"},
+		AddressLines: []string{"1 Studio Lane, Suite 5", "8001 Zürich", "SWITZERLAND"},
+		Contacts:     []cohabitaters.CardContact{{ResourceName: "people/3", Name: `Krusty "The Clown"`}},
+	},
+}
+
+func TestWriteCSV(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteCSV(&b, testCards, CSVOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "Addressee,Names,Street Address,Street Address 2,City,Region,Postal Code,Country,Country Code,Mailing Address,Contact IDs\r\n" +
+		"Homer & Marge Simpson,Homer Simpson; Marge Simpson,742 Evergreen Terrace,,Springfield,IL,62701,,,\"742 Evergreen Terrace\r\nSPRINGFIELD, IL 62701\",people/1; people/2\r\n" +
+		"\"Krusty \"\"The Clown\"\"\",\"Krusty \"\"The Clown\"\"\",\"1 Studio Lane, Suite 5\",,Zürich,,8001,Switzerland,CH,\"1 Studio Lane, Suite 5\r\n8001 Zürich\r\nSWITZERLAND\",people/3\r\n"
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("WriteCSV() mismatch (-want +got):\n%s", diff)
+	}
+
+	records, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := records[2][0]; got != testCards[1].Addressee {
+		t.Errorf("round trip got: %q, want: %q", got, testCards[1].Addressee)
+	}
+}
+
+func TestWriteCSVBOM(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteCSV(&b, nil, CSVOptions{BOM: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(b.String(), "\xef\xbb\xbfAddressee,") {
+		t.Errorf("missing byte order mark: %q", b.String())
+	}
+}
+
+func TestEscapeFormula(t *testing.T) {
+	tests := []struct {
+		Field string
+		Want  string
+	}{
+		{Field: "", Want: ""},
+		{Field: "Homer Simpson", Want: "Homer Simpson"},
+		{Field: `=HYPERLINK("http://example.com","x")`, Want: `'=HYPERLINK("http://example.com","x")`},
+		{Field: "+1 555 0100", Want: "'+1 555 0100"},
+		{Field: "-2+3", Want: "'-2+3"},
+		{Field: "@SUM(A1)", Want: "'@SUM(A1)"},
+		{Field: "\t=1", Want: "'\t=1"},
+		{Field: "a=b", Want: "a=b"},
+	}
+	for _, tt := range tests {
+		if got := escapeFormula(tt.Field); got != tt.Want {
+			t.Errorf("escapeFormula(%q) got: %q, want: %q", tt.Field, got, tt.Want)
+		}
+	}
+}
+
+func TestWriteCSVEscapesFormulas(t *testing.T) {
+	cards := []cohabitaters.XmasCard{{
+		Names:     []string{"=cmd|' /C calc'!A0"},
+		Addressee: "=cmd|' /C calc'!A0",
+		Contacts:  []cohabitaters.CardContact{{ResourceName: "people/1"}},
+	}}
+	var b bytes.Buffer
+	if err := WriteCSV(&b, cards, CSVOptions{BOM: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(b.String(), utf8BOM))).ReadAll()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, field := range records[1][:2] {
+		if !strings.HasPrefix(field, "'=") {
+			t.Errorf("formula not escaped, got: %q", field)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package export
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/bfallik/cohabitaters/normalize"
+	"github.com/bfallik/cohabitaters/vcard"
+)
+
+// uidNamespace is the RFC 4122 URL namespace. Contact UIDs are name based
+// UUIDs of the contact's resource name in it, so exporting the same contacts
+// twice gives the same UIDs and importers update rather than duplicate them.
+var uidNamespace = [16]byte{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+// uid returns a version 5 UUID URN for name.
+func uid(name string) string {
+	h := sha1.New()
+	h.Write(uidNamespace[:])
+	h.Write([]byte(name))
+	u := h.Sum(nil)[:16]
+	u[6] = u[6]&0x0f | 0x50 // version 5
+	u[8] = u[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// householdUID identifies a household by its members, whatever order they
+// are listed in.
+func householdUID(card cohabitaters.XmasCard) string {
+	names := make([]string, len(card.Contacts))
+	for i, c := range card.Contacts {
+		names[i] = c.ResourceName
+	}
+	sort.Strings(names)
+	return uid("household:" + strings.Join(names, "|"))
+}
+
+// adr returns the ADR property of a card's address, labeled with its
+// formatted mailing address.
+func adr(card cohabitaters.XmasCard) vcard.Property {
+	a := card.Address
+	country := a.Country
+	if len(country) == 0 && len(a.CountryCode) > 0 {
+		country = normalize.CountryName(a.CountryCode)
+	}
+
+	var c vcard.Card
+	c.AddComponents("ADR", []string{"", a.StreetAddress2, a.StreetAddress, a.City, a.Region, a.PostalCode, country},
+		map[string][]string{"TYPE": {"home"}, "LABEL": {strings.Join(card.AddressLines, "\n")}})
+	return c[0]
+}
+
+// VCards converts each card into a KIND:group vCard for the household,
+// followed by a KIND:individual vCard for each of its members. The group
+// names the members with MEMBER properties, and every card carries the
+// household's address.
+func VCards(cards []cohabitaters.XmasCard) []vcard.Card {
+	var out []vcard.Card
+	for _, card := range cards {
+		address := adr(card)
+
+		var group vcard.Card
+		group.AddText("VERSION", "4.0", nil)
+		group.AddText("KIND", "group", nil)
+		group.AddText("UID", householdUID(card), nil)
+		group.AddText("FN", card.Addressee, nil)
+		for _, c := range card.Contacts {
+			group = append(group, vcard.Property{Name: "MEMBER", Value: uid(c.ResourceName)})
+		}
+		group = append(group, address)
+		out = append(out, group)
+
+		for _, c := range card.Contacts {
+			var member vcard.Card
+			member.AddText("VERSION", "4.0", nil)
+			member.AddText("KIND", "individual", nil)
+			member.AddText("UID", uid(c.ResourceName), nil)
+			member.AddText("FN", c.Name, nil)
+			member.AddComponents("N", []string{c.FamilyName, c.GivenName, "", "", ""}, nil)
+			member = append(member, address)
+			out = append(out, member)
+		}
+	}
+	return out
+}
+
+// WriteVCard writes the cards as a vCard 4.0 file; see VCards.
+func WriteVCard(w io.Writer, cards []cohabitaters.XmasCard) error {
+	enc := vcard.NewEncoder(w)
+	for _, card := range VCards(cards) {
+		if err := enc.Encode(card); err != nil {
+			return err
+		}
+	}
+	return nil
+}
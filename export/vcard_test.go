@@ -0,0 +1,105 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bfallik/cohabitaters/vcard"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteVCard(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteVCard(&b, testCards); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "BEGIN:VCARD\r\n" +
+		"VERSION:4.0\r\n" +
+		"KIND:group\r\n" +
+		"UID:" + householdUID(testCards[0]) + "\r\n" +
+		"FN:Homer & Marge Simpson\r\n" +
+		"MEMBER:" + uid("people/1") + "\r\n" +
+		"MEMBER:" + uid("people/2") + "\r\n" +
+		"ADR;LABEL=\"742 Evergreen Terrace^nSPRINGFIELD, IL 62701\";TYPE=home:;;742 Ev\r\n" +
+		" ergreen Terrace;Springfield;IL;62701;\r\n" +
+		"END:VCARD\r\n"
+	if got := b.String()[:len(want)]; got != want {
+		t.Errorf("WriteVCard() mismatch (-want +got):\n%s", cmp.Diff(want, got))
+	}
+}
+
+func Test_uid(t *testing.T) {
+	// a version 5 UUID of the URL namespace, as other implementations compute it
+	if got, want := uid("http://www.example.com/"), "urn:uuid:fcde3c85-2270-590f-9e7c-ee003d65e0e2"; got != want {
+		t.Errorf("uid() got: %v, want: %v", got, want)
+	}
+}
+
+// household is what a reader can recover from the exported vCards.
+type household struct {
+	Addressee string
+	Members   []string
+	Address   []string
+	Label     string
+}
+
+func TestVCardRoundTrip(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteVCard(&b, testCards); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var groups []vcard.Card
+	names := map[string]string{} // UID -> FN of individuals
+	dec := vcard.NewDecoder(&b)
+	for {
+		card, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if card.Value("VERSION") != "4.0" {
+			t.Errorf("unexpected VERSION: %v", card.Value("VERSION"))
+		}
+		switch card.Value("KIND") {
+		case "group":
+			groups = append(groups, card)
+		case "individual":
+			names[card.Value("UID")] = card.Value("FN")
+		}
+	}
+
+	var got []household
+	for _, g := range groups {
+		h := household{
+			Addressee: g.Value("FN"),
+			Address:   g.Get("ADR").Components(),
+			Label:     g.Get("ADR").Param("LABEL"),
+		}
+		for _, m := range g.All("MEMBER") {
+			h.Members = append(h.Members, names[m.Text()])
+		}
+		got = append(got, h)
+	}
+
+	var want []household
+	for _, card := range testCards {
+		a := card.Address
+		h := household{
+			Addressee: card.Addressee,
+			Members:   card.Names,
+			Address:   []string{"", a.StreetAddress2, a.StreetAddress, a.City, a.Region, a.PostalCode, a.Country},
+			Label:     strings.Join(card.AddressLines, "\n"),
+		}
+		want = append(want, h)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,120 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/bfallik/cohabitaters/xlsx"
+)
+
+// XLSX sheet names.
+const (
+	SheetHouseholds = "Households"
+	SheetSkipped    = "Skipped contacts"
+	SheetContacts   = "Per-contact detail"
+)
+
+// householdsHeader is CSVHeader with the household's number first, which the
+// per-contact sheet refers to, and whether a person should check it last.
+var householdsHeader = append(append([]string{"Household"}, CSVHeader...), "Needs Review")
+
+var skippedHeader = []string{"Name", "Contact ID", "Reason", "Detail", "Candidate Addresses"}
+
+var contactsHeader = []string{
+	"Contact ID",
+	"Name",
+	"Given Name",
+	"Family Name",
+	"Household",
+	"Addressee",
+	"Contact's Address",
+	"Grouped Because",
+}
+
+// XLSX converts a result into a workbook with a row per household, a row per
+// skipped contact and a row per contact on a card.
+func XLSX(res cohabitaters.Result) *xlsx.Workbook {
+	var wb xlsx.Workbook
+
+	households := wb.AddSheet(SheetHouseholds)
+	households.SetHeader(householdsHeader...)
+	households.Widths = map[int]float64{10: 36} // Mailing Address
+	for i, card := range res.Cards {
+		row := []any{i + 1}
+		for _, v := range csvRecord(card) {
+			row = append(row, v)
+		}
+		households.AddRow(append(row, yesNo(card.NeedsReview()))...)
+	}
+
+	skipped := wb.AddSheet(SheetSkipped)
+	skipped.SetHeader(skippedHeader...)
+	for _, s := range res.Skipped {
+		var addrs []string
+		for _, a := range s.Addresses {
+			addrs = append(addrs, fmt.Sprintf("%s: %s", a.Type, oneLineAddress(a.Address)))
+		}
+		skipped.AddRow(s.Name, s.ResourceName, string(s.Reason), s.Detail, strings.Join(addrs, "\n"))
+	}
+
+	contacts := wb.AddSheet(SheetContacts)
+	contacts.SetHeader(contactsHeader...)
+	for i, card := range res.Cards {
+		for _, c := range card.Contacts {
+			contacts.AddRow(c.ResourceName, c.Name, c.GivenName, c.FamilyName, i+1, card.Addressee,
+				oneLineAddress(c.Address), strings.Join(matchReasons(card, c.ResourceName), "\n"))
+		}
+	}
+	return &wb
+}
+
+// WriteXLSX writes the result as an Excel workbook; see XLSX.
+func WriteXLSX(w io.Writer, res cohabitaters.Result) error {
+	_, err := XLSX(res).WriteTo(w)
+	return err
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "Yes"
+	}
+	return "No"
+}
+
+func oneLineAddress(a cohabitaters.Address) string {
+	var parts []string
+	for _, p := range []string{a.StreetAddress, a.StreetAddress2, a.City, a.Region, a.PostalCode, a.Country} {
+		if p = strings.Join(strings.Fields(p), " "); len(p) > 0 {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// matchReasons explains why a contact shares its card, one line per contact
+// it was matched with.
+func matchReasons(card cohabitaters.XmasCard, resourceName string) []string {
+	names := make(map[string]string, len(card.Contacts))
+	for _, c := range card.Contacts {
+		names[c.ResourceName] = c.Name
+	}
+
+	var out []string
+	for _, m := range card.Matches {
+		other := m.B
+		switch resourceName {
+		case m.A:
+		case m.B:
+			other = m.A
+		default:
+			continue
+		}
+		if n, ok := names[other]; ok {
+			other = n
+		}
+		out = append(out, fmt.Sprintf("%s: %s", other, m.Reason))
+	}
+	return out
+}
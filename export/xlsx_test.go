@@ -0,0 +1,113 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/google/go-cmp/cmp"
+)
+
+// readSheet returns the text of every cell in one worksheet of an xlsx file,
+// row by row, with blanks for empty cells between filled ones.
+func readSheet(t *testing.T, b []byte, n int) [][]string {
+	t.Helper()
+	z, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f, err := z.Open("xl/worksheets/sheet" + strconv.Itoa(n) + ".xml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var ws struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(data, &ws); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out [][]string
+	for _, r := range ws.Rows {
+		var row []string
+		for _, c := range r.Cells {
+			col := int(c.Ref[0] - 'A')
+			for len(row) < col {
+				row = append(row, "")
+			}
+			row = append(row, c.Value+c.Inline)
+		}
+		out = append(out, row)
+	}
+	return out
+}
+
+func TestWriteXLSX(t *testing.T) {
+	cards := append([]cohabitaters.XmasCard(nil), testCards...)
+	cards[0].Matches = []cohabitaters.Match{{A: "people/1", B: "people/2", Reason: "street 1.00, city 1.00", Review: true}}
+	cards[0].Contacts = []cohabitaters.CardContact{
+		{ResourceName: "people/1", Name: "Homer Simpson", GivenName: "Homer", FamilyName: "Simpson", Address: cards[0].Address},
+		{ResourceName: "people/2", Name: "Marge Simpson", GivenName: "Marge", FamilyName: "Simpson", Address: cards[0].Address},
+	}
+	res := cohabitaters.Result{
+		Cards: cards,
+		Skipped: []cohabitaters.SkippedContact{
+			{ResourceName: "people/4", Name: "Sideshow Bob", Reason: cohabitaters.SkipAmbiguousAddress, Addresses: []cohabitaters.ContactAddress{
+				{Type: "home", Address: cohabitaters.Address{StreetAddress: "1 Prison Rd", City: "Springfield"}},
+				{Type: "other", Address: cohabitaters.Address{City: "Capital City"}},
+			}},
+			{ResourceName: "people/5", Reason: cohabitaters.SkipAPIError, Detail: "not found"},
+		},
+	}
+
+	var b bytes.Buffer
+	if err := WriteXLSX(&b, res); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	households := readSheet(t, b.Bytes(), 1)
+	if diff := cmp.Diff(householdsHeader, households[0]); diff != "" {
+		t.Errorf("Households header mismatch (-want +got):\n%s", diff)
+	}
+	wantHomer := []string{"1", "Homer & Marge Simpson", "Homer Simpson; Marge Simpson", "742 Evergreen Terrace", "", "Springfield", "IL", "62701", "", "",
+		"742 Evergreen Terrace\nSPRINGFIELD, IL 62701", "people/1; people/2", "Yes"}
+	if diff := cmp.Diff(wantHomer, households[1]); diff != "" {
+		t.Errorf("Households row mismatch (-want +got):\n%s", diff)
+	}
+	if got := len(households); got != 3 {
+		t.Errorf("unexpected Households rows, got: %v, want: %v", got, 3)
+	}
+
+	wantSkipped := [][]string{
+		skippedHeader,
+		{"Sideshow Bob", "people/4", "ambiguous address", "", "home: 1 Prison Rd, Springfield\nother: Capital City"},
+		{"", "people/5", "API error", "not found"},
+	}
+	if diff := cmp.Diff(wantSkipped, readSheet(t, b.Bytes(), 2)); diff != "" {
+		t.Errorf("Skipped contacts mismatch (-want +got):\n%s", diff)
+	}
+
+	wantContacts := [][]string{
+		contactsHeader,
+		{"people/1", "Homer Simpson", "Homer", "Simpson", "1", "Homer & Marge Simpson", "742 Evergreen Terrace, Springfield, IL, 62701", "Marge Simpson: street 1.00, city 1.00"},
+		{"people/2", "Marge Simpson", "Marge", "Simpson", "1", "Homer & Marge Simpson", "742 Evergreen Terrace, Springfield, IL, 62701", "Homer Simpson: street 1.00, city 1.00"},
+		{"people/3", `Krusty "The Clown"`, "", "", "2", `Krusty "The Clown"`},
+	}
+	if diff := cmp.Diff(wantContacts, readSheet(t, b.Bytes(), 3)); diff != "" {
+		t.Errorf("Per-contact detail mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,140 @@
+package cohabitaters
+
+import (
+	_ "embed"
+	"encoding/json"
+	"strings"
+
+	"github.com/bfallik/cohabitaters/normalize"
+)
+
+// addressFormat describes how one country lays out a postal address, after
+// the format strings of Google's libaddressinput. Fmt places the fields:
+//
+//	%A  street address lines
+//	%C  city or post town
+//	%S  state, province or prefecture
+//	%Z  postal code
+//	%n  line break
+//
+// Upper lists the field letters that are written in capitals.
+type addressFormat struct {
+	Fmt   string `json:"fmt"`
+	Upper string `json:"upper"`
+	// RegionCodes abbreviates states and provinces, e.g. "Illinois" to "IL".
+	RegionCodes bool `json:"region_codes"`
+}
+
+//go:embed addressformats.json
+var addressFormatsJSON []byte
+
+// addressFormats maps ISO 3166-1 alpha-2 codes to formats; "ZZ" is the
+// fallback for every other country.
+var addressFormats = func() map[string]addressFormat {
+	var m map[string]addressFormat
+	if err := json.Unmarshal(addressFormatsJSON, &m); err != nil {
+		panic(err)
+	}
+	return m
+}()
+
+// Lines formats the address as the lines of a mailing label, ordered and
+// capitalized the way the destination country expects. When the address is
+// in another country than senderCountry, an ISO 3166-1 alpha-2 code, the
+// destination country's name is added in capitals as the last line. An
+// address with no country is assumed to be domestic, and an empty
+// senderCountry adds the country to every address that has one.
+func (a Address) Lines(senderCountry string) []string {
+	code := a.countryCode()
+	foreign := len(code) > 0 && !strings.EqualFold(code, senderCountry)
+	if len(code) == 0 {
+		// a country we can't place is still foreign mail
+		foreign = len(strings.TrimSpace(a.Country)) > 0
+		if !foreign {
+			code = strings.ToUpper(senderCountry)
+		}
+	}
+	format, ok := addressFormats[code]
+	if !ok {
+		format = addressFormats["ZZ"]
+	}
+
+	region := strings.TrimSpace(a.Region)
+	if r := normalize.Region(region); format.RegionCodes && len(r) == 2 {
+		region = r
+	}
+
+	fields := map[byte]string{
+		'A': joinLines(a.StreetAddress, a.StreetAddress2),
+		'C': oneLine(a.City),
+		'S': oneLine(region),
+		'Z': oneLine(a.PostalCode),
+	}
+	for _, f := range []byte(format.Upper) {
+		fields[f] = strings.ToUpper(fields[f])
+	}
+
+	var lines []string
+	for _, line := range strings.Split(format.Fmt, "%n") {
+		for _, l := range strings.Split(formatLine(line, fields), "\n") {
+			if l = strings.TrimSpace(l); len(l) > 0 {
+				lines = append(lines, l)
+			}
+		}
+	}
+
+	if foreign {
+		country := normalize.CountryName(code)
+		if len(country) == 0 {
+			country = oneLine(a.Country)
+		}
+		lines = append(lines, strings.ToUpper(country))
+	}
+	return lines
+}
+
+// formatLine fills in one line of a format string. The separator before a
+// missing field is dropped, so "%C, %S %Z" with no region gives
+// "Springfield 62701".
+func formatLine(line string, fields map[byte]string) string {
+	var (
+		b       strings.Builder
+		literal string
+	)
+	for i := 0; i < len(line); i++ {
+		if line[i] != '%' || i+1 == len(line) {
+			literal += line[i : i+1]
+			continue
+		}
+		i++
+		value := fields[line[i]]
+		if len(value) == 0 {
+			literal = "" // drop the separator before a missing field
+			continue
+		}
+		// a separator needs something before it; a prefix such as
+		// "SINGAPORE " doesn't
+		if b.Len() > 0 || len(strings.Trim(literal, " ,-")) > 0 {
+			b.WriteString(literal)
+		}
+		literal = ""
+		b.WriteString(value)
+	}
+	return b.String()
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func joinLines(lines ...string) string {
+	var out []string
+	for _, l := range lines {
+		for _, part := range strings.Split(l, "\n") {
+			if part = oneLine(part); len(part) > 0 {
+				out = append(out, part)
+			}
+		}
+	}
+	return strings.Join(out, "\n")
+}
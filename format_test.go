@@ -0,0 +1,149 @@
+package cohabitaters
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestAddressLines(t *testing.T) {
+	tests := []struct {
+		Desc   string
+		Sender string
+		In     Address
+	}{
+		{
+			Desc:   "US domestic",
+			Sender: "US",
+			In:     Address{StreetAddress: "742 Evergreen Terrace", City: "Springfield", Region: "Illinois", PostalCode: "62701", Country: "USA"},
+		},
+		{
+			Desc:   "US without a country",
+			Sender: "US",
+			In:     Address{StreetAddress: "742 Evergreen Terrace", StreetAddress2: "Apt 2", City: "Springfield", Region: "IL", PostalCode: "62701"},
+		},
+		{
+			Desc:   "US without a region",
+			Sender: "US",
+			In:     Address{StreetAddress: "742 Evergreen Terrace", City: "Springfield", PostalCode: "62701"},
+		},
+		{
+			Desc:   "US from Canada",
+			Sender: "CA",
+			In:     Address{StreetAddress: "742 Evergreen Terrace", City: "Springfield", Region: "IL", PostalCode: "62701", CountryCode: "US"},
+		},
+		{
+			Desc:   "Canada",
+			Sender: "US",
+			In:     Address{StreetAddress: "24 Sussex Drive", City: "Ottawa", Region: "Ontario", PostalCode: "K1M 1M4", Country: "Canada"},
+		},
+		{
+			Desc:   "United Kingdom",
+			Sender: "US",
+			In:     Address{StreetAddress: "221B Baker Street", City: "London", PostalCode: "NW1 6XE", Country: "United Kingdom"},
+		},
+		{
+			Desc:   "United Kingdom domestic",
+			Sender: "GB",
+			In:     Address{StreetAddress: "221B Baker Street", City: "London", PostalCode: "NW1 6XE", Country: "England"},
+		},
+		{
+			Desc:   "Germany",
+			Sender: "US",
+			In:     Address{StreetAddress: "Unter den Linden 77", City: "Berlin", PostalCode: "10117", CountryCode: "de"},
+		},
+		{
+			Desc:   "France",
+			Sender: "US",
+			In:     Address{StreetAddress: "55 Rue du Faubourg Saint-Honoré", City: "Paris", PostalCode: "75008", Country: "France"},
+		},
+		{
+			Desc:   "Japan",
+			Sender: "US",
+			In:     Address{StreetAddress: "1-1 Chiyoda", City: "Chiyoda-ku", Region: "Tokyo", PostalCode: "100-8111", Country: "Japan"},
+		},
+		{
+			Desc:   "Singapore",
+			Sender: "US",
+			In:     Address{StreetAddress: "1 Fullerton Square", PostalCode: "049178", Country: "Singapore"},
+		},
+		{
+			Desc:   "unlisted country",
+			Sender: "US",
+			In:     Address{StreetAddress: "Kenyatta Avenue 10", City: "Nairobi", PostalCode: "00100", Country: "Kenya"},
+		},
+		{
+			Desc:   "Iceland",
+			Sender: "US",
+			In:     Address{StreetAddress: "Laufásvegur 21", City: "Reykjavík", PostalCode: "101", Country: "Iceland"},
+		},
+		{
+			Desc:   "unrecognized country",
+			Sender: "US",
+			In:     Address{StreetAddress: "1 Main Street", City: "Capital City", Country: "Atlantis"},
+		},
+		{
+			Desc: "no sender",
+			In:   Address{StreetAddress: "742 Evergreen Terrace", City: "Springfield", Region: "IL", PostalCode: "62701", CountryCode: "US"},
+		},
+	}
+
+	var b strings.Builder
+	for _, test := range tests {
+		fmt.Fprintf(&b, "== %s (from %q)\n", test.Desc, test.Sender)
+		for _, line := range test.In.Lines(test.Sender) {
+			fmt.Fprintln(&b, line)
+		}
+		fmt.Fprintln(&b)
+	}
+	got := b.String()
+
+	golden := filepath.Join("testdata", "address_lines.golden")
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(string(want), got); diff != "" {
+		t.Errorf("Lines() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_formatLine(t *testing.T) {
+	fields := map[byte]string{'C': "Springfield", 'S': "IL", 'Z': "62701"}
+	tests := []struct {
+		Line string
+		Drop byte
+		Want string
+	}{
+		{Line: "%C, %S %Z", Want: "Springfield, IL 62701"},
+		{Line: "%C, %S %Z", Drop: 'S', Want: "Springfield 62701"},
+		{Line: "%C, %S %Z", Drop: 'C', Want: "IL 62701"},
+		{Line: "%C, %S %Z", Drop: 'Z', Want: "Springfield, IL"},
+		{Line: "SINGAPORE %Z", Want: "SINGAPORE 62701"},
+		{Line: "SINGAPORE %Z", Drop: 'Z', Want: ""},
+	}
+
+	for _, test := range tests {
+		in := map[byte]string{}
+		for k, v := range fields {
+			if k != test.Drop {
+				in[k] = v
+			}
+		}
+		if got := formatLine(test.Line, in); got != test.Want {
+			t.Errorf("formatLine(%q) without %c got: %q, want: %q", test.Line, test.Drop, got, test.Want)
+		}
+	}
+}
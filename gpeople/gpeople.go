@@ -0,0 +1,245 @@
+// Package gpeople adapts the Google People API to a cohabitaters.ContactSource.
+package gpeople
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bfallik/cohabitaters"
+	"google.golang.org/api/people/v1"
+)
+
+const (
+	// maxBatchGet is the People API limit on resource names per people.getBatchGet call.
+	maxBatchGet = 200
+
+	defaultWorkers = 4
+)
+
+// Source reads contacts and contact groups through a People API service.
+type Source struct {
+	svc       *people.Service
+	batchSize int
+	workers   int
+}
+
+var _ cohabitaters.AllContactsSource = (*Source)(nil)
+
+func New(svc *people.Service) *Source {
+	return &Source{
+		svc:       svc,
+		batchSize: maxBatchGet,
+		workers:   defaultWorkers,
+	}
+}
+
+// systemGroups are the contact groups Google maintains for every user. They
+// say nothing about who lives together.
+var systemGroups = map[string]bool{
+	"contactGroups/all":         true,
+	"contactGroups/blocked":     true,
+	"contactGroups/chatBuddies": true,
+	"contactGroups/coworkers":   true,
+	"contactGroups/family":      true,
+	"contactGroups/friends":     true,
+	"contactGroups/myContacts":  true,
+	"contactGroups/starred":     true,
+}
+
+func NewAddress(in *people.Address) cohabitaters.Address {
+	return cohabitaters.Address{
+		StreetAddress:  in.StreetAddress,
+		StreetAddress2: in.ExtendedAddress,
+		City:           in.City,
+		Region:         in.Region,
+		Country:        in.Country,
+		CountryCode:    in.CountryCode,
+		PostalCode:     in.PostalCode,
+	}
+}
+
+func NewContactGroup(in *people.ContactGroup) cohabitaters.ContactGroup {
+	return cohabitaters.ContactGroup{
+		ResourceName:  in.ResourceName,
+		Name:          in.Name,
+		FormattedName: in.FormattedName,
+		MemberCount:   int(in.MemberCount),
+	}
+}
+
+func NewContact(in *people.Person) cohabitaters.Contact {
+	c := cohabitaters.Contact{ResourceName: in.ResourceName}
+	for _, n := range in.Names {
+		c.Names = append(c.Names, cohabitaters.Name{
+			DisplayName: n.DisplayName,
+			GivenName:   n.GivenName,
+			FamilyName:  n.FamilyName,
+		})
+	}
+	for _, a := range in.Addresses {
+		ca := cohabitaters.ContactAddress{
+			Address: NewAddress(a),
+			Type:    a.Type,
+		}
+		if a.Metadata != nil {
+			ca.Primary = a.Metadata.Primary
+		}
+		c.Addresses = append(c.Addresses, ca)
+	}
+	for _, r := range in.Relations {
+		c.Relations = append(c.Relations, cohabitaters.Relation{Person: r.Person, Type: r.Type})
+	}
+	for _, m := range in.Memberships {
+		if m.ContactGroupMembership == nil || systemGroups[m.ContactGroupMembership.ContactGroupResourceName] {
+			continue
+		}
+		c.Memberships = append(c.Memberships, m.ContactGroupMembership.ContactGroupResourceName)
+	}
+	return c
+}
+
+// AllContactsGroup returns the group Google keeps of the contacts the user
+// added, as opposed to the other people they've been in touch with.
+func (s *Source) AllContactsGroup() string {
+	return "contactGroups/myContacts"
+}
+
+// ContactGroups lists every contact group, following the pages of the
+// response until the last.
+func (s *Source) ContactGroups(ctx context.Context) ([]cohabitaters.ContactGroup, error) {
+	var groups []cohabitaters.ContactGroup
+	var pageToken string
+	for {
+		resp, err := s.svc.ContactGroups.List().PageToken(pageToken).Context(ctx).Do()
+		if err != nil {
+			return nil, err
+		}
+		for _, cg := range resp.ContactGroups {
+			groups = append(groups, NewContactGroup(cg))
+		}
+		pageToken = resp.NextPageToken
+		if len(pageToken) == 0 {
+			return groups, nil
+		}
+	}
+}
+
+// GroupMembers first asks for the group's member count and then requests
+// exactly that many members, since contactGroups.get returns no members unless
+// told how many to return.
+func (s *Source) GroupMembers(ctx context.Context, groupResourceName string) ([]string, error) {
+	cg, err := s.svc.ContactGroups.Get(groupResourceName).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	if cg.MemberCount == 0 {
+		return nil, nil
+	}
+
+	resp, err := s.svc.ContactGroups.Get(groupResourceName).MaxMembers(cg.MemberCount).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return resp.MemberResourceNames, nil
+}
+
+func chunk(in []string, size int) [][]string {
+	var out [][]string
+	for len(in) > size {
+		out = append(out, in[:size])
+		in = in[size:]
+	}
+	if len(in) > 0 {
+		out = append(out, in)
+	}
+	return out
+}
+
+// Contacts splits resourceNames into batches the People API accepts and
+// fetches them concurrently on a bounded number of workers. The result is in
+// the same order as a single batchGet of every name would return.
+func (s *Source) Contacts(ctx context.Context, resourceNames []string) ([]cohabitaters.Contact, error) {
+	batches := chunk(resourceNames, s.batchSize)
+	results := make([][]cohabitaters.Contact, len(batches))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+	)
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(s.workers, len(batches)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range work {
+				contacts, err := s.batchGet(ctx, batches[idx])
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+					mu.Unlock()
+					continue
+				}
+				results[idx] = contacts
+			}
+		}()
+	}
+
+	for idx := range batches {
+		work <- idx
+	}
+	close(work)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	var contacts []cohabitaters.Contact
+	for _, r := range results {
+		contacts = append(contacts, r...)
+	}
+	return contacts, nil
+}
+
+func (s *Source) batchGet(ctx context.Context, resourceNames []string) ([]cohabitaters.Contact, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.svc.People.GetBatchGet().ResourceNames(resourceNames...).PersonFields("names,addresses,relations,memberships").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	contacts := make([]cohabitaters.Contact, 0, len(resp.Responses))
+	for _, pr := range resp.Responses {
+		if pr.Person == nil || pr.Status != nil && pr.Status.Code != 0 {
+			msg := "no person returned"
+			if pr.Status != nil && len(pr.Status.Message) > 0 {
+				msg = pr.Status.Message
+			}
+			contacts = append(contacts, cohabitaters.Contact{ResourceName: pr.RequestedResourceName, FetchError: msg})
+			continue
+		}
+		contacts = append(contacts, NewContact(pr.Person))
+	}
+	return contacts, nil
+}
+
+// Me returns the authorized user's own profile, whose addresses make a
+// return address. Reading them needs the user.addresses.read scope.
+func (s *Source) Me(ctx context.Context) (cohabitaters.Contact, error) {
+	p, err := s.svc.People.Get("people/me").PersonFields("names,addresses").Context(ctx).Do()
+	if err != nil {
+		return cohabitaters.Contact{}, err
+	}
+	return NewContact(p), nil
+}
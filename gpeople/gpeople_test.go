@@ -0,0 +1,295 @@
+package gpeople
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	"google.golang.org/api/people/v1"
+)
+
+// fakePeopleAPI is a stand-in for the subset of the People API the Source uses.
+type fakePeopleAPI struct {
+	groups   map[string][]string // contactGroups/x -> member resource names
+	pageSize int                 // contact groups listed per page
+	failures map[string]string   // people/x -> error message
+
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+	batchCalls  atomic.Int32
+}
+
+func newFakePeopleAPI(groupSize int) *fakePeopleAPI {
+	members := make([]string, groupSize)
+	for i := range members {
+		members[i] = fmt.Sprintf("people/c%d", i)
+	}
+	return &fakePeopleAPI{groups: map[string][]string{"contactGroups/family": members}, pageSize: 1}
+}
+
+func fakePerson(resourceName string) *people.Person {
+	return &people.Person{
+		ResourceName: resourceName,
+		Names:        []*people.Name{{DisplayName: "Name of " + resourceName}},
+		Addresses:    []*people.Address{{StreetAddress: resourceName + " Main St", City: "Springfield", Type: "home"}},
+	}
+}
+
+func (f *fakePeopleAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/v1/people:batchGet":
+		f.batchGet(w, r)
+	case r.URL.Path == "/v1/people/me":
+		_ = json.NewEncoder(w).Encode(fakePerson("people/me"))
+	case r.URL.Path == "/v1/contactGroups":
+		f.listGroups(w, r)
+	case strings.HasPrefix(r.URL.Path, "/v1/contactGroups/"):
+		f.getGroup(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// listGroups lists the groups in order of resource name, pageSize at a time.
+func (f *fakePeopleAPI) listGroups(w http.ResponseWriter, r *http.Request) {
+	rns := make([]string, 0, len(f.groups))
+	for rn := range f.groups {
+		rns = append(rns, rn)
+	}
+	slices.Sort(rns)
+
+	start, _ := strconv.Atoi(r.URL.Query().Get("pageToken"))
+	end := min(start+f.pageSize, len(rns))
+	resp := people.ListContactGroupsResponse{TotalItems: int64(len(rns))}
+	for _, rn := range rns[start:end] {
+		resp.ContactGroups = append(resp.ContactGroups, &people.ContactGroup{
+			ResourceName:  rn,
+			Name:          strings.TrimPrefix(rn, "contactGroups/"),
+			FormattedName: strings.TrimPrefix(rn, "contactGroups/"),
+			MemberCount:   int64(len(f.groups[rn])),
+		})
+	}
+	if end < len(rns) {
+		resp.NextPageToken = strconv.Itoa(end)
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (f *fakePeopleAPI) getGroup(w http.ResponseWriter, r *http.Request) {
+	rn := strings.TrimPrefix(r.URL.Path, "/v1/")
+	members, ok := f.groups[rn]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	maxMembers, _ := strconv.Atoi(r.URL.Query().Get("maxMembers"))
+	cg := people.ContactGroup{
+		ResourceName:        rn,
+		MemberCount:         int64(len(members)),
+		MemberResourceNames: members[:min(maxMembers, len(members))],
+	}
+	_ = json.NewEncoder(w).Encode(cg)
+}
+
+func (f *fakePeopleAPI) batchGet(w http.ResponseWriter, r *http.Request) {
+	f.batchCalls.Add(1)
+	n := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+	for {
+		m := f.maxInFlight.Load()
+		if n <= m || f.maxInFlight.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond) // give other workers a chance to overlap
+
+	names := r.URL.Query()["resourceNames"]
+	if len(names) > maxBatchGet {
+		http.Error(w, "too many resource names", http.StatusBadRequest)
+		return
+	}
+
+	var resp people.GetPeopleResponse
+	for _, rn := range names {
+		if msg, ok := f.failures[rn]; ok {
+			resp.Responses = append(resp.Responses, &people.PersonResponse{
+				RequestedResourceName: rn,
+				Status:                &people.Status{Code: 5, Message: msg},
+			})
+			continue
+		}
+		resp.Responses = append(resp.Responses, &people.PersonResponse{
+			RequestedResourceName: rn,
+			Person:                fakePerson(rn),
+		})
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func newTestSource(t *testing.T, h http.Handler) *Source {
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+
+	svc, err := people.NewService(context.Background(), option.WithEndpoint(ts.URL+"/"), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return New(svc)
+}
+
+func TestContactGroups(t *testing.T) {
+	api := newFakePeopleAPI(3)
+	api.groups["contactGroups/friends"] = []string{"people/c7"}
+	src := newTestSource(t, api)
+
+	got, err := src.ContactGroups(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []cohabitaters.ContactGroup{
+		{ResourceName: "contactGroups/family", Name: "family", FormattedName: "family", MemberCount: 3},
+		{ResourceName: "contactGroups/friends", Name: "friends", FormattedName: "friends", MemberCount: 1},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ContactGroups() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGroupMembers(t *testing.T) {
+	for _, size := range []int{0, 1, 1000, 4321} {
+		t.Run(strconv.Itoa(size), func(t *testing.T) {
+			api := newFakePeopleAPI(size)
+			src := newTestSource(t, api)
+
+			got, err := src.GroupMembers(context.Background(), "contactGroups/family")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != size {
+				t.Errorf("unexpected member count, got: %v, want: %v", len(got), size)
+			}
+		})
+	}
+}
+
+func TestContacts(t *testing.T) {
+	const size = 4321
+	api := newFakePeopleAPI(size)
+	src := newTestSource(t, api)
+	ctx := context.Background()
+
+	members, err := src.GroupMembers(ctx, "contactGroups/family")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := src.Contacts(ctx, members)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a single unbounded batchGet would return every person in request order
+	for idx, rn := range members {
+		if diff := cmp.Diff(NewContact(fakePerson(rn)), got[idx]); diff != "" {
+			t.Fatalf("Contacts()[%d] mismatch (-want +got):\n%s", idx, diff)
+		}
+	}
+	if len(got) != size {
+		t.Errorf("unexpected contact count, got: %v, want: %v", len(got), size)
+	}
+
+	wantCalls := (size + maxBatchGet - 1) / maxBatchGet
+	if got := int(api.batchCalls.Load()); got != wantCalls {
+		t.Errorf("unexpected batchGet calls, got: %v, want: %v", got, wantCalls)
+	}
+	if got := int(api.maxInFlight.Load()); got > defaultWorkers {
+		t.Errorf("too many concurrent batchGet calls, got: %v, want <= %v", got, defaultWorkers)
+	}
+}
+
+func TestContactsError(t *testing.T) {
+	src := newTestSource(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+
+	if _, err := src.Contacts(context.Background(), []string{"people/1", "people/2"}); err == nil {
+		t.Errorf("missing expected error")
+	}
+}
+
+func TestContactsPartialFailure(t *testing.T) {
+	api := newFakePeopleAPI(0)
+	api.failures = map[string]string{"people/2": "Requested entity was not found."}
+	src := newTestSource(t, api)
+
+	got, err := src.Contacts(context.Background(), []string{"people/1", "people/2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []cohabitaters.Contact{
+		NewContact(fakePerson("people/1")),
+		{ResourceName: "people/2", FetchError: "Requested entity was not found."},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Contacts() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMe(t *testing.T) {
+	src := newTestSource(t, newFakePeopleAPI(0))
+
+	got, err := src.Me(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(NewContact(fakePerson("people/me")), got); diff != "" {
+		t.Errorf("Me() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewContact(t *testing.T) {
+	in := &people.Person{
+		ResourceName: "people/1",
+		Names:        []*people.Name{{DisplayName: "Homer Simpson", GivenName: "Homer", FamilyName: "Simpson"}},
+		Relations:    []*people.Relation{{Person: "Marge Simpson", Type: "spouse"}},
+		Memberships: []*people.Membership{
+			{ContactGroupMembership: &people.ContactGroupMembership{ContactGroupResourceName: "contactGroups/myContacts"}},
+			{ContactGroupMembership: &people.ContactGroupMembership{ContactGroupResourceName: "contactGroups/4c1d"}},
+			{DomainMembership: &people.DomainMembership{InViewerDomain: true}},
+		},
+	}
+
+	want := cohabitaters.Contact{
+		ResourceName: "people/1",
+		Names:        []cohabitaters.Name{{DisplayName: "Homer Simpson", GivenName: "Homer", FamilyName: "Simpson"}},
+		Relations:    []cohabitaters.Relation{{Person: "Marge Simpson", Type: "spouse"}},
+		Memberships:  []string{"contactGroups/4c1d"},
+	}
+	if diff := cmp.Diff(want, NewContact(in)); diff != "" {
+		t.Errorf("NewContact() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_chunk(t *testing.T) {
+	in := []string{"a", "b", "c", "d", "e"}
+	want := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+	if diff := cmp.Diff(want, chunk(in, 2)); diff != "" {
+		t.Errorf("chunk() mismatch (-want +got):\n%s", diff)
+	}
+	if got := chunk(nil, 2); len(got) != 0 {
+		t.Errorf("unexpected chunks for empty input: %v", got)
+	}
+}
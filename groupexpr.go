@@ -0,0 +1,310 @@
+package cohabitaters
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// GroupOp combines the contacts of two group expressions.
+type GroupOp rune
+
+const (
+	// GroupUnion selects contacts in either operand, written ∪ or +.
+	GroupUnion GroupOp = '∪'
+	// GroupIntersect selects contacts in both operands, written ∩ or &.
+	GroupIntersect GroupOp = '∩'
+	// GroupMinus selects contacts in the left operand but not the right,
+	// written − or a hyphen with spaces around it.
+	GroupMinus GroupOp = '−'
+)
+
+// AllContactsGroup in a group expression selects every contact in the
+// address book.
+const AllContactsGroup = "*"
+
+// AllContactsSource is implemented by contact sources with a group that
+// holds every contact, which the group expression "*" selects.
+type AllContactsSource interface {
+	ContactSource
+	// AllContactsGroup returns the resource name of that group, or an
+	// empty string if there isn't one after all.
+	AllContactsGroup() string
+}
+
+// GroupExpr is a set expression over contact groups, such as
+// "Family ∪ Friends − Sent 2025", selecting the contacts to coalesce. It is
+// either a single group, named by Group, or Op applied to Left and Right.
+type GroupExpr struct {
+	Group       string
+	Op          GroupOp
+	Left, Right *GroupExpr
+}
+
+// ParseGroupExpr parses a group expression. Groups are written by name and
+// combined with ∪ (or +), ∩ (or &) and − (or " - "); ∩ binds tighter than
+// the others, which apply left to right, and parentheses group. A name
+// containing an operator can be put in double quotes. "*" stands for every
+// contact.
+func ParseGroupExpr(s string) (*GroupExpr, error) {
+	tokens, err := tokenizeGroupExpr(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty group expression")
+	}
+	p := groupExprParser{tokens: tokens}
+	e, err := p.union()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in group expression", p.tokens[p.pos].text)
+	}
+	return e, nil
+}
+
+type groupExprToken struct {
+	op   rune // an operator or parenthesis, or 0 for a group name
+	text string
+}
+
+// groupExprOps maps each operator, including the ASCII spellings, to its
+// GroupOp.
+var groupExprOps = map[rune]GroupOp{
+	'∪': GroupUnion,
+	'+': GroupUnion,
+	'∩': GroupIntersect,
+	'&': GroupIntersect,
+	'−': GroupMinus,
+}
+
+func isGroupExprOp(r rune) bool {
+	_, ok := groupExprOps[r]
+	return ok || r == '(' || r == ')'
+}
+
+// isSpacedHyphen reports whether rs[i] is a hyphen standing on its own,
+// which is a minus, rather than part of a name like "Sent-2025".
+func isSpacedHyphen(rs []rune, i int) bool {
+	return rs[i] == '-' &&
+		(i == 0 || unicode.IsSpace(rs[i-1])) &&
+		(i+1 == len(rs) || unicode.IsSpace(rs[i+1]))
+}
+
+func tokenizeGroupExpr(s string) ([]groupExprToken, error) {
+	var tokens []groupExprToken
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case isGroupExprOp(r):
+			tokens = append(tokens, groupExprToken{op: r, text: string(r)})
+			i++
+		case isSpacedHyphen(rs, i):
+			tokens = append(tokens, groupExprToken{op: '−', text: "-"})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(rs) && rs[end] != '"' {
+				end++
+			}
+			if end == len(rs) {
+				return nil, fmt.Errorf("unterminated quote in group expression")
+			}
+			tokens = append(tokens, groupExprToken{text: string(rs[i+1 : end])})
+			i = end + 1
+		default:
+			end := i
+			for end < len(rs) && !isGroupExprOp(rs[end]) && !isSpacedHyphen(rs, end) && rs[end] != '"' {
+				end++
+			}
+			tokens = append(tokens, groupExprToken{text: strings.TrimSpace(string(rs[i:end]))})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type groupExprParser struct {
+	tokens []groupExprToken
+	pos    int
+}
+
+func (p *groupExprParser) peek() rune {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].op != 0 {
+		return p.tokens[p.pos].op
+	}
+	return 0
+}
+
+// union parses operands joined by ∪ and −.
+func (p *groupExprParser) union() (*GroupExpr, error) {
+	left, err := p.intersection()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := groupExprOps[p.peek()]
+		if op != GroupUnion && op != GroupMinus {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.intersection()
+		if err != nil {
+			return nil, err
+		}
+		left = &GroupExpr{Op: op, Left: left, Right: right}
+	}
+}
+
+// intersection parses operands joined by ∩.
+func (p *groupExprParser) intersection() (*GroupExpr, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	for groupExprOps[p.peek()] == GroupIntersect {
+		p.pos++
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &GroupExpr{Op: GroupIntersect, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+// operand parses a group name or a parenthesized expression.
+func (p *groupExprParser) operand() (*GroupExpr, error) {
+	if p.pos == len(p.tokens) {
+		return nil, fmt.Errorf("group expression ends with an operator")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	switch tok.op {
+	case 0:
+		if len(tok.text) == 0 {
+			return nil, fmt.Errorf("empty group name in group expression")
+		}
+		return &GroupExpr{Group: tok.text}, nil
+	case '(':
+		e, err := p.union()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ) in group expression")
+		}
+		p.pos++
+		return e, nil
+	}
+	return nil, fmt.Errorf("unexpected %q in group expression", tok.text)
+}
+
+// String writes the expression back out with ∪, ∩ and −, quoting names and
+// adding parentheses only where needed.
+func (e *GroupExpr) String() string {
+	if e.Op == 0 {
+		// quote a name that wouldn't read back as itself
+		tokens, err := tokenizeGroupExpr(e.Group)
+		if err == nil && len(tokens) == 1 && tokens[0].op == 0 && tokens[0].text == e.Group {
+			return e.Group
+		}
+		return `"` + e.Group + `"`
+	}
+	left, right := e.Left.String(), e.Right.String()
+	if e.Op == GroupIntersect && e.Left.Op != 0 && e.Left.Op != GroupIntersect {
+		left = "(" + left + ")"
+	}
+	if e.Right.Op != 0 && (e.Op == GroupIntersect || e.Right.Op != GroupIntersect) {
+		right = "(" + right + ")"
+	}
+	return left + " " + string(e.Op) + " " + right
+}
+
+// resolveGroup returns the resource name of the group called name: an exact
+// match on its name, or failing that on its formatted name ignoring case.
+func resolveGroup(src ContactSource, groups []ContactGroup, name string) (string, error) {
+	if name == AllContactsGroup {
+		if all, ok := src.(AllContactsSource); ok && len(all.AllContactsGroup()) > 0 {
+			return all.AllContactsGroup(), nil
+		}
+		return "", fmt.Errorf("the address book has no group of all contacts")
+	}
+	for _, g := range groups {
+		if g.Name == name {
+			return g.ResourceName, nil
+		}
+	}
+	for _, g := range groups {
+		if strings.EqualFold(g.FormattedName, name) || strings.EqualFold(g.Name, name) {
+			return g.ResourceName, nil
+		}
+	}
+	return "", fmt.Errorf("no contact group is called %q", name)
+}
+
+// Members returns the resource names of the contacts the expression selects,
+// along with the resource names of the groups it refers to.
+func (e *GroupExpr) Members(ctx context.Context, src ContactSource) ([]string, []string, error) {
+	groups, err := src.ContactGroups(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to retrieve contactGroups: %w", err)
+	}
+	var used []string
+	members, err := e.members(ctx, src, groups, &used)
+	return members, used, err
+}
+
+func (e *GroupExpr) members(ctx context.Context, src ContactSource, groups []ContactGroup, used *[]string) ([]string, error) {
+	if e.Op == 0 {
+		rn, err := resolveGroup(src, groups, e.Group)
+		if err != nil {
+			return nil, err
+		}
+		*used = append(*used, rn)
+		members, err := src.GroupMembers(ctx, rn)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve contactGroup members: %w", err)
+		}
+		return members, nil
+	}
+
+	left, err := e.Left.members(ctx, src, groups, used)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.Right.members(ctx, src, groups, used)
+	if err != nil {
+		return nil, err
+	}
+	inRight := make(map[string]bool, len(right))
+	for _, rn := range right {
+		inRight[rn] = true
+	}
+
+	var out []string
+	seen := map[string]bool{}
+	add := func(rn string) {
+		if !seen[rn] {
+			seen[rn] = true
+			out = append(out, rn)
+		}
+	}
+	for _, rn := range left {
+		if e.Op == GroupUnion || (e.Op == GroupIntersect) == inRight[rn] {
+			add(rn)
+		}
+	}
+	if e.Op == GroupUnion {
+		for _, rn := range right {
+			add(rn)
+		}
+	}
+	return out, nil
+}
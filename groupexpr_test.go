@@ -0,0 +1,128 @@
+package cohabitaters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseGroupExpr(t *testing.T) {
+	tests := []struct {
+		In   string
+		Want string
+	}{
+		{"Xmas Card", "Xmas Card"},
+		{"Family ∪ Friends − Already-sent-2025", "Family ∪ Friends − Already-sent-2025"},
+		{"Family + Friends - Already-sent-2025", "Family ∪ Friends − Already-sent-2025"},
+		{"Family − (Friends ∪ Work)", "Family − (Friends ∪ Work)"},
+		{"Family ∪ Friends ∩ Local", "Family ∪ Friends ∩ Local"},
+		{"(Family ∪ Friends) & Local", "(Family ∪ Friends) ∩ Local"},
+		{"Local ∩ (Family ∩ Friends)", "Local ∩ (Family ∩ Friends)"},
+		{`"Ham & Eggs" ∪ *`, `"Ham & Eggs" ∪ *`},
+		{`"Xmas Card"`, "Xmas Card"},
+		{"*", "*"},
+	}
+	for _, test := range tests {
+		t.Run(test.In, func(t *testing.T) {
+			e, err := ParseGroupExpr(test.In)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := e.String(); got != test.Want {
+				t.Errorf("incorrect expression, got: %v, want: %v", got, test.Want)
+			}
+			again, err := ParseGroupExpr(e.String())
+			if err != nil {
+				t.Fatalf("unexpected error reparsing: %v", err)
+			}
+			if diff := cmp.Diff(e, again); diff != "" {
+				t.Errorf("reparsed expression mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseGroupExprErrors(t *testing.T) {
+	for _, in := range []string{"", "  ", "Family ∪", "∪ Family", "(Family", "Family)", `"Family`, "Family ∪ ()"} {
+		if _, err := ParseGroupExpr(in); err == nil {
+			t.Errorf("ParseGroupExpr(%q) expected an error", in)
+		}
+	}
+}
+
+type allContactsFake struct {
+	fakeSource
+}
+
+func (allContactsFake) AllContactsGroup() string { return "everyone" }
+
+func TestGroupExprMembers(t *testing.T) {
+	ctx := context.Background()
+	src := allContactsFake{fakeSource{
+		groups: map[string][]string{
+			"everyone": {"people/1", "people/2", "people/3", "people/4"},
+			"Family":   {"people/1", "people/2"},
+			"Friends":  {"people/2", "people/3"},
+			"Sent":     {"people/1"},
+		},
+		people: map[string]Contact{
+			"people/1": homeContact("people/1", "Homer Simpson", mainStreet),
+			"people/2": homeContact("people/2", "Marge Simpson", mainStreet),
+			"people/3": homeContact("people/3", "Ned Flanders", elmStreet),
+		},
+	}}
+
+	tests := []struct {
+		Expr       string
+		Want       []string
+		WantGroups []string
+	}{
+		{"Family ∪ Friends − Sent", []string{"people/2", "people/3"}, []string{"Family", "Friends", "Sent"}},
+		{"family ∩ friends", []string{"people/2"}, []string{"Family", "Friends"}},
+		{"* − Family", []string{"people/3", "people/4"}, []string{"everyone", "Family"}},
+	}
+	for _, test := range tests {
+		t.Run(test.Expr, func(t *testing.T) {
+			e, err := ParseGroupExpr(test.Expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, groups, err := e.Members(ctx, src)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.Want, got); diff != "" {
+				t.Errorf("Members() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.WantGroups, groups); diff != "" {
+				t.Errorf("Members() groups mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("unknown group", func(t *testing.T) {
+		e, _ := ParseGroupExpr("Family ∪ Neighbors")
+		if _, _, err := e.Members(ctx, src); err == nil {
+			t.Errorf("missing expected error")
+		}
+	})
+
+	t.Run("no group of all contacts", func(t *testing.T) {
+		e, _ := ParseGroupExpr("*")
+		if _, _, err := e.Members(ctx, src.fakeSource); err == nil {
+			t.Errorf("missing expected error")
+		}
+	})
+
+	t.Run("coalesce", func(t *testing.T) {
+		e, _ := ParseGroupExpr("Family ∪ Friends")
+		res, err := GetXmasCardsForExpr(ctx, src, e, Options{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(res.Cards) != 2 {
+			t.Errorf("unexpected card count, got: %v, want: 2", len(res.Cards))
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/bfallik/cohabitaters/cohabdb"
+	"github.com/bfallik/cohabitaters/gpeople"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/people/v1"
+)
+
+// primaryAccount names the Google account the user logged in with among the
+// accounts they linked.
+const primaryAccount = "your Google account"
+
+// accountNames names the user's own Google account followed by the accounts
+// they linked, as cohabitaters.MergedSource knows them.
+func accountNames(linked []linkedSource) []string {
+	names := []string{primaryAccount}
+	for _, ls := range linked {
+		names = append(names, ls.email)
+	}
+	return names
+}
+
+// linkedToken returns the token saved for a linked account.
+func linkedToken(la cohabdb.LinkedAccount) (*oauth2.Token, error) {
+	var tok oauth2.Token
+	if err := json.Unmarshal([]byte(la.Token), &tok); err != nil {
+		return nil, fmt.Errorf("invalid token for %s: %w", la.Email, err)
+	}
+	return &tok, nil
+}
+
+// linkedSource reads the contacts of an account the user linked. Its contact
+// groups are listed once per request, when it's read, and serve both the
+// group picker and coalescing.
+type linkedSource struct {
+	*gpeople.Source
+	email  string
+	groups []*people.ContactGroup // including those Google maintains
+}
+
+func (ls linkedSource) ContactGroups(ctx context.Context) ([]cohabitaters.ContactGroup, error) {
+	groups := make([]cohabitaters.ContactGroup, len(ls.groups))
+	for i, cg := range ls.groups {
+		groups[i] = gpeople.NewContactGroup(cg)
+	}
+	return groups, nil
+}
+
+// linkedSources reads each linked account as it is now. An account that
+// can't be read, e.g. because access to it was revoked, is logged and left
+// out, so the user can still unlink it and coalesce the rest.
+func (w WebUI) linkedSources(ctx context.Context, linked []cohabdb.LinkedAccount) []linkedSource {
+	var out []linkedSource
+	for _, la := range linked {
+		ls, err := w.readLinkedAccount(ctx, la)
+		if err != nil {
+			log.Printf("skipping linked account %s: %v", la.Email, err)
+			continue
+		}
+		out = append(out, ls)
+	}
+	return out
+}
+
+func (w WebUI) readLinkedAccount(ctx context.Context, la cohabdb.LinkedAccount) (linkedSource, error) {
+	tok, err := linkedToken(la)
+	if err != nil {
+		return linkedSource{}, err
+	}
+	srv, err := w.google(ctx, tok).service(ctx)
+	if err != nil {
+		return linkedSource{}, err
+	}
+	resp, err := listContactGroups(ctx, srv)
+	if err != nil {
+		return linkedSource{}, err
+	}
+	return linkedSource{Source: gpeople.New(srv), email: la.Email, groups: resp.ContactGroups}, nil
+}
+
+// mergeLinkedGroups combines the contact groups of the user's own account
+// with those of each linked account, giving groups of the same name a single
+// entry.
+func mergeLinkedGroups(own []*people.ContactGroup, linked []linkedSource) []*people.ContactGroup {
+	convert := func(cgs []*people.ContactGroup) []cohabitaters.ContactGroup {
+		var gs []cohabitaters.ContactGroup
+		for _, cg := range cgs {
+			gs = append(gs, gpeople.NewContactGroup(cg))
+		}
+		return gs
+	}
+	groups := [][]cohabitaters.ContactGroup{convert(own)}
+	for _, ls := range linked {
+		groups = append(groups, convert(userContactGroups(ls.groups)))
+	}
+
+	var out []*people.ContactGroup
+	for _, g := range cohabitaters.MergeContactGroups(accountNames(linked), groups) {
+		out = append(out, &people.ContactGroup{
+			ResourceName:  g.ResourceName,
+			Name:          g.Name,
+			FormattedName: g.FormattedName,
+			MemberCount:   int64(g.MemberCount),
+		})
+	}
+	return out
+}
+
+// contactSource reads the user's Google contacts: those of the account they
+// logged in with along with those of every linked account that could be
+// read.
+func (w WebUI) contactSource(ctx context.Context, token *oauth2.Token, linked []linkedSource) (cohabitaters.ContactSource, error) {
+	own, err := w.google(ctx, token).source(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(linked) == 0 {
+		return own, nil
+	}
+
+	accounts := []cohabitaters.Account{{Name: primaryAccount, Source: own}}
+	for _, ls := range linked {
+		accounts = append(accounts, cohabitaters.Account{Name: ls.email, Source: ls})
+	}
+	return cohabitaters.NewMergedSource(accounts...), nil
+}
+
+// UnlinkAccount forgets a linked Google account and its token.
+func (w WebUI) UnlinkAccount(c echo.Context) error {
+	email := c.FormValue("account-email")
+	if len(email) == 0 {
+		c.Logger().Error("missing expected account-email")
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	return w.updateAndRenderGroups(c, func(ctx context.Context, sessionID int, userID int64) error {
+		return w.Queries.DeleteLinkedAccount(ctx, cohabdb.DeleteLinkedAccountParams{UserID: userID, Email: email})
+	}, nil)
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bfallik/cohabitaters/cohabdb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+	"google.golang.org/api/people/v1"
+)
+
+func Test_mergeLinkedGroups(t *testing.T) {
+	own := []*people.ContactGroup{
+		{ResourceName: "contactGroups/1", Name: "Xmas Card", FormattedName: "Xmas Card", MemberCount: 2},
+	}
+	linked := []linkedSource{
+		{email: "marge@example.com", groups: []*people.ContactGroup{
+			{ResourceName: "contactGroups/9", Name: "Xmas Card", FormattedName: "Xmas Card", GroupType: "USER_CONTACT_GROUP", MemberCount: 3},
+			{ResourceName: "contactGroups/7", Name: "Church", FormattedName: "Church", GroupType: "USER_CONTACT_GROUP", MemberCount: 1},
+			{ResourceName: "contactGroups/myContacts", Name: "myContacts", GroupType: "SYSTEM_CONTACT_GROUP", MemberCount: 40},
+		}},
+		{email: "bart@example.com"},
+	}
+
+	got := mergeLinkedGroups(own, linked)
+	want := []*people.ContactGroup{
+		{ResourceName: "contactGroups/1", Name: "Xmas Card", FormattedName: "Xmas Card", MemberCount: 5},
+		{ResourceName: "accounts/marge@example.com/contactGroups/7", Name: "Church", FormattedName: "Church", MemberCount: 1},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mergeLinkedGroups() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// fakePeopleAPI serves the parts of the People API the handlers read,
+// counting the contact group listings of each access token. The access token
+// "revoked" is refused everywhere.
+func fakePeopleAPI(listings map[string]int) *httptest.Server {
+	var mu sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("Authorization") == "Bearer revoked" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": {"code": 401, "message": "token revoked"}}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/contactGroups":
+			mu.Lock()
+			listings[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]++
+			mu.Unlock()
+			w.Write([]byte(`{"contactGroups": [{"resourceName": "contactGroups/xmas", "name": "Xmas Card", "groupType": "USER_CONTACT_GROUP", "memberCount": 1}]}`))
+		case "/v1/contactGroups/xmas":
+			w.Write([]byte(`{"resourceName": "contactGroups/xmas", "name": "Xmas Card", "memberCount": 1, "memberResourceNames": ["people/1"]}`))
+		case "/v1/people:batchGet":
+			w.Write([]byte(`{"responses": [{"requestedResourceName": "people/1", "person": {"resourceName": "people/1", "names": [{"displayName": "Homer Simpson"}], "addresses": [{"type": "home", "streetAddress": "742 Evergreen Terrace", "city": "Springfield"}]}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// linkedQuerier has a logged in user who selected their Xmas Card group and
+// linked two accounts, one they since revoked access to.
+type linkedQuerier struct {
+	mockQuerier
+}
+
+func (lq linkedQuerier) GetSession(ctx context.Context, id int64) (cohabdb.Session, error) {
+	return cohabdb.Session{
+		ID:                   id,
+		CreatedAt:            time.Now().Unix(),
+		IsLoggedIn:           true,
+		ContactGroupsJson:    sql.NullString{Valid: true, String: `[{"resourceName": "contactGroups/xmas", "name": "Xmas Card", "memberCount": 1}]`},
+		SelectedResourceName: sql.NullString{Valid: true, String: "contactGroups/xmas"},
+	}, nil
+}
+
+func (lq linkedQuerier) GetToken(ctx context.Context, id int64) (sql.NullString, error) {
+	return sql.NullString{Valid: true, String: `{"access_token": "homer"}`}, nil
+}
+
+func (lq linkedQuerier) ListLinkedAccounts(ctx context.Context, userID int64) ([]cohabdb.LinkedAccount, error) {
+	return []cohabdb.LinkedAccount{
+		{UserID: userID, Email: "marge@example.com", Token: `{"access_token": "revoked"}`},
+		{UserID: userID, Email: "bart@example.com", Token: `{"access_token": "bart"}`},
+	}, nil
+}
+
+func TestRootUnreadableLinkedAccount(t *testing.T) {
+	listings := map[string]int{}
+	api := fakePeopleAPI(listings)
+	defer api.Close()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("_session_store", sessions.NewCookieStore([]byte{}))
+
+	h := &WebUI{
+		OauthConfig:   &oauth2.Config{},
+		Queries:       linkedQuerier{},
+		peopleOptions: []option.ClientOption{option.WithEndpoint(api.URL + "/")},
+	}
+	if err := h.Root(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("unexpected status, got: %v, want: %v", rec.Code, http.StatusOK)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"Homer Simpson", "marge@example.com", "bart@example.com"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in the page", want)
+		}
+	}
+	if diff := cmp.Diff(map[string]int{"homer": 1, "bart": 1}, listings); diff != "" {
+		t.Errorf("contact group listings mismatch (-want +got):\n%s", diff)
+	}
+}
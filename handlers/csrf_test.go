@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestCSRF(t *testing.T) {
+	e := echo.New()
+	var token string
+	e.GET("/", func(c echo.Context) error {
+		token = csrfToken(c)
+		return c.NoContent(http.StatusOK)
+	}, CSRF())
+	e.POST("/change", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, CSRF())
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := rec.Result().Cookies()
+	if len(token) == 0 || len(cookies) != 1 {
+		t.Fatalf("expected a token and its cookie, got: %q, %v", token, cookies)
+	}
+
+	tests := []struct {
+		Desc   string
+		Header string
+		Want   int
+	}{
+		{Desc: "missing token", Want: http.StatusBadRequest},
+		{Desc: "wrong token", Header: "forged", Want: http.StatusForbidden},
+		{Desc: "token", Header: token, Want: http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/change", nil)
+			req.AddCookie(cookies[0])
+			if len(test.Header) > 0 {
+				req.Header.Set(echo.HeaderXCSRFToken, test.Header)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != test.Want {
+				t.Errorf("unexpected status, got: %v, want: %v", rec.Code, test.Want)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/bfallik/cohabitaters/addressbook"
+	"github.com/bfallik/cohabitaters/envelopes"
+	"github.com/bfallik/cohabitaters/export"
+	"github.com/bfallik/cohabitaters/html"
+	"github.com/bfallik/cohabitaters/labels"
+	"github.com/bfallik/cohabitaters/pdf"
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+)
+
+// exportResults coalesces the contact group named by the contact-group query
+// parameter for the logged in user, recording the user's preferences and
+// contact groups in out.
+func (w WebUI) exportResults(c echo.Context, out *html.TmplIndexData) (cohabitaters.Result, error) {
+	s, err := session.Get(sessionName, c)
+	if err != nil {
+		c.Logger().Infof("error getting previous session: %w", err)
+	}
+	sessionID := sessionID(s)
+
+	ctx := c.Request().Context()
+	isLoggedIn, err := w.isUserLoggedIn(ctx, sessionID)
+	if err != nil {
+		return cohabitaters.Result{}, err
+	}
+	if !isLoggedIn {
+		return cohabitaters.Result{}, echo.NewHTTPError(http.StatusUnauthorized)
+	}
+
+	contactGroup := c.QueryParam("contact-group")
+	if len(contactGroup) == 0 {
+		return cohabitaters.Result{}, echo.NewHTTPError(http.StatusBadRequest, "missing contact-group")
+	}
+
+	token, err := w.getGoogleToken(ctx, sessionID)
+	if err != nil {
+		return cohabitaters.Result{}, err
+	}
+	if !token.Valid() && !addressbook.IsResourceName(contactGroup) && !strings.HasPrefix(contactGroup, groupExprPrefix) {
+		return cohabitaters.Result{}, echo.NewHTTPError(http.StatusUnauthorized)
+	}
+
+	session, err := w.Queries.GetSession(ctx, int64(sessionID))
+	if err != nil {
+		return cohabitaters.Result{}, err
+	}
+	linked, err := w.allContactGroups(ctx, session, out)
+	if err != nil {
+		return cohabitaters.Result{}, err
+	}
+	out.SelectedResourceName = contactGroup
+
+	res, err := w.coalesce(ctx, sessionID, token, linked, contactGroup, out)
+	if errors.Is(err, cohabitaters.ErrEmptyGroup) {
+		return cohabitaters.Result{}, nil
+	}
+	return res, err
+}
+
+func setAttachment(c echo.Context, contentType, filename string) {
+	c.Response().Header().Set(echo.HeaderContentType, contentType)
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+}
+
+// ExportCSV downloads one CSV row per card. The bom query parameter adds a
+// byte order mark for Excel.
+func (w WebUI) ExportCSV(c echo.Context) error {
+	res, err := w.exportResults(c, &html.TmplIndexData{})
+	if err != nil {
+		return err
+	}
+
+	var opts export.CSVOptions
+	opts.BOM, _ = strconv.ParseBool(c.QueryParam("bom"))
+
+	setAttachment(c, "text/csv; charset=utf-8", "xmas-cards.csv")
+	c.Response().WriteHeader(http.StatusOK)
+	return export.WriteCSV(c.Response(), res.Cards, opts)
+}
+
+// ExportVCard downloads a vCard 4.0 file with a group card for each
+// household and a card for each of its members.
+func (w WebUI) ExportVCard(c echo.Context) error {
+	res, err := w.exportResults(c, &html.TmplIndexData{})
+	if err != nil {
+		return err
+	}
+
+	setAttachment(c, "text/vcard; charset=utf-8", "xmas-cards.vcf")
+	c.Response().WriteHeader(http.StatusOK)
+	return export.WriteVCard(c.Response(), res.Cards)
+}
+
+// ExportXLSX downloads an Excel workbook with sheets for the households, the
+// skipped contacts and the contact behind each household.
+func (w WebUI) ExportXLSX(c echo.Context) error {
+	res, err := w.exportResults(c, &html.TmplIndexData{})
+	if err != nil {
+		return err
+	}
+
+	setAttachment(c, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xmas-cards.xlsx")
+	c.Response().WriteHeader(http.StatusOK)
+	return export.WriteXLSX(c.Response(), res)
+}
+
+// ExportLabels downloads a PDF of mailing labels. The sheet query parameter
+// names the label stock, or "layout:" and the name of one of the user's
+// saved layouts; font picks the typeface of a built-in sheet and skip counts
+// labels already used on the first sheet.
+func (w WebUI) ExportLabels(c echo.Context) error {
+	var opts labels.Options
+	var err error
+	layoutName, isLayout := strings.CutPrefix(c.QueryParam("sheet"), layoutSheetPrefix)
+	if !isLayout {
+		if opts.Sheet, err = labels.LookupSheet(c.QueryParam("sheet")); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+	if opts.Font, err = pdf.ParseFont(c.QueryParam("font")); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if skip := c.QueryParam("skip"); len(skip) > 0 {
+		// a layout's sheet size is only known once it is loaded, below
+		if opts.Skip, err = strconv.Atoi(skip); err != nil || opts.Skip < 0 || (!isLayout && opts.Skip >= opts.Sheet.PerPage()) {
+			return echo.NewHTTPError(http.StatusBadRequest, labels.ErrSkip.Error())
+		}
+	}
+
+	var prefs html.TmplIndexData
+	res, err := w.exportResults(c, &prefs)
+	if err != nil {
+		return err
+	}
+
+	sheet := opts.Sheet
+	if isLayout {
+		if opts.Layout, err = findLabelLayout(prefs.LabelLayouts, layoutName); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		sheet = opts.Layout.Sheet()
+	}
+	if opts.Skip >= sheet.PerPage() {
+		return echo.NewHTTPError(http.StatusBadRequest, labels.ErrSkip.Error())
+	}
+
+	setAttachment(c, "application/pdf", "xmas-labels-"+fileSafe(sheet.Name)+".pdf")
+	c.Response().WriteHeader(http.StatusOK)
+	return labels.Write(c.Response(), res.Cards, opts)
+}
+
+// fileSafe makes name usable in a download's filename.
+func fileSafe(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, name)
+}
+
+// ExportEnvelopes downloads a PDF with one envelope per card. The envelope
+// query parameter names the size and font picks the typeface. The return
+// address is the one the user saved, or else the one in their Google profile.
+func (w WebUI) ExportEnvelopes(c echo.Context) error {
+	var opts envelopes.Options
+	var err error
+	if opts.Envelope, err = envelopes.LookupEnvelope(c.QueryParam("envelope")); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if opts.Font, err = pdf.ParseFont(c.QueryParam("font")); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	var prefs html.TmplIndexData
+	res, err := w.exportResults(c, &prefs)
+	if err != nil {
+		return err
+	}
+
+	opts.SenderCountry = prefs.SenderCountry
+	if lines := envelopes.ParseLines(prefs.ReturnAddress); len(lines) > 0 {
+		opts.ReturnAddress.Lines = lines
+	} else {
+		opts.ReturnAddress = w.profileReturnAddress(c, prefs.AddressTypeOrder)
+	}
+
+	setAttachment(c, "application/pdf", "xmas-envelopes-"+opts.Envelope.Name+".pdf")
+	c.Response().WriteHeader(http.StatusOK)
+	return envelopes.Write(c.Response(), res.Cards, opts)
+}
+
+// profileReturnAddress reads a return address from the logged in user's
+// Google profile. Users who logged in before the app asked to read their
+// addresses get no return address rather than an error.
+func (w WebUI) profileReturnAddress(c echo.Context, typeOrder string) envelopes.ReturnAddress {
+	s, err := session.Get(sessionName, c)
+	if err != nil {
+		c.Logger().Infof("error getting previous session: %w", err)
+	}
+
+	ctx := c.Request().Context()
+	token, err := w.getGoogleToken(ctx, sessionID(s))
+	if err != nil {
+		c.Logger().Warnf("unable to get token for return address: %v", err)
+		return envelopes.ReturnAddress{}
+	}
+
+	googs := w.google(ctx, token)
+	me, err := googs.getMe(ctx)
+	if err != nil {
+		c.Logger().Warnf("unable to read return address from profile: %v", err)
+		return envelopes.ReturnAddress{}
+	}
+	return envelopes.FromContact(me, cohabitaters.SelectionPolicy{TypeOrder: cohabitaters.ParseTypeOrder(typeOrder)})
+}
+
+// PrintCards renders the cards as a checklist to print from the browser.
+func (w WebUI) PrintCards(c echo.Context) error {
+	var data html.TmplIndexData
+	res, err := w.exportResults(c, &data)
+	if err != nil {
+		return err
+	}
+
+	input := html.TmplPrintData{
+		GroupName:     data.SelectedResourceName,
+		Date:          time.Now(),
+		Cards:         res.Cards,
+		Skipped:       res.Skipped,
+		SortOrder:     data.SortOrder,
+		SenderCountry: data.SenderCountry,
+		Subtotals:     res.Subtotals,
+	}
+	if idx := contactGroupIndex(data.Groups, data.SelectedResourceName); idx >= 0 {
+		input.GroupName = data.Groups[idx].FormattedName
+		input.CountContacts = int(data.Groups[idx].MemberCount)
+	} else if text, ok := strings.CutPrefix(data.SelectedResourceName, groupExprPrefix); ok {
+		input.GroupName = text
+		input.CountContacts = resultContacts(res)
+	}
+	return renderComponentHTML(c, html.ComponentPagePrint(input))
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo/v4"
+)
+
+func TestExportRequiresLogin(t *testing.T) {
+	h := &WebUI{Queries: mockQuerier{}}
+
+	tests := []struct {
+		Name    string
+		Handler echo.HandlerFunc
+		URL     string
+	}{
+		{"csv", h.ExportCSV, "/export/csv"},
+		{"vcf", h.ExportVCard, "/export/vcf"},
+		{"xlsx", h.ExportXLSX, "/export/xlsx"},
+		{"labels", h.ExportLabels, "/export/labels"},
+		{"envelopes", h.ExportEnvelopes, "/export/envelopes"},
+		{"print", h.PrintCards, "/print"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, test.URL+"?contact-group=contactGroups/xmas", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set("_session_store", sessions.NewCookieStore([]byte{}))
+
+			var httpErr *echo.HTTPError
+			if err := test.Handler(c); !errors.As(err, &httpErr) || httpErr.Code != http.StatusUnauthorized {
+				t.Errorf("unexpected error, got: %v, want: %v", err, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestExportBadRequest(t *testing.T) {
+	h := &WebUI{Queries: mockQuerier{}}
+
+	tests := []struct {
+		Name    string
+		Handler echo.HandlerFunc
+		URL     string
+	}{
+		{"unknown sheet", h.ExportLabels, "/export/labels?sheet=9999"},
+		{"unknown label font", h.ExportLabels, "/export/labels?font=Comic+Sans"},
+		{"skip too large", h.ExportLabels, "/export/labels?sheet=5163&skip=10"},
+		{"skip not a number", h.ExportLabels, "/export/labels?skip=two"},
+		{"unknown envelope", h.ExportEnvelopes, "/export/envelopes?envelope=C5"},
+		{"unknown envelope font", h.ExportEnvelopes, "/export/envelopes?font=Comic+Sans"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, test.URL+"&contact-group=contactGroups/xmas", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set("_session_store", sessions.NewCookieStore([]byte{}))
+
+			var httpErr *echo.HTTPError
+			if err := test.Handler(c); !errors.As(err, &httpErr) || httpErr.Code != http.StatusBadRequest {
+				t.Errorf("unexpected error, got: %v, want: %v", err, http.StatusBadRequest)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/bfallik/cohabitaters/cohabdb"
+	"github.com/bfallik/cohabitaters/html"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/oauth2"
+)
+
+// groupExprPrefix marks a selected resource name that is a
+// cohabitaters.GroupExpr over the user's contact groups rather than a single
+// group.
+const groupExprPrefix = "expr:"
+
+// groupExprSource returns the contacts a group expression is evaluated over:
+// the user's Google contacts along with their imported contacts, so an
+// expression may name the groups of either. Groups of the same name in both
+// are one group. Only the imported contacts are read when the user hasn't
+// given access to Google.
+func (w WebUI) groupExprSource(ctx context.Context, userID int64, token *oauth2.Token, linked []linkedSource) (cohabitaters.ContactSource, error) {
+	book, filename, err := w.importedBook(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid() {
+		if book == nil {
+			return nil, errors.New("give access to Google Contacts or import contacts to combine groups")
+		}
+		return book, nil
+	}
+
+	google, err := w.contactSource(ctx, token, linked)
+	if err != nil {
+		return nil, err
+	}
+	if book == nil {
+		return google, nil
+	}
+	return cohabitaters.NewMergedSource(
+		cohabitaters.Account{Name: primaryAccount, Source: google},
+		cohabitaters.Account{Name: filename, Source: book},
+	), nil
+}
+
+// resultContacts counts the contacts coalesced into res.
+func resultContacts(res cohabitaters.Result) int {
+	n := len(res.Skipped)
+	for _, card := range res.Cards {
+		n += len(card.Contacts)
+	}
+	return n
+}
+
+// GroupExpression selects the contacts of a set expression over the user's
+// contact groups, like "Family ∪ Friends − Sent 2025", in place of a single
+// group, saves it with the user's preferences and re-renders the results. An
+// expression that doesn't parse is reported next to the form.
+func (w WebUI) GroupExpression(c echo.Context) error {
+	text := strings.TrimSpace(c.FormValue("group-expression"))
+	expr, err := cohabitaters.ParseGroupExpr(text)
+	var problem string
+	if err != nil {
+		problem = err.Error()
+	}
+
+	return w.updateAndRenderGroups(c, func(ctx context.Context, sessionID int, userID int64) error {
+		if len(problem) > 0 {
+			return nil
+		}
+		if err := w.Queries.UpsertUserPreference(ctx, cohabdb.UpsertUserPreferenceParams{
+			UserID: userID,
+			Name:   prefGroupExpression,
+			Value:  expr.String(),
+		}); err != nil {
+			return err
+		}
+		return w.Queries.UpdateSelectedResourceName(ctx, cohabdb.UpdateSelectedResourceNameParams{
+			ID:                   int64(sessionID),
+			SelectedResourceName: sql.NullString{Valid: true, String: groupExprPrefix + expr.String()},
+		})
+	}, func(out *html.TmplIndexData) {
+		if len(problem) > 0 {
+			out.GroupExpression = text
+			out.GroupExpressionError = problem
+		}
+	})
+}
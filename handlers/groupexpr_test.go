@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bfallik/cohabitaters/cohabdb"
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+)
+
+// exprQuerier has a logged in user with both Google and imported contacts,
+// whose session selected nothing yet.
+type exprQuerier struct {
+	mockQuerier
+	expression string // the saved group expression
+}
+
+func (eq exprQuerier) GetSession(ctx context.Context, id int64) (cohabdb.Session, error) {
+	return cohabdb.Session{
+		ID:                id,
+		CreatedAt:         time.Now().Unix(),
+		IsLoggedIn:        true,
+		ContactGroupsJson: sql.NullString{Valid: true, String: `[{"resourceName": "contactGroups/xmas", "name": "Xmas Card", "memberCount": 1}]`},
+	}, nil
+}
+
+func (eq exprQuerier) GetToken(ctx context.Context, id int64) (sql.NullString, error) {
+	return sql.NullString{Valid: true, String: `{"access_token": "homer"}`}, nil
+}
+
+func (eq exprQuerier) GetContactImport(ctx context.Context, userID int64) (cohabdb.ContactImport, error) {
+	return cohabdb.ContactImport{UserID: userID, Filename: "neighbors.vcf", Contents: "BEGIN:VCARD\r\n" +
+		"VERSION:3.0\r\n" +
+		"FN:Ned Flanders\r\n" +
+		"N:Flanders;Ned;;;\r\n" +
+		"ADR;TYPE=HOME:;;744 Evergreen Terrace;Springfield;IL;62701;USA\r\n" +
+		"CATEGORIES:Neighbors\r\n" +
+		"END:VCARD\r\n"}, nil
+}
+
+func (eq exprQuerier) GetUserPreference(ctx context.Context, arg cohabdb.GetUserPreferenceParams) (string, error) {
+	if arg.Name == prefGroupExpression && len(eq.expression) > 0 {
+		return eq.expression, nil
+	}
+	return "", sql.ErrNoRows
+}
+
+func TestRootGroupExpression(t *testing.T) {
+	api := fakePeopleAPI(map[string]int{})
+	defer api.Close()
+
+	tests := []struct {
+		Desc       string
+		Expression string
+		Want       []string
+		Missing    []string
+	}{
+		{
+			Desc:       "groups of Google and imported contacts",
+			Expression: "Xmas Card ∪ Neighbors",
+			Want:       []string{"Homer Simpson", "Ned Flanders"},
+		},
+		{
+			Desc:       "imported group only",
+			Expression: "Neighbors",
+			Want:       []string{"Ned Flanders"},
+			Missing:    []string{"Homer Simpson"},
+		},
+		{
+			Desc:    "nothing saved",
+			Missing: []string{"Homer Simpson", "Ned Flanders"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Desc, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set("_session_store", sessions.NewCookieStore([]byte{}))
+
+			h := &WebUI{
+				OauthConfig:   &oauth2.Config{},
+				Queries:       exprQuerier{expression: tt.Expression},
+				peopleOptions: []option.ClientOption{option.WithEndpoint(api.URL + "/")},
+			}
+			if err := h.Root(c); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			body := rec.Body.String()
+			for _, want := range tt.Want {
+				if !strings.Contains(body, want) {
+					t.Errorf("missing %q in the page", want)
+				}
+			}
+			for _, missing := range tt.Missing {
+				if strings.Contains(body, missing) {
+					t.Errorf("unexpected %q in the page", missing)
+				}
+			}
+			if strings.Contains(body, "no contact group is called") {
+				t.Errorf("unexpected group expression error in the page")
+			}
+		})
+	}
+}
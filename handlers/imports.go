@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/bfallik/cohabitaters/addressbook"
+	"github.com/bfallik/cohabitaters/cohabdb"
+	"github.com/bfallik/cohabitaters/html"
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+	"google.golang.org/api/people/v1"
+)
+
+// maxImportSize caps an uploaded contacts file.
+const maxImportSize = 10 << 20
+
+// importedBook returns the address book the user uploaded and the name of
+// its file, or nil when they haven't uploaded one.
+func (w WebUI) importedBook(ctx context.Context, userID int64) (*addressbook.Book, string, error) {
+	ci, err := w.Queries.GetContactImport(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	book, err := addressbook.Read(ci.Filename, bytes.NewReader([]byte(ci.Contents)))
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read imported contacts %s: %w", ci.Filename, err)
+	}
+	return book, ci.Filename, nil
+}
+
+// allContactGroups records in out the Google contact groups saved on the
+// session, merged with those the user's linked accounts have now, followed by
+// the groups of the user's imported contacts. It returns the linked accounts
+// that could be read, for coalescing their contacts without listing their
+// groups again.
+func (w WebUI) allContactGroups(ctx context.Context, session cohabdb.Session, out *html.TmplIndexData) ([]linkedSource, error) {
+	groups, err := contactGroups(session)
+	if err != nil {
+		return nil, err
+	}
+	linked, err := w.Queries.ListLinkedAccounts(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	sources := w.linkedSources(ctx, linked)
+	if len(sources) > 0 {
+		groups = mergeLinkedGroups(groups, sources)
+	}
+	out.Groups = groups
+	for _, la := range linked {
+		out.LinkedAccounts = append(out.LinkedAccounts, la.Email)
+	}
+
+	book, filename, err := w.importedBook(ctx, session.UserID)
+	if err != nil {
+		// a file that read fine when it was uploaded shouldn't fail now, and
+		// the user can always upload it again
+		log.Printf("skipping imported contacts: %v", err)
+		return sources, nil
+	}
+	if book == nil {
+		return sources, nil
+	}
+	imported, err := book.ContactGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range imported {
+		out.Groups = append(out.Groups, &people.ContactGroup{
+			ResourceName:  g.ResourceName,
+			Name:          g.Name,
+			FormattedName: g.FormattedName,
+			MemberCount:   int64(g.MemberCount),
+		})
+	}
+	out.ImportFilename = filename
+	out.ImportCount = book.Len()
+	return sources, nil
+}
+
+// readContactsUpload returns the name and contents of the uploaded
+// contacts-file.
+func readContactsUpload(c echo.Context) (string, []byte, error) {
+	fh, err := c.FormFile("contacts-file")
+	if err != nil {
+		return "", nil, errors.New("choose a contacts file to upload")
+	}
+	if fh.Size > maxImportSize {
+		return "", nil, fmt.Errorf("the contacts file is %d bytes, more than the %d allowed", fh.Size, maxImportSize)
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxImportSize))
+	return fh.Filename, data, err
+}
+
+// UploadContactImport reads an uploaded vCard or CSV file, saves it in place
+// of any earlier one and selects all of its contacts. A file that can't be
+// read is reported next to the upload form.
+func (w WebUI) UploadContactImport(c echo.Context) error {
+	var problem string
+	filename, data, err := readContactsUpload(c)
+	if err == nil {
+		_, err = addressbook.Read(filename, bytes.NewReader(data))
+	}
+	if err != nil {
+		problem = err.Error()
+	}
+
+	return w.updateAndRenderGroups(c, func(ctx context.Context, sessionID int, userID int64) error {
+		if len(problem) > 0 {
+			return nil
+		}
+		if err := w.Queries.UpsertContactImport(ctx, cohabdb.UpsertContactImportParams{
+			UserID:   userID,
+			Filename: filename,
+			Contents: string(data),
+		}); err != nil {
+			return err
+		}
+		return w.Queries.UpdateSelectedResourceName(ctx, cohabdb.UpdateSelectedResourceNameParams{
+			ID:                   int64(sessionID),
+			SelectedResourceName: sql.NullString{Valid: true, String: addressbook.AllContacts},
+		})
+	}, func(out *html.TmplIndexData) {
+		out.ImportError = problem
+	})
+}
+
+// DeleteContactImport forgets the user's imported contacts.
+func (w WebUI) DeleteContactImport(c echo.Context) error {
+	return w.updateAndRenderGroups(c, func(ctx context.Context, sessionID int, userID int64) error {
+		return w.Queries.DeleteContactImport(ctx, userID)
+	}, nil)
+}
+
+// updateAndRenderGroups applies update for the logged in user and re-renders
+// the contact group picker along with the results for the selected group,
+// since an import changes both.
+func (w WebUI) updateAndRenderGroups(c echo.Context, update func(ctx context.Context, sessionID int, userID int64) error, adjust func(out *html.TmplIndexData)) error {
+	s, err := session.Get(sessionName, c)
+	if err != nil {
+		c.Logger().Infof("error getting previous session: %w", err)
+	}
+	sessionID := sessionID(s)
+
+	ctx := c.Request().Context()
+	isLoggedIn, err := w.isUserLoggedIn(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if !isLoggedIn {
+		c.Logger().Infof("request to import contacts without login session")
+		return c.Render(http.StatusUnauthorized, "error.html", nil)
+	}
+
+	user, err := w.Queries.GetUserBySession(ctx, int64(sessionID))
+	if err != nil {
+		return err
+	}
+	if err := update(ctx, sessionID, user.ID); err != nil {
+		return err
+	}
+
+	tmplData := newTmplIndexData()
+	tmplData.IsLoggedIn = true
+	if err = w.fillTmplIndexData(ctx, sessionID, "", &tmplData); err != nil {
+		return err
+	}
+	if adjust != nil {
+		adjust(&tmplData)
+	}
+
+	return renderComponentHTML(c, html.ComponentGroupsPanel(tmplData))
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/bfallik/cohabitaters/cohabdb"
+	"github.com/bfallik/cohabitaters/html"
+	"github.com/bfallik/cohabitaters/labels"
+	"github.com/labstack/echo/v4"
+)
+
+// maxLayoutSize caps an uploaded label layout; real ones are a few hundred
+// bytes.
+const maxLayoutSize = 64 << 10
+
+// layoutSheetPrefix marks a sheet query parameter that names one of the
+// user's saved layouts rather than a built-in sheet.
+const layoutSheetPrefix = "layout:"
+
+// labelLayouts returns the user's saved label layouts. Layouts that no longer
+// parse, say after the format changed, are logged and left out.
+func (w WebUI) labelLayouts(ctx context.Context, userID int64) ([]labels.Layout, error) {
+	rows, err := w.Queries.ListLabelLayouts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var out []labels.Layout
+	for _, row := range rows {
+		l, err := labels.ParseLayout([]byte(row.Layout))
+		if err != nil {
+			log.Printf("skipping saved label layout %q: %v", row.Name, err)
+			continue
+		}
+		out = append(out, l)
+	}
+	return out, nil
+}
+
+// findLabelLayout returns the layout called name from layouts.
+func findLabelLayout(layouts []labels.Layout, name string) (*labels.Layout, error) {
+	for i := range layouts {
+		if layouts[i].Name == name {
+			return &layouts[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown label layout %q", name)
+}
+
+// readLayoutUpload returns the contents of the uploaded layout-file.
+func readLayoutUpload(c echo.Context) ([]byte, error) {
+	fh, err := c.FormFile("layout-file")
+	if err != nil {
+		return nil, errors.New("choose a layout file to upload")
+	}
+	if fh.Size > maxLayoutSize {
+		return nil, fmt.Errorf("the layout file is %d bytes, more than the %d allowed", fh.Size, maxLayoutSize)
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, maxLayoutSize))
+}
+
+// UploadLabelLayout validates and saves an uploaded label layout, replacing
+// any of the user's layouts with the same name, and re-renders the results
+// for the selected contact group. Problems with the layout are shown next to
+// the upload form.
+func (w WebUI) UploadLabelLayout(c echo.Context) error {
+	var problems []string
+	data, err := readLayoutUpload(c)
+	if err != nil {
+		problems = []string{err.Error()}
+	}
+	var layout labels.Layout
+	if len(problems) == 0 {
+		var verr *labels.ValidationError
+		layout, err = labels.ParseLayout(data)
+		if errors.As(err, &verr) {
+			problems = verr.Problems
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return w.updateAndRenderTableResultsWith(c, func(ctx context.Context, userID int64) error {
+		if len(problems) > 0 {
+			return nil
+		}
+		return w.Queries.UpsertLabelLayout(ctx, cohabdb.UpsertLabelLayoutParams{
+			UserID: userID,
+			Name:   layout.Name,
+			Layout: string(data),
+		})
+	}, func(out *html.TmplIndexData) {
+		out.LayoutErrors = problems
+	})
+}
+
+// DeleteLabelLayout removes one of the user's label layouts and re-renders
+// the results for the selected contact group.
+func (w WebUI) DeleteLabelLayout(c echo.Context) error {
+	name := strings.TrimSpace(c.FormValue("layout-name"))
+	if len(name) == 0 {
+		c.Logger().Errorf("missing layout-name")
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	return w.updateAndRenderTableResults(c, func(ctx context.Context, userID int64) error {
+		return w.Queries.DeleteLabelLayout(ctx, cohabdb.DeleteLabelLayoutParams{UserID: userID, Name: name})
+	})
+}
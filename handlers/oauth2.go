@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bfallik/cohabitaters/cohabdb"
@@ -25,6 +26,10 @@ import (
 	"google.golang.org/api/people/v1"
 )
 
+// linkStatePrefix starts the OAuth state of a request to link another
+// account, telling the callback not to replace the login's own token.
+const linkStatePrefix = "link."
+
 const (
 	oauthCookieName       = "oauthStateCookie"
 	RedirectURLAuthn      = "redirectURLAuthn"
@@ -55,8 +60,65 @@ func getContactGroupsList(ctx context.Context, cfg *oauth2.Config, token *oauth2
 	if err != nil {
 		return nil, fmt.Errorf("unable to create people service %w", err)
 	}
+	return listContactGroups(ctx, srv)
+}
+
+// listContactGroups gathers every page of the account's contact groups into
+// the one response.
+func listContactGroups(ctx context.Context, srv *people.Service) (*people.ListContactGroupsResponse, error) {
+	var out people.ListContactGroupsResponse
+	err := srv.ContactGroups.List().Pages(ctx, func(resp *people.ListContactGroupsResponse) error {
+		out.ContactGroups = append(out.ContactGroups, resp.ContactGroups...)
+		out.TotalItems = resp.TotalItems
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// getUserContactGroups returns the groups the account's owner created, leaving
+// out the ones Google maintains.
+func getUserContactGroups(ctx context.Context, cfg *oauth2.Config, token *oauth2.Token) ([]*people.ContactGroup, error) {
+	groupsResponse, err := getContactGroupsList(ctx, cfg, token)
+	if err != nil {
+		return nil, err
+	}
+	return userContactGroups(groupsResponse.ContactGroups), nil
+}
+
+// userContactGroups leaves out of groups the ones Google maintains.
+func userContactGroups(groups []*people.ContactGroup) []*people.ContactGroup {
+	userGroups := []*people.ContactGroup{}
+	for _, cg := range groups {
+		if cg.GroupType == "USER_CONTACT_GROUP" {
+			userGroups = append(userGroups, cg)
+		}
+	}
+	return userGroups
+}
 
-	return srv.ContactGroups.List().Do()
+// getAccountEmail returns the primary email address of the Google account the
+// token belongs to.
+func getAccountEmail(ctx context.Context, cfg *oauth2.Config, token *oauth2.Token) (string, error) {
+	srv, err := people.NewService(ctx, option.WithTokenSource(cfg.TokenSource(ctx, token)))
+	if err != nil {
+		return "", fmt.Errorf("unable to create people service %w", err)
+	}
+	me, err := srv.People.Get("people/me").PersonFields("emailAddresses").Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+	for _, e := range me.EmailAddresses {
+		if e.Metadata != nil && e.Metadata.Primary {
+			return e.Value, nil
+		}
+	}
+	if len(me.EmailAddresses) == 0 {
+		return "", fmt.Errorf("the Google account has no email address")
+	}
+	return me.EmailAddresses[0].Value, nil
 }
 
 func mustRandInt() int {
@@ -146,6 +208,55 @@ func (o *Oauth2) GoogleForceApproval(c echo.Context) error {
 	return c.JSON(http.StatusOK, struct{ ForceApproval bool }{!session.GoogleForceApproval})
 }
 
+// GoogleLinkAuthz asks Google for access to another account's contacts, to be
+// read along with those of the account the user logged in with. Access is
+// offline since the linked account's token is kept beyond the session.
+func (o *Oauth2) GoogleLinkAuthz(c echo.Context) error {
+	host := c.Request().Host
+
+	oauthState := newStateAuthCookie(host)
+	oauthState.Value = linkStatePrefix + oauthState.Value
+	c.SetCookie(oauthState)
+
+	callback := url.URL{
+		Scheme: c.Request().Header.Get("X-Forwarded-Proto"),
+		Host:   host,
+		Path:   c.Echo().Reverse(RedirectURLAuthz),
+	}
+	if callback.Scheme == "" {
+		callback.Scheme = "http"
+	}
+	o.OauthConfig.RedirectURL = callback.String()
+
+	u := o.OauthConfig.AuthCodeURL(oauthState.Value, oauth2.AccessTypeOffline, oauth2.SetAuthURLParam("prompt", "select_account consent"))
+	return c.Redirect(http.StatusTemporaryRedirect, u)
+}
+
+// linkAccount saves the token of an account linked by the logged in user,
+// replacing the one saved when it was last linked. Its contact groups are
+// listed afresh whenever the page is shown.
+func (o *Oauth2) linkAccount(ctx context.Context, sessionID int, tok *oauth2.Token) error {
+	user, err := o.Queries.GetUserBySession(ctx, int64(sessionID))
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+	email, err := getAccountEmail(ctx, o.OauthConfig, tok)
+	if err != nil {
+		return fmt.Errorf("error getting linked account: %w", err)
+	}
+
+	tokenJSON, err := json.Marshal(tok)
+	if err != nil {
+		return err
+	}
+
+	return o.Queries.UpsertLinkedAccount(ctx, cohabdb.UpsertLinkedAccountParams{
+		UserID: user.ID,
+		Email:  email,
+		Token:  string(tokenJSON),
+	})
+}
+
 func (o *Oauth2) setGoogleToken(ctx context.Context, sessionID int, tok *oauth2.Token) error {
 	bs, err := json.Marshal(tok)
 	if err != nil {
@@ -191,23 +302,23 @@ func (o *Oauth2) GoogleCallbackAuthz(c echo.Context) error {
 		return fmt.Errorf("code exchange error: %w", err)
 	}
 
-	groupsResponse, err := getContactGroupsList(ctx, o.OauthConfig, token)
+	s, err := session.Get("default_session", c)
 	if err != nil {
-		return err
+		return fmt.Errorf("error getting session: %w", err)
 	}
+	sessionID := sessionID(s)
 
-	userGroups := []*people.ContactGroup{}
-	for _, cg := range groupsResponse.ContactGroups {
-		if cg.GroupType == "USER_CONTACT_GROUP" {
-			userGroups = append(userGroups, cg)
+	if strings.HasPrefix(oauthState.Value, linkStatePrefix) {
+		if err := o.linkAccount(ctx, sessionID, token); err != nil {
+			return err
 		}
+		return c.Redirect(http.StatusTemporaryRedirect, "/")
 	}
 
-	s, err := session.Get("default_session", c)
+	userGroups, err := getUserContactGroups(ctx, o.OauthConfig, token)
 	if err != nil {
-		return fmt.Errorf("error getting session: %w", err)
+		return err
 	}
-	sessionID := sessionID(s)
 
 	bs, err := json.Marshal(userGroups)
 	if err != nil {
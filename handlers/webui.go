@@ -9,12 +9,17 @@ import (
 	"net/http"
 	"net/url"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/a-h/templ"
 	"github.com/bfallik/cohabitaters"
+	"github.com/bfallik/cohabitaters/addressbook"
 	"github.com/bfallik/cohabitaters/cohabdb"
+	"github.com/bfallik/cohabitaters/envelopes"
+	"github.com/bfallik/cohabitaters/gpeople"
 	"github.com/bfallik/cohabitaters/html"
+	"github.com/bfallik/cohabitaters/normalize"
 	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 	"golang.org/x/oauth2"
@@ -26,17 +31,64 @@ const sessionName = "default_session"
 const clientID = "1048297799487-pibn8vimfmlii915gn5frkjgorq3oqhn.apps.googleusercontent.com"
 const sessionTimeout = 600 * time.Second
 
+// prefAddressTypeOrder names the user preference holding the comma separated
+// address types to fall back on when a contact has no home or primary address.
+const prefAddressTypeOrder = "address_type_order"
+
+// prefSalutationStyle names the user preference holding the
+// cohabitaters.SalutationStyle used to address cards.
+const prefSalutationStyle = "salutation_style"
+
+// prefSenderCountry names the user preference holding the ISO 3166-1
+// alpha-2 code of the country the user mails cards from.
+const prefSenderCountry = "sender_country"
+
+const defaultSenderCountry = "US"
+
+// prefReturnAddress names the user preference holding the return address
+// printed on envelopes, one line per row. When it is empty the address in
+// the user's own Google profile is used.
+const prefReturnAddress = "return_address"
+
+// prefSortOrder names the user preference holding the
+// cohabitaters.SortOrder of the cards.
+const prefSortOrder = "sort_order"
+
+// prefGroupExpression names the user preference holding the last
+// cohabitaters.GroupExpr the user selected, which is selected again once the
+// session that selected it expires.
+const prefGroupExpression = "group_expression"
+
 type googleSvcs struct {
 	TokenSource oauth2.TokenSource
+	Options     []option.ClientOption
 }
 
-func (gs googleSvcs) getContacts(ctx context.Context, contactGroupResource string) ([]cohabitaters.XmasCard, error) {
-	srv, err := people.NewService(ctx, option.WithTokenSource(gs.TokenSource))
+func (gs googleSvcs) service(ctx context.Context) (*people.Service, error) {
+	opts := append([]option.ClientOption{option.WithTokenSource(gs.TokenSource)}, gs.Options...)
+	srv, err := people.NewService(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create people service %w", err)
 	}
+	return srv, nil
+}
+
+func (gs googleSvcs) source(ctx context.Context) (*gpeople.Source, error) {
+	srv, err := gs.service(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	return cohabitaters.GetXmasCards(srv, contactGroupResource)
+	return gpeople.New(srv), nil
+}
+
+func (gs googleSvcs) getMe(ctx context.Context) (cohabitaters.Contact, error) {
+	src, err := gs.source(ctx)
+	if err != nil {
+		return cohabitaters.Contact{}, err
+	}
+
+	return src.Me(ctx)
 }
 
 func contactGroupIndex(cgs []*people.ContactGroup, target string) int {
@@ -46,6 +98,16 @@ func contactGroupIndex(cgs []*people.ContactGroup, target string) int {
 type WebUI struct {
 	OauthConfig *oauth2.Config
 	Queries     cohabdb.Querier
+	Matcher     cohabitaters.Matcher
+
+	// peopleOptions are passed to every People API service, e.g. to reach a
+	// fake one in tests.
+	peopleOptions []option.ClientOption
+}
+
+// google returns the Google services token gives access to.
+func (w WebUI) google(ctx context.Context, token *oauth2.Token) googleSvcs {
+	return googleSvcs{TokenSource: w.OauthConfig.TokenSource(ctx, token), Options: w.peopleOptions}
 }
 
 func newTmplIndexData() html.TmplIndexData {
@@ -54,6 +116,169 @@ func newTmplIndexData() html.TmplIndexData {
 	}
 }
 
+func (w WebUI) userPreference(ctx context.Context, userID int64, name string) (string, error) {
+	value, err := w.Queries.GetUserPreference(ctx, cohabdb.GetUserPreferenceParams{UserID: userID, Name: name})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return value, err
+}
+
+// userOptions builds the coalescing options from the user's saved
+// preferences and per-contact address overrides, and records the
+// preferences in out so the page can show them.
+func (w WebUI) userOptions(ctx context.Context, userID int64, out *html.TmplIndexData) (cohabitaters.Options, error) {
+	typeOrder, err := w.userPreference(ctx, userID, prefAddressTypeOrder)
+	if err != nil {
+		return cohabitaters.Options{}, err
+	}
+
+	salutation, err := w.userPreference(ctx, userID, prefSalutationStyle)
+	if err != nil {
+		return cohabitaters.Options{}, err
+	}
+	style, err := cohabitaters.ParseSalutationStyle(salutation)
+	if err != nil {
+		style = cohabitaters.SalutationNames
+	}
+
+	senderCountry, err := w.userPreference(ctx, userID, prefSenderCountry)
+	if err != nil {
+		return cohabitaters.Options{}, err
+	}
+	if len(senderCountry) == 0 {
+		senderCountry = defaultSenderCountry
+	}
+
+	returnAddress, err := w.userPreference(ctx, userID, prefReturnAddress)
+	if err != nil {
+		return cohabitaters.Options{}, err
+	}
+
+	layouts, err := w.labelLayouts(ctx, userID)
+	if err != nil {
+		return cohabitaters.Options{}, err
+	}
+
+	sortPref, err := w.userPreference(ctx, userID, prefSortOrder)
+	if err != nil {
+		return cohabitaters.Options{}, err
+	}
+	sortOrder, err := cohabitaters.ParseSortOrder(sortPref)
+	if err != nil {
+		sortOrder = cohabitaters.SortNone
+	}
+
+	overrides, err := w.Queries.ListAddressOverrides(ctx, userID)
+	if err != nil {
+		return cohabitaters.Options{}, err
+	}
+
+	policy := cohabitaters.SelectionPolicy{
+		TypeOrder: cohabitaters.ParseTypeOrder(typeOrder),
+		Overrides: make(map[string]string, len(overrides)),
+	}
+	for _, o := range overrides {
+		policy.Overrides[o.ResourceName] = o.AddressKey
+	}
+
+	households, err := w.householdOverrides(ctx, userID)
+	if err != nil {
+		return cohabitaters.Options{}, err
+	}
+
+	out.AddressTypeOrder = typeOrder
+	out.SalutationStyle = style
+	out.SenderCountry = senderCountry
+	out.ReturnAddress = returnAddress
+	out.SortOrder = sortOrder
+	out.LabelLayouts = layouts
+	return cohabitaters.Options{
+		Matcher:       w.Matcher,
+		Policy:        policy,
+		Salutation:    style,
+		Households:    households,
+		SenderCountry: senderCountry,
+		Sort:          sortOrder,
+	}, nil
+}
+
+func (w WebUI) householdOverrides(ctx context.Context, userID int64) (cohabitaters.HouseholdOverrides, error) {
+	merges, err := w.Queries.ListHouseholdMerges(ctx, userID)
+	if err != nil {
+		return cohabitaters.HouseholdOverrides{}, err
+	}
+	splits, err := w.Queries.ListHouseholdSplits(ctx, userID)
+	if err != nil {
+		return cohabitaters.HouseholdOverrides{}, err
+	}
+
+	out := cohabitaters.HouseholdOverrides{Splits: make(map[string]bool, len(splits))}
+	for _, m := range merges {
+		out.Merges = append(out.Merges, [2]string{m.ResourceNameA, m.ResourceNameB})
+	}
+	for _, s := range splits {
+		out.Splits[s.ResourceName] = true
+	}
+	return out, nil
+}
+
+// coalesce fetches a contact group, from the user's Google accounts or their
+// imported contacts, and coalesces it with the user's saved options, recording the
+// options in out. linked are the linked accounts allContactGroups read.
+func (w WebUI) coalesce(ctx context.Context, sessionID int, token *oauth2.Token, linked []linkedSource, contactGroupResource string, out *html.TmplIndexData) (cohabitaters.Result, error) {
+	user, err := w.Queries.GetUserBySession(ctx, int64(sessionID))
+	if err != nil {
+		return cohabitaters.Result{}, err
+	}
+	opts, err := w.userOptions(ctx, user.ID, out)
+	if err != nil {
+		return cohabitaters.Result{}, err
+	}
+
+	if text, ok := strings.CutPrefix(contactGroupResource, groupExprPrefix); ok {
+		expr, err := cohabitaters.ParseGroupExpr(text)
+		if err != nil {
+			return cohabitaters.Result{}, err
+		}
+		src, err := w.groupExprSource(ctx, user.ID, token, linked)
+		if err != nil {
+			return cohabitaters.Result{}, err
+		}
+		return cohabitaters.GetXmasCardsForExpr(ctx, src, expr, opts)
+	}
+
+	if addressbook.IsResourceName(contactGroupResource) {
+		book, _, err := w.importedBook(ctx, user.ID)
+		if err != nil {
+			return cohabitaters.Result{}, err
+		}
+		if book == nil {
+			return cohabitaters.Result{}, cohabitaters.ErrEmptyGroup
+		}
+		return cohabitaters.GetXmasCards(ctx, book, contactGroupResource, opts)
+	}
+
+	src, err := w.contactSource(ctx, token, linked)
+	if err != nil {
+		return cohabitaters.Result{}, err
+	}
+	return cohabitaters.GetXmasCards(ctx, src, contactGroupResource, opts)
+}
+
+// contactGroups returns the user's contact groups, saved on the session at
+// login.
+func contactGroups(session cohabdb.Session) ([]*people.ContactGroup, error) {
+	if !session.ContactGroupsJson.Valid {
+		return nil, fmt.Errorf("invalid ContactGroups JSON")
+	}
+	var groups []*people.ContactGroup
+	if err := json.Unmarshal([]byte(session.ContactGroupsJson.String), &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
 func (w WebUI) fillTmplIndexData(ctx context.Context, sessionID int, selectedResourceName string, out *html.TmplIndexData) error {
 	token, err := w.getGoogleToken(ctx, sessionID)
 	if err != nil {
@@ -65,15 +290,11 @@ func (w WebUI) fillTmplIndexData(ctx context.Context, sessionID int, selectedRes
 		return err
 	}
 
-	var groups []*people.ContactGroup
-	if session.ContactGroupsJson.Valid {
-		if err := json.Unmarshal([]byte(session.ContactGroupsJson.String), &groups); err != nil {
-			return err
-		}
-	} else {
-		return fmt.Errorf("invalid ContactGroups JSON")
+	linked, err := w.allContactGroups(ctx, session, out)
+	if err != nil {
+		return err
 	}
-	out.Groups = groups
+	groups := out.Groups
 
 	if len(groups) == 0 {
 		return nil
@@ -82,24 +303,56 @@ func (w WebUI) fillTmplIndexData(ctx context.Context, sessionID int, selectedRes
 	if len(selectedResourceName) == 0 && session.SelectedResourceName.Valid {
 		selectedResourceName = session.SelectedResourceName.String
 	}
+	if len(selectedResourceName) == 0 {
+		text, err := w.userPreference(ctx, session.UserID, prefGroupExpression)
+		if err != nil {
+			return err
+		}
+		if len(text) > 0 {
+			selectedResourceName = groupExprPrefix + text
+		}
+	}
 
-	if token.Valid() && len(selectedResourceName) > 0 {
+	// imported contacts don't need Google, and a group may be gone since it
+	// was selected, e.g. when the imported contacts were deleted
+	var groupName string
+	countContacts := -1
+	if text, ok := strings.CutPrefix(selectedResourceName, groupExprPrefix); ok {
+		if !token.Valid() && len(out.ImportFilename) == 0 {
+			return nil
+		}
+		groupName = text
+		out.GroupExpression = text
+	} else {
 		idx := contactGroupIndex(groups, selectedResourceName)
-		cg := groups[idx]
+		if idx < 0 || !(token.Valid() || addressbook.IsResourceName(selectedResourceName)) {
+			return nil
+		}
+		groupName = groups[idx].Name
+		countContacts = int(groups[idx].MemberCount)
+	}
 
-		googs := googleSvcs{TokenSource: w.OauthConfig.TokenSource(ctx, token)}
-		cards, err := googs.getContacts(ctx, selectedResourceName)
-		if err != nil {
-			if errors.Is(err, cohabitaters.ErrEmptyGroup) {
-				out.GroupErrorMsg = fmt.Sprintf("No contacts found in group <%s>", cg.Name)
-				return nil
-			}
-			return err
+	res, err := w.coalesce(ctx, sessionID, token, linked, selectedResourceName, out)
+	if err != nil {
+		if errors.Is(err, cohabitaters.ErrEmptyGroup) {
+			out.GroupErrorMsg = fmt.Sprintf("No contacts found in group <%s>", groupName)
+			return nil
+		}
+		if len(out.GroupExpression) > 0 {
+			// e.g. a group named in the expression was since deleted
+			out.GroupExpressionError = err.Error()
+			return nil
 		}
-		out.TableResults = cards
-		out.CountContacts = int(cg.MemberCount)
-		out.SelectedResourceName = selectedResourceName
+		return err
+	}
+	if countContacts < 0 {
+		countContacts = resultContacts(res)
 	}
+	out.TableResults = res.Cards
+	out.Skipped = res.Skipped
+	out.Subtotals = res.Subtotals
+	out.CountContacts = countContacts
+	out.SelectedResourceName = selectedResourceName
 
 	return nil
 }
@@ -181,6 +434,7 @@ func (w WebUI) Root(c echo.Context) error {
 	u.Path = c.Echo().Reverse(RedirectURLAuthn)
 	tmplData.LoginURL = u.String()
 	tmplData.IsLoggedIn = isLoggedIn
+	tmplData.CSRFToken = csrfToken(c)
 
 	if isLoggedIn {
 		if err = w.fillTmplIndexData(c.Request().Context(), sessionID, "", &tmplData); err != nil {
@@ -250,6 +504,206 @@ func (w WebUI) PartialTableResults(c echo.Context) error {
 	return renderComponentHTML(c, html.ComponentTableResults(tmplData))
 }
 
+// AddressOverride records which of a contact's addresses to use and
+// re-renders the results for the selected contact group.
+func (w WebUI) AddressOverride(c echo.Context) error {
+	resourceName := c.FormValue("resource-name")
+	addressKey := c.FormValue("address-key")
+	if len(resourceName) == 0 || len(addressKey) == 0 {
+		c.Logger().Error("missing expected resource-name or address-key")
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	return w.updateAndRenderTableResults(c, func(ctx context.Context, userID int64) error {
+		return w.Queries.UpsertAddressOverride(ctx, cohabdb.UpsertAddressOverrideParams{
+			UserID:       userID,
+			ResourceName: resourceName,
+			AddressKey:   addressKey,
+		})
+	})
+}
+
+// AddressTypeOrder saves the user's preferred address types and re-renders
+// the results for the selected contact group.
+func (w WebUI) AddressTypeOrder(c echo.Context) error {
+	typeOrder := strings.Join(cohabitaters.ParseTypeOrder(c.FormValue("address-types")), ", ")
+
+	return w.updateAndRenderTableResults(c, func(ctx context.Context, userID int64) error {
+		return w.Queries.UpsertUserPreference(ctx, cohabdb.UpsertUserPreferenceParams{
+			UserID: userID,
+			Name:   prefAddressTypeOrder,
+			Value:  typeOrder,
+		})
+	})
+}
+
+// SalutationStyle saves how the user wants cards addressed and re-renders
+// the results for the selected contact group.
+func (w WebUI) SalutationStyle(c echo.Context) error {
+	style, err := cohabitaters.ParseSalutationStyle(c.FormValue("salutation-style"))
+	if err != nil {
+		c.Logger().Errorf("invalid salutation-style: %v", err)
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	return w.updateAndRenderTableResults(c, func(ctx context.Context, userID int64) error {
+		return w.Queries.UpsertUserPreference(ctx, cohabdb.UpsertUserPreferenceParams{
+			UserID: userID,
+			Name:   prefSalutationStyle,
+			Value:  string(style),
+		})
+	})
+}
+
+// SenderCountry saves the country the user mails cards from and re-renders
+// the results for the selected contact group.
+func (w WebUI) SenderCountry(c echo.Context) error {
+	country := normalize.CountryCode(c.FormValue("sender-country"))
+	if len(country) == 0 {
+		c.Logger().Errorf("unrecognized sender-country: %q", c.FormValue("sender-country"))
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	return w.updateAndRenderTableResults(c, func(ctx context.Context, userID int64) error {
+		return w.Queries.UpsertUserPreference(ctx, cohabdb.UpsertUserPreferenceParams{
+			UserID: userID,
+			Name:   prefSenderCountry,
+			Value:  country,
+		})
+	})
+}
+
+// ReturnAddress saves the return address printed on envelopes and re-renders
+// the results for the selected contact group. An empty address goes back to
+// using the one in the user's Google profile.
+func (w WebUI) ReturnAddress(c echo.Context) error {
+	lines := envelopes.ParseLines(c.FormValue("return-address"))
+
+	return w.updateAndRenderTableResults(c, func(ctx context.Context, userID int64) error {
+		return w.Queries.UpsertUserPreference(ctx, cohabdb.UpsertUserPreferenceParams{
+			UserID: userID,
+			Name:   prefReturnAddress,
+			Value:  strings.Join(lines, "\n"),
+		})
+	})
+}
+
+// SortOrder saves the order the user wants the cards in and re-renders the
+// results for the selected contact group.
+func (w WebUI) SortOrder(c echo.Context) error {
+	order, err := cohabitaters.ParseSortOrder(c.FormValue("sort-order"))
+	if err != nil {
+		c.Logger().Errorf("invalid sort-order: %v", err)
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	return w.updateAndRenderTableResults(c, func(ctx context.Context, userID int64) error {
+		return w.Queries.UpsertUserPreference(ctx, cohabdb.UpsertUserPreferenceParams{
+			UserID: userID,
+			Name:   prefSortOrder,
+			Value:  string(order),
+		})
+	})
+}
+
+// MergeContacts puts the selected contacts, and everyone already on their
+// cards, on a single card and re-renders the results.
+func (w WebUI) MergeContacts(c echo.Context) error {
+	params, err := c.FormParams()
+	if err != nil {
+		return err
+	}
+	resourceNames := slices.Clone(params["resource-name"])
+	slices.Sort(resourceNames)
+	resourceNames = slices.Compact(resourceNames)
+	if len(resourceNames) < 2 {
+		c.Logger().Error("need at least two resource-name values to merge")
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	return w.updateAndRenderTableResults(c, func(ctx context.Context, userID int64) error {
+		for _, rn := range resourceNames {
+			if err := w.Queries.DeleteHouseholdSplit(ctx, cohabdb.DeleteHouseholdSplitParams{UserID: userID, ResourceName: rn}); err != nil {
+				return err
+			}
+		}
+		for _, rn := range resourceNames[1:] {
+			if err := w.Queries.InsertHouseholdMerge(ctx, cohabdb.InsertHouseholdMergeParams{
+				UserID:        userID,
+				ResourceNameA: resourceNames[0],
+				ResourceNameB: rn,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SplitContact gives a contact a card of their own, undoing any merges the
+// user made by hand, and re-renders the results.
+func (w WebUI) SplitContact(c echo.Context) error {
+	resourceName := c.FormValue("split-resource-name")
+	if len(resourceName) == 0 {
+		c.Logger().Error("missing expected split-resource-name")
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	return w.updateAndRenderTableResults(c, func(ctx context.Context, userID int64) error {
+		if err := w.Queries.DeleteHouseholdMerges(ctx, cohabdb.DeleteHouseholdMergesParams{
+			UserID:        userID,
+			ResourceNameA: resourceName,
+			ResourceNameB: resourceName,
+		}); err != nil {
+			return err
+		}
+		return w.Queries.InsertHouseholdSplit(ctx, cohabdb.InsertHouseholdSplitParams{UserID: userID, ResourceName: resourceName})
+	})
+}
+
+func (w WebUI) updateAndRenderTableResults(c echo.Context, update func(ctx context.Context, userID int64) error) error {
+	return w.updateAndRenderTableResultsWith(c, update, nil)
+}
+
+// updateAndRenderTableResultsWith is updateAndRenderTableResults with a hook
+// to adjust the page data, e.g. to report a problem with the update, before
+// it is rendered.
+func (w WebUI) updateAndRenderTableResultsWith(c echo.Context, update func(ctx context.Context, userID int64) error, adjust func(out *html.TmplIndexData)) error {
+	s, err := session.Get(sessionName, c)
+	if err != nil {
+		c.Logger().Infof("error getting previous session: %w", err)
+	}
+	sessionID := sessionID(s)
+
+	ctx := c.Request().Context()
+	isLoggedIn, err := w.isUserLoggedIn(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if !isLoggedIn {
+		c.Logger().Infof("request to update results without login session")
+		return c.Render(http.StatusUnauthorized, "error.html", nil)
+	}
+
+	user, err := w.Queries.GetUserBySession(ctx, int64(sessionID))
+	if err != nil {
+		return err
+	}
+	if err := update(ctx, user.ID); err != nil {
+		return err
+	}
+
+	tmplData := newTmplIndexData()
+	if err = w.fillTmplIndexData(ctx, sessionID, "", &tmplData); err != nil {
+		return err
+	}
+	if adjust != nil {
+		adjust(&tmplData)
+	}
+
+	return renderComponentHTML(c, html.ComponentTableResults(tmplData))
+}
+
 func (w WebUI) Logout(c echo.Context) error {
 	s, err := session.Get(sessionName, c)
 	if err != nil {
@@ -51,6 +51,14 @@ func (ms mockQuerier) InsertUser(ctx context.Context, arg cohabdb.InsertUserPara
 	return cohabdb.User{}, nil
 }
 
+func (ms mockQuerier) DeleteHouseholdMerges(ctx context.Context, arg cohabdb.DeleteHouseholdMergesParams) error {
+	return nil
+}
+
+func (ms mockQuerier) DeleteHouseholdSplit(ctx context.Context, arg cohabdb.DeleteHouseholdSplitParams) error {
+	return nil
+}
+
 func (ms mockQuerier) ExpireSession(ctx context.Context, id int64) error {
 	return nil
 }
@@ -71,6 +79,78 @@ func (ms mockQuerier) GetUserBySession(ctx context.Context, id int64) (cohabdb.U
 	return cohabdb.User{}, nil
 }
 
+func (ms mockQuerier) GetUserPreference(ctx context.Context, arg cohabdb.GetUserPreferenceParams) (string, error) {
+	return "", sql.ErrNoRows
+}
+
+func (ms mockQuerier) InsertHouseholdMerge(ctx context.Context, arg cohabdb.InsertHouseholdMergeParams) error {
+	return nil
+}
+
+func (ms mockQuerier) InsertHouseholdSplit(ctx context.Context, arg cohabdb.InsertHouseholdSplitParams) error {
+	return nil
+}
+
+func (ms mockQuerier) ListHouseholdMerges(ctx context.Context, userID int64) ([]cohabdb.HouseholdMerge, error) {
+	return nil, nil
+}
+
+func (ms mockQuerier) ListHouseholdSplits(ctx context.Context, userID int64) ([]cohabdb.HouseholdSplit, error) {
+	return nil, nil
+}
+
+func (ms mockQuerier) ListAddressOverrides(ctx context.Context, userID int64) ([]cohabdb.AddressOverride, error) {
+	return nil, nil
+}
+
+func (ms mockQuerier) ListLabelLayouts(ctx context.Context, userID int64) ([]cohabdb.LabelLayout, error) {
+	return nil, nil
+}
+
+func (ms mockQuerier) GetLabelLayout(ctx context.Context, arg cohabdb.GetLabelLayoutParams) (string, error) {
+	return "", sql.ErrNoRows
+}
+
+func (ms mockQuerier) UpsertLabelLayout(ctx context.Context, arg cohabdb.UpsertLabelLayoutParams) error {
+	return nil
+}
+
+func (ms mockQuerier) DeleteLabelLayout(ctx context.Context, arg cohabdb.DeleteLabelLayoutParams) error {
+	return nil
+}
+
+func (ms mockQuerier) GetContactImport(ctx context.Context, userID int64) (cohabdb.ContactImport, error) {
+	return cohabdb.ContactImport{}, sql.ErrNoRows
+}
+
+func (ms mockQuerier) UpsertContactImport(ctx context.Context, arg cohabdb.UpsertContactImportParams) error {
+	return nil
+}
+
+func (ms mockQuerier) DeleteContactImport(ctx context.Context, userID int64) error {
+	return nil
+}
+
+func (ms mockQuerier) ListLinkedAccounts(ctx context.Context, userID int64) ([]cohabdb.LinkedAccount, error) {
+	return nil, nil
+}
+
+func (ms mockQuerier) UpsertLinkedAccount(ctx context.Context, arg cohabdb.UpsertLinkedAccountParams) error {
+	return nil
+}
+
+func (ms mockQuerier) DeleteLinkedAccount(ctx context.Context, arg cohabdb.DeleteLinkedAccountParams) error {
+	return nil
+}
+
+func (ms mockQuerier) UpsertAddressOverride(ctx context.Context, arg cohabdb.UpsertAddressOverrideParams) error {
+	return nil
+}
+
+func (ms mockQuerier) UpsertUserPreference(ctx context.Context, arg cohabdb.UpsertUserPreferenceParams) error {
+	return nil
+}
+
 func (ms mockQuerier) UpdateContactGroupsJSON(ctx context.Context, arg cohabdb.UpdateContactGroupsJSONParams) error {
 	return nil
 }
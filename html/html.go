@@ -28,6 +28,8 @@ func mustSub(emb fs.FS, dir string) fs.FS {
 
 type TmplIndexData = templs.PageIndexInput
 
+type TmplPrintData = templs.PagePrintInput
+
 func ComponentPageIndex(input TmplIndexData) templ.Component {
 	return templs.PageIndex(input)
 }
@@ -35,3 +37,11 @@ func ComponentPageIndex(input TmplIndexData) templ.Component {
 func ComponentTableResults(input TmplIndexData) templ.Component {
 	return templs.Results(input)
 }
+
+func ComponentGroupsPanel(input TmplIndexData) templ.Component {
+	return templs.GroupsPanel(input)
+}
+
+func ComponentPagePrint(input TmplPrintData) templ.Component {
+	return templs.PagePrint(input)
+}
@@ -0,0 +1,147 @@
+package templs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/bfallik/cohabitaters"
+	"github.com/bfallik/cohabitaters/envelopes"
+	"github.com/bfallik/cohabitaters/pdf"
+)
+
+func reviewTitle(card cohabitaters.XmasCard) string {
+	names := map[string]string{}
+	for _, c := range card.Contacts {
+		names[c.ResourceName] = c.Name
+	}
+	name := func(rn string) string {
+		if n, ok := names[rn]; ok {
+			return n
+		}
+		return rn
+	}
+
+	var lines []string
+	for _, m := range card.Matches {
+		if m.Review {
+			lines = append(lines, fmt.Sprintf("merged %s and %s: %s", name(m.A), name(m.B), m.Reason))
+		}
+	}
+	for _, m := range card.NearMisses {
+		lines = append(lines, fmt.Sprintf("kept %s and %s apart: %s", name(m.A), name(m.B), m.Reason))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func skippedName(s cohabitaters.SkippedContact) string {
+	if len(s.Name) > 0 {
+		return s.Name
+	}
+	return s.ResourceName
+}
+
+func formatAddress(a cohabitaters.Address) string {
+	var parts []string
+	for _, p := range []string{a.StreetAddress, a.StreetAddress2, a.City, a.Region, a.PostalCode, a.Country} {
+		if p = strings.TrimSpace(p); len(p) > 0 {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func salutationLabel(style cohabitaters.SalutationStyle) string {
+	switch style {
+	case cohabitaters.SalutationFamily:
+		return "The Smith Family"
+	case cohabitaters.SalutationFullNames:
+		return "John Smith & Jane Smith"
+	default:
+		return "John & Jane Smith"
+	}
+}
+
+func sortOrderLabel(order cohabitaters.SortOrder) string {
+	switch order {
+	case cohabitaters.SortAddressee:
+		return "By addressee"
+	case cohabitaters.SortPostal:
+		return "By country, region, postal code and street"
+	default:
+		return "As found"
+	}
+}
+
+func cardCount(n int) string {
+	if n == 1 {
+		return "1 card"
+	}
+	return strconv.Itoa(n) + " cards"
+}
+
+// subtotalsSummary describes where the cards are going, e.g.
+// "12 domestic and 3 international cards: United States 12, Canada 3".
+func subtotalsSummary(s cohabitaters.Subtotals) string {
+	var countries []string
+	for _, c := range s.Countries {
+		countries = append(countries, fmt.Sprintf("%s %d", countryLabel(c.Destination), c.Cards))
+	}
+	return fmt.Sprintf("%d domestic and %d international cards: %s", s.Domestic, s.International, strings.Join(countries, ", "))
+}
+
+func countryLabel(d cohabitaters.Destination) string {
+	if len(d.Country) == 0 {
+		return "Unknown country"
+	}
+	return d.Country
+}
+
+// countryHeading labels the group of postal sorted cards that starts at
+// cards[idx], e.g. "Canada: 3 cards". It returns "" when cards[idx] is in
+// the same country as the card before it.
+func countryHeading(cards []cohabitaters.XmasCard, idx int, senderCountry string, s cohabitaters.Subtotals) string {
+	d := cards[idx].Address.Destination(senderCountry)
+	if idx > 0 && cards[idx-1].Address.Destination(senderCountry) == d {
+		return ""
+	}
+	for _, c := range s.Countries {
+		if c.Destination == d {
+			return countryLabel(d) + ": " + cardCount(c.Cards)
+		}
+	}
+	return countryLabel(d)
+}
+
+// exportURL links to an export endpoint for a contact group. bom asks for a
+// byte order mark so Excel reads the file as UTF-8.
+func exportURL(path, contactGroup string, bom bool) templ.SafeURL {
+	q := url.Values{"contact-group": {contactGroup}}
+	if bom {
+		q.Set("bom", "true")
+	}
+	return templ.SafeURL(path + "?" + q.Encode())
+}
+
+// envelopeLabel describes an envelope size for a menu, e.g.
+// `#10 (9.5" x 4.125")`.
+func envelopeLabel(e envelopes.Envelope) string {
+	name := e.Name
+	if _, err := strconv.Atoi(name); err == nil {
+		name = "#" + name
+	}
+	return fmt.Sprintf(`%s (%g" x %g")`, name, e.Page.Width/pdf.Inch, e.Page.Height/pdf.Inch)
+}
+
+// csrfHeaders returns the hx-headers that send the CSRF token along with
+// every htmx request.
+func csrfHeaders(token string) string {
+	bs, err := json.Marshal(map[string]string{"X-CSRF-Token": token})
+	if err != nil {
+		panic(fmt.Sprintf("unable to marshal CSRF headers: %v", err))
+	}
+	return string(bs)
+}
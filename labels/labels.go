@@ -0,0 +1,259 @@
+// Package labels prints XmasCard addresses onto sheets of adhesive mailing
+// labels.
+package labels
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/bfallik/cohabitaters/pdf"
+)
+
+// Sheet describes the layout of a label sheet. Lengths are in points.
+type Sheet struct {
+	Name string
+	Page pdf.Size
+	// Columns and Rows count the labels across and down a page.
+	Columns, Rows int
+	// Width and Height are the size of one label.
+	Width, Height float64
+	// Top and Left are the page margins above and beside the first label.
+	Top, Left float64
+	// ColumnPitch and RowPitch are the distances between the left and top
+	// edges of neighbouring labels, i.e. the label size plus any gap.
+	ColumnPitch, RowPitch float64
+}
+
+var (
+	// Avery5160 is 30 1" x 2 5/8" labels on US Letter.
+	Avery5160 = Sheet{
+		Name:    "5160",
+		Page:    pdf.Letter,
+		Columns: 3, Rows: 10,
+		Width: 2.625 * pdf.Inch, Height: 1 * pdf.Inch,
+		Top: 0.5 * pdf.Inch, Left: 0.1875 * pdf.Inch,
+		ColumnPitch: 2.75 * pdf.Inch, RowPitch: 1 * pdf.Inch,
+	}
+	// Avery5163 is 10 2" x 4" labels on US Letter.
+	Avery5163 = Sheet{
+		Name:    "5163",
+		Page:    pdf.Letter,
+		Columns: 2, Rows: 5,
+		Width: 4 * pdf.Inch, Height: 2 * pdf.Inch,
+		Top: 0.5 * pdf.Inch, Left: 0.15625 * pdf.Inch,
+		ColumnPitch: 4.1875 * pdf.Inch, RowPitch: 2 * pdf.Inch,
+	}
+	// AveryL7160 is 21 63.5mm x 38.1mm labels on A4.
+	AveryL7160 = Sheet{
+		Name:    "L7160",
+		Page:    pdf.A4,
+		Columns: 3, Rows: 7,
+		Width: 63.5 * pdf.Millimeter, Height: 38.1 * pdf.Millimeter,
+		Top: 15.15 * pdf.Millimeter, Left: 7.2 * pdf.Millimeter,
+		ColumnPitch: 66.04 * pdf.Millimeter, RowPitch: 38.1 * pdf.Millimeter,
+	}
+)
+
+// Sheets lists the supported sheets, default first.
+var Sheets = []Sheet{Avery5160, Avery5163, AveryL7160}
+
+// LookupSheet returns the sheet named name, or Avery5160 if name is empty.
+func LookupSheet(name string) (Sheet, error) {
+	if len(name) == 0 {
+		return Avery5160, nil
+	}
+	for _, s := range Sheets {
+		if strings.EqualFold(name, s.Name) {
+			return s, nil
+		}
+	}
+	return Sheet{}, fmt.Errorf("unknown label sheet %q", name)
+}
+
+// PerPage returns the number of labels on one sheet.
+func (s Sheet) PerPage() int {
+	return s.Columns * s.Rows
+}
+
+// origin returns the bottom left corner of label i of a page, counting across
+// each row from the top left.
+func (s Sheet) origin(i int) (x, y float64) {
+	row, col := i/s.Columns, i%s.Columns
+	x = s.Left + float64(col)*s.ColumnPitch
+	y = s.Page.Height - s.Top - float64(row)*s.RowPitch - s.Height
+	return x, y
+}
+
+const (
+	// DefaultFontSize is used when Options.FontSize is zero.
+	DefaultFontSize = 11.0
+	// MinFontSize is the smallest size text is shrunk to so a long address
+	// fits its label.
+	MinFontSize = 6.0
+	// DefaultPadding is used when Options.Padding is zero.
+	DefaultPadding = 0.1 * pdf.Inch
+	// leading is the distance between baselines relative to the font size.
+	leading = 1.2
+)
+
+// Options tunes Write. The zero value prints Avery 5160 labels in 11pt
+// Helvetica.
+type Options struct {
+	// Sheet is the label layout; a zero Sheet means Avery5160.
+	Sheet Sheet
+	Font  pdf.Font
+	// FontSize is the largest size used; addresses that don't fit are set
+	// smaller, down to MinFontSize.
+	FontSize float64
+	// Padding is the blank margin kept inside each edge of a label.
+	Padding float64
+	// OffsetX and OffsetY shift everything right and up, in points, to make
+	// up for a printer that doesn't feed sheets squarely.
+	OffsetX, OffsetY float64
+	// Skip is the number of labels already used on the first sheet; printing
+	// starts at the next one.
+	Skip int
+	// Layout, when set, replaces Sheet, Font, FontSize and Padding with a
+	// user's own layout.
+	Layout *Layout
+}
+
+// ErrSkip means Options.Skip doesn't leave a label free on the first sheet.
+var ErrSkip = errors.New("skip must leave at least one label on the first sheet")
+
+func (o Options) withDefaults() Options {
+	if o.Sheet.PerPage() == 0 {
+		o.Sheet = Avery5160
+	}
+	if len(o.Font) == 0 {
+		o.Font = pdf.Helvetica
+	}
+	if o.FontSize == 0 {
+		o.FontSize = DefaultFontSize
+	}
+	if o.Padding == 0 {
+		o.Padding = DefaultPadding
+	}
+	return o
+}
+
+// Lines returns the text printed for a card: the addressee followed by the
+// mailing address.
+func Lines(card cohabitaters.XmasCard) []string {
+	addressee := card.Addressee
+	if len(addressee) == 0 {
+		addressee = strings.Join(card.Names, " & ")
+	}
+	return append([]string{addressee}, card.AddressLines...)
+}
+
+// fitFontSize returns the biggest size, up to largest, at which lines fit
+// within width and height, but never less than MinFontSize.
+func fitFontSize(font pdf.Font, largest float64, lines []string, width, height float64) float64 {
+	return math.Max(font.FitSize(largest, lines, width, height, leading), MinFontSize)
+}
+
+// Write prints one label per card, left aligned and centered vertically, as
+// a PDF. When opts.Layout is set its sheet, fonts and lines are used instead.
+func Write(w io.Writer, cards []cohabitaters.XmasCard, opts Options) error {
+	opts = opts.withDefaults()
+	sheet := opts.Sheet
+	if opts.Layout != nil {
+		sheet = opts.Layout.Sheet()
+	}
+	if opts.Skip < 0 || opts.Skip >= sheet.PerPage() {
+		return ErrSkip
+	}
+
+	var doc pdf.Document
+	var page *pdf.Page
+	for i, card := range cards {
+		slot := (opts.Skip + i) % sheet.PerPage()
+		if page == nil || slot == 0 {
+			page = doc.AddPage(sheet.Page)
+		}
+
+		x, y := sheet.origin(slot)
+		box := box{X: x + opts.OffsetX, Y: y + opts.OffsetY, Width: sheet.Width, Height: sheet.Height, Padding: opts.Padding}
+		if opts.Layout != nil {
+			box.Padding = opts.Layout.padding()
+			runs := opts.Layout.runs(card)
+			box.fit(runs)
+			box.draw(page, runs, opts.Layout.Align)
+			continue
+		}
+
+		lines := Lines(card)
+		size := fitFontSize(opts.Font, opts.FontSize, lines, box.Width-2*box.Padding, box.Height-2*box.Padding)
+		runs := make([]run, len(lines))
+		for i, line := range lines {
+			runs[i] = run{Text: line, Font: opts.Font, Size: size}
+		}
+		box.draw(page, runs, AlignLeft)
+	}
+	if doc.Pages() == 0 {
+		doc.AddPage(sheet.Page)
+	}
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// run is one line of text on a label.
+type run struct {
+	Text string
+	Font pdf.Font
+	Size float64
+}
+
+// box is the area of one label on a page.
+type box struct {
+	X, Y, Width, Height float64
+	Padding             float64
+}
+
+// fit shrinks runs, keeping their relative sizes, until they fit within the
+// box's padding, but never below MinFontSize.
+func (b box) fit(runs []run) {
+	width, height := b.Width-2*b.Padding, b.Height-2*b.Padding
+	scale, block := 1.0, 0.0
+	for _, r := range runs {
+		if w := r.Font.Width(r.Text, r.Size); w > width {
+			scale = math.Min(scale, width/w)
+		}
+		block += leading * r.Size
+	}
+	if block > height {
+		scale = math.Min(scale, height/block)
+	}
+	for i := range runs {
+		runs[i].Size = math.Max(runs[i].Size*scale, MinFontSize)
+	}
+}
+
+// draw writes runs one per line, centered vertically in the box.
+func (b box) draw(page *pdf.Page, runs []run, align Align) {
+	var block float64
+	for _, r := range runs {
+		block += leading * r.Size
+	}
+	top := b.Y + (b.Height+block)/2
+	for _, r := range runs {
+		// the baseline sits one ascent, about 0.8 of the size, below the top
+		// of the line
+		baseline := top - (leading-1)*r.Size/2 - 0.8*r.Size
+		x := b.X + b.Padding
+		switch align {
+		case AlignCenter:
+			x = b.X + (b.Width-r.Font.Width(r.Text, r.Size))/2
+		case AlignRight:
+			x = b.X + b.Width - b.Padding - r.Font.Width(r.Text, r.Size)
+		}
+		page.Text(x, baseline, r.Font, r.Size, r.Text)
+		top -= leading * r.Size
+	}
+}
@@ -0,0 +1,167 @@
+package labels
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/bfallik/cohabitaters/pdf"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSheetsFitPage(t *testing.T) {
+	for _, s := range Sheets {
+		x, y := s.origin(s.PerPage() - 1)
+		if right := x + s.Width; right > s.Page.Width {
+			t.Errorf("%s: last label ends at x=%.2f, past the page width %.2f", s.Name, right, s.Page.Width)
+		}
+		if y < 0 {
+			t.Errorf("%s: last label starts at y=%.2f, below the page", s.Name, y)
+		}
+		// sheets are laid out symmetrically
+		if bottom := y; math.Abs(bottom-s.Top) > 1 {
+			t.Errorf("%s: bottom margin %.2f, want about %.2f", s.Name, bottom, s.Top)
+		}
+		if right := s.Page.Width - x - s.Width; math.Abs(right-s.Left) > 1 {
+			t.Errorf("%s: right margin %.2f, want about %.2f", s.Name, right, s.Left)
+		}
+	}
+}
+
+func TestOrigin(t *testing.T) {
+	tests := []struct {
+		Index int
+		X, Y  float64
+	}{
+		{0, 13.5, 684},
+		{1, 211.5, 684},
+		{3, 13.5, 612},
+		{29, 409.5, 36},
+	}
+
+	for _, test := range tests {
+		x, y := Avery5160.origin(test.Index)
+		if x != test.X || y != test.Y {
+			t.Errorf("origin(%d) got: (%v, %v), want: (%v, %v)", test.Index, x, y, test.X, test.Y)
+		}
+	}
+}
+
+func TestLookupSheet(t *testing.T) {
+	tests := []struct {
+		In   string
+		Want string
+	}{
+		{"", "5160"},
+		{"5163", "5163"},
+		{"l7160", "L7160"},
+	}
+
+	for _, test := range tests {
+		got, err := LookupSheet(test.In)
+		if err != nil {
+			t.Errorf("LookupSheet(%q) unexpected error: %v", test.In, err)
+		}
+		if got.Name != test.Want {
+			t.Errorf("LookupSheet(%q) got: %v, want: %v", test.In, got.Name, test.Want)
+		}
+	}
+
+	if _, err := LookupSheet("9999"); err == nil {
+		t.Errorf("expected an error for an unknown sheet")
+	}
+}
+
+func TestFitFontSize(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Lines []string
+		Want  float64
+	}{
+		{"fits", []string{"Homer & Marge Simpson", "742 Evergreen Terrace"}, 11},
+		{"too wide", []string{strings.Repeat("W", 20)}, 180.0 / (20 * 0.944)},
+		{"too many lines", []string{"a", "b", "c", "d", "e", "f"}, 72.0 / (1.2 * 6)},
+		{"never tiny", []string{strings.Repeat("W", 200)}, MinFontSize},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			got := fitFontSize(pdf.Helvetica, 11, test.Lines, 180, 72)
+			if math.Abs(got-test.Want) > 0.001 {
+				t.Errorf("unexpected size, got: %v, want: %v", got, test.Want)
+			}
+		})
+	}
+}
+
+func TestLines(t *testing.T) {
+	card := cohabitaters.XmasCard{
+		Names:        []string{"Homer Simpson", "Marge Simpson"},
+		AddressLines: []string{"742 Evergreen Terrace", "SPRINGFIELD IL 62701"},
+	}
+	want := []string{"Homer Simpson & Marge Simpson", "742 Evergreen Terrace", "SPRINGFIELD IL 62701"}
+	if diff := cmp.Diff(want, Lines(card)); diff != "" {
+		t.Errorf("Lines() mismatch (-want +got):\n%s", diff)
+	}
+
+	card.Addressee = "Homer & Marge Simpson"
+	if got := Lines(card)[0]; got != card.Addressee {
+		t.Errorf("unexpected addressee, got: %v, want: %v", got, card.Addressee)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	cards := make([]cohabitaters.XmasCard, 12)
+	for i := range cards {
+		cards[i] = cohabitaters.XmasCard{Addressee: "Ned Flanders", AddressLines: []string{"744 Evergreen Terrace", "SPRINGFIELD IL 62701"}}
+	}
+
+	tests := []struct {
+		Name  string
+		Opts  Options
+		Pages int
+	}{
+		{"one sheet", Options{}, 1},
+		{"partly used sheet", Options{Skip: 25}, 2},
+		{"small sheet", Options{Sheet: Avery5163}, 2},
+		{"A4", Options{Sheet: AveryL7160, Font: pdf.TimesRoman, FontSize: 12}, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := Write(&b, cards, test.Opts); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := strings.Count(b.String(), "/Type /Page "); got != test.Pages {
+				t.Errorf("unexpected page count, got: %v, want: %v", got, test.Pages)
+			}
+			if got := strings.Count(b.String(), "(Ned Flanders) Tj"); got != len(cards) {
+				t.Errorf("unexpected label count, got: %v, want: %v", got, len(cards))
+			}
+		})
+	}
+}
+
+func TestWriteSkip(t *testing.T) {
+	var b bytes.Buffer
+	card := cohabitaters.XmasCard{Addressee: "Ned Flanders"}
+
+	// the first free label of a 5160 sheet after skipping 4 is the second
+	// label of the second row, centered in its 72pt height
+	if err := Write(&b, []cohabitaters.XmasCard{card}, Options{Skip: 4, FontSize: 10}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "BT /F1 10 Tf 218.7 645 Td (Ned Flanders) Tj ET"; !strings.Contains(b.String(), want) {
+		t.Errorf("output is missing %q", want)
+	}
+
+	for _, skip := range []int{-1, 30} {
+		if err := Write(&b, nil, Options{Skip: skip}); !errors.Is(err, ErrSkip) {
+			t.Errorf("Write(Skip: %d) got: %v, want: %v", skip, err, ErrSkip)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package labels
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/bfallik/cohabitaters/normalize"
+	"github.com/bfallik/cohabitaters/pdf"
+)
+
+// Layout is a label sheet described by the user rather than built in. It is
+// written as JSON, for example:
+//
+//	{
+//	  "name": "Kraft 2x4",
+//	  "page": "letter",
+//	  "columns": 2,
+//	  "rows": 4,
+//	  "label": {"width": "4in", "height": "2.5in"},
+//	  "margin": {"top": "0.5in", "left": "0.17in"},
+//	  "gap": {"column": "0.16in"},
+//	  "padding": "0.2in",
+//	  "font": "Times-Roman",
+//	  "font_size": 12,
+//	  "align": "center",
+//	  "lines": [
+//	    {"text": "{addressee}", "font": "Times-Bold", "font_size": 14},
+//	    {"text": "{address}"}
+//	  ]
+//	}
+//
+// Lengths are points, or strings with an "in", "mm", "cm" or "pt" unit. The
+// page is "letter", "a4" or a width and height. Each line's text may name
+// the card's fields in braces; see LayoutFields.
+type Layout struct {
+	Name    string     `json:"name"`
+	Page    LayoutPage `json:"page"`
+	Columns int        `json:"columns"`
+	Rows    int        `json:"rows"`
+	Label   struct {
+		Width  Length `json:"width"`
+		Height Length `json:"height"`
+	} `json:"label"`
+	// Margin is the space above and beside the first label.
+	Margin struct {
+		Top  Length `json:"top"`
+		Left Length `json:"left"`
+	} `json:"margin"`
+	// Gap is the space between neighbouring labels.
+	Gap struct {
+		Column Length `json:"column"`
+		Row    Length `json:"row"`
+	} `json:"gap"`
+	// Padding is the blank margin kept inside each edge of a label; zero
+	// means DefaultPadding.
+	Padding Length `json:"padding"`
+	// Font and FontSize are the defaults for lines that don't set their own;
+	// zero values mean Helvetica and DefaultFontSize.
+	Font     pdf.Font     `json:"font"`
+	FontSize float64      `json:"font_size"`
+	Align    Align        `json:"align"`
+	Lines    []LayoutLine `json:"lines"`
+}
+
+// LayoutLine is one line of text on a Layout's labels.
+type LayoutLine struct {
+	Text     string   `json:"text"`
+	Font     pdf.Font `json:"font"`
+	FontSize float64  `json:"font_size"`
+}
+
+// Align is the horizontal alignment of a label's lines.
+type Align string
+
+const (
+	AlignLeft   Align = "left"
+	AlignCenter Align = "center"
+	AlignRight  Align = "right"
+)
+
+// MaxFontSize is the largest size a Layout may ask for.
+const MaxFontSize = 72.0
+
+// LayoutFields describes the fields a LayoutLine can use, written in braces
+// as in "{addressee}".
+var LayoutFields = []struct{ Name, Description string }{
+	{"addressee", "who the card is addressed to, e.g. John & Jane Smith"},
+	{"names", "every name on the card, separated by commas"},
+	{"address", "the whole mailing address, one line per line; must be alone on its line"},
+	{"street", "the first street address line"},
+	{"street2", "the second street address line"},
+	{"city", "the city"},
+	{"region", "the state, province or region"},
+	{"postal_code", "the postal code"},
+	{"country", "the country"},
+}
+
+// Length is a distance in points.
+type Length float64
+
+// UnmarshalJSON reads a number of points or a string with a unit.
+func (l *Length) UnmarshalJSON(b []byte) error {
+	var f float64
+	if err := json.Unmarshal(b, &f); err == nil {
+		*l = Length(f)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("length must be a number or a string such as \"2.5in\", got %s", b)
+	}
+	parsed, err := ParseLength(s)
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
+
+// ParseLength reads a length such as "2.5in", "63.5mm" or "12pt". A bare
+// number is in points.
+func ParseLength(s string) (Length, error) {
+	num, unit := strings.TrimSpace(strings.ToLower(s)), 1.0
+	for suffix, u := range map[string]float64{"in": pdf.Inch, "mm": pdf.Millimeter, "cm": 10 * pdf.Millimeter, "pt": 1} {
+		if strings.HasSuffix(num, suffix) {
+			num, unit = strings.TrimSpace(strings.TrimSuffix(num, suffix)), u
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid length %q", s)
+	}
+	return Length(f * unit), nil
+}
+
+// LayoutPage is the page size of a Layout.
+type LayoutPage struct {
+	Width  Length `json:"width"`
+	Height Length `json:"height"`
+}
+
+// UnmarshalJSON reads "letter", "a4" or an object with a width and height.
+func (p *LayoutPage) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		switch strings.ToLower(name) {
+		case "letter":
+			*p = LayoutPage{Length(pdf.Letter.Width), Length(pdf.Letter.Height)}
+		case "a4":
+			*p = LayoutPage{Length(pdf.A4.Width), Length(pdf.A4.Height)}
+		default:
+			return fmt.Errorf("unknown page size %q, want \"letter\", \"a4\" or a width and height", name)
+		}
+		return nil
+	}
+	type plain LayoutPage
+	return json.Unmarshal(b, (*plain)(p))
+}
+
+// ValidationError lists everything wrong with a Layout.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid layout: " + strings.Join(e.Problems, "; ")
+}
+
+func (e *ValidationError) add(format string, args ...any) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+// ParseLayout reads and validates a JSON Layout. Problems with it are
+// reported as a *ValidationError.
+func ParseLayout(data []byte) (Layout, error) {
+	var l Layout
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&l); err != nil {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			return Layout{}, &ValidationError{Problems: []string{fmt.Sprintf("not valid JSON at byte %d: %v", syntax.Offset, err)}}
+		}
+		return Layout{}, &ValidationError{Problems: []string{strings.TrimPrefix(err.Error(), "json: ")}}
+	}
+	if err := l.Validate(); err != nil {
+		return Layout{}, err
+	}
+	return l, nil
+}
+
+// Validate checks that the layout's labels fit on its page and that its
+// fonts and fields exist.
+func (l Layout) Validate() error {
+	var e ValidationError
+	if len(strings.TrimSpace(l.Name)) == 0 {
+		e.add("name is required")
+	}
+	if l.Page.Width <= 0 || l.Page.Height <= 0 {
+		e.add("page width and height must be positive")
+	}
+	if l.Columns < 1 || l.Rows < 1 {
+		e.add("columns and rows must be at least 1")
+	}
+	if l.Label.Width <= 0 || l.Label.Height <= 0 {
+		e.add("label width and height must be positive")
+	}
+	if l.Margin.Top < 0 || l.Margin.Left < 0 || l.Gap.Column < 0 || l.Gap.Row < 0 || l.Padding < 0 {
+		e.add("margins, gaps and padding can't be negative")
+	}
+	if len(e.Problems) == 0 {
+		s := l.Sheet()
+		x, y := s.origin(s.PerPage() - 1)
+		if x+s.Width > s.Page.Width+0.5 {
+			e.add("the labels are %s wide with the left margin, wider than the %s page", inches(x+s.Width), inches(s.Page.Width))
+		}
+		if y < -0.5 {
+			e.add("the labels are %s tall with the top margin, taller than the %s page", inches(s.Page.Height-y), inches(s.Page.Height))
+		}
+		if pad := l.padding(); 2*pad >= s.Width || 2*pad >= s.Height {
+			e.add("padding of %s leaves no room on the label", inches(pad))
+		}
+	}
+
+	checkFont := func(where string, font pdf.Font, size float64) {
+		if len(font) > 0 {
+			if _, err := pdf.ParseFont(string(font)); err != nil {
+				e.add("%s: %v", where, err)
+			}
+		}
+		if size != 0 && (size < MinFontSize || size > MaxFontSize) {
+			e.add("%s: font_size must be between %g and %g", where, MinFontSize, MaxFontSize)
+		}
+	}
+	checkFont("layout", l.Font, l.FontSize)
+	switch l.Align {
+	case "", AlignLeft, AlignCenter, AlignRight:
+	default:
+		e.add("align must be left, center or right, not %q", l.Align)
+	}
+
+	if len(l.Lines) == 0 {
+		e.add("at least one line is required")
+	}
+	for i, line := range l.Lines {
+		where := fmt.Sprintf("line %d", i+1)
+		checkFont(where, line.Font, line.FontSize)
+		fields, err := parseFields(line.Text)
+		if err != nil {
+			e.add("%s: %v", where, err)
+			continue
+		}
+		for _, f := range fields {
+			if f == "address" && strings.TrimSpace(line.Text) != "{address}" {
+				e.add("%s: {address} must be alone on its line", where)
+			}
+		}
+	}
+
+	if len(e.Problems) > 0 {
+		return &e
+	}
+	return nil
+}
+
+func inches(l float64) string {
+	return fmt.Sprintf("%.2fin", l/pdf.Inch)
+}
+
+// parseFields returns the fields named in text, checking that each exists.
+func parseFields(text string) ([]string, error) {
+	var fields []string
+	for rest := text; len(rest) > 0; {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			break
+		}
+		if rest[open] == '}' {
+			return nil, fmt.Errorf("unmatched '}' in %q", text)
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unmatched '{' in %q", text)
+		}
+		name := rest[open+1 : open+end]
+		if !knownField(name) {
+			return nil, fmt.Errorf("unknown field {%s}", name)
+		}
+		fields = append(fields, name)
+		rest = rest[open+end+1:]
+	}
+	return fields, nil
+}
+
+func knownField(name string) bool {
+	for _, f := range LayoutFields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldValue returns a card's value for a field other than "address".
+func fieldValue(card cohabitaters.XmasCard, name string) string {
+	a := card.Address
+	switch name {
+	case "addressee":
+		if len(card.Addressee) > 0 {
+			return card.Addressee
+		}
+		return strings.Join(card.Names, " & ")
+	case "names":
+		return strings.Join(card.Names, ", ")
+	case "street":
+		return a.StreetAddress
+	case "street2":
+		return a.StreetAddress2
+	case "city":
+		return a.City
+	case "region":
+		return a.Region
+	case "postal_code":
+		return a.PostalCode
+	case "country":
+		if len(a.Country) == 0 && len(a.CountryCode) > 0 {
+			return normalize.CountryName(a.CountryCode)
+		}
+		return a.Country
+	}
+	return ""
+}
+
+// expandFields replaces the fields named in text with the card's values.
+func expandFields(text string, card cohabitaters.XmasCard) string {
+	var b strings.Builder
+	for {
+		open := strings.IndexByte(text, '{')
+		end := strings.IndexByte(text, '}')
+		if open < 0 || end < open {
+			b.WriteString(text)
+			return b.String()
+		}
+		b.WriteString(text[:open])
+		b.WriteString(fieldValue(card, text[open+1:end]))
+		text = text[end+1:]
+	}
+}
+
+// Sheet returns the layout's grid as a Sheet.
+func (l Layout) Sheet() Sheet {
+	return Sheet{
+		Name:        l.Name,
+		Page:        pdf.Size{Width: float64(l.Page.Width), Height: float64(l.Page.Height)},
+		Columns:     l.Columns,
+		Rows:        l.Rows,
+		Width:       float64(l.Label.Width),
+		Height:      float64(l.Label.Height),
+		Top:         float64(l.Margin.Top),
+		Left:        float64(l.Margin.Left),
+		ColumnPitch: float64(l.Label.Width + l.Gap.Column),
+		RowPitch:    float64(l.Label.Height + l.Gap.Row),
+	}
+}
+
+func (l Layout) padding() float64 {
+	if l.Padding == 0 {
+		return DefaultPadding
+	}
+	return float64(l.Padding)
+}
+
+// runs fills in the layout's lines for a card. Lines that come out blank,
+// such as a missing second street line, are left out.
+func (l Layout) runs(card cohabitaters.XmasCard) []run {
+	defaultFont, _ := pdf.ParseFont(string(l.Font))
+	defaultSize := l.FontSize
+	if defaultSize == 0 {
+		defaultSize = DefaultFontSize
+	}
+
+	var runs []run
+	for _, line := range l.Lines {
+		font, size := defaultFont, defaultSize
+		if len(line.Font) > 0 {
+			font, _ = pdf.ParseFont(string(line.Font))
+		}
+		if line.FontSize != 0 {
+			size = line.FontSize
+		}
+
+		texts := card.AddressLines
+		if strings.TrimSpace(line.Text) != "{address}" {
+			texts = []string{expandFields(line.Text, card)}
+		}
+		for _, text := range texts {
+			if text = strings.Join(strings.Fields(text), " "); len(text) > 0 {
+				runs = append(runs, run{Text: text, Font: font, Size: size})
+			}
+		}
+	}
+	return runs
+}
@@ -0,0 +1,201 @@
+package labels
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bfallik/cohabitaters"
+	"github.com/bfallik/cohabitaters/pdf"
+	"github.com/google/go-cmp/cmp"
+)
+
+const kraftLayout = `{
+  "name": "Kraft 2x4",
+  "page": "letter",
+  "columns": 2,
+  "rows": 4,
+  "label": {"width": "4in", "height": "2.5in"},
+  "margin": {"top": "0.5in", "left": "0.17in"},
+  "gap": {"column": "0.16in"},
+  "padding": "0.2in",
+  "font": "times-roman",
+  "font_size": 12,
+  "align": "center",
+  "lines": [
+    {"text": "{addressee}", "font": "Times-Bold", "font_size": 14},
+    {"text": "{street2}"},
+    {"text": "{address}"}
+  ]
+}`
+
+func TestParseLayout(t *testing.T) {
+	l, err := ParseLayout([]byte(kraftLayout))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Sheet{
+		Name:    "Kraft 2x4",
+		Page:    pdf.Letter,
+		Columns: 2, Rows: 4,
+		Width: 4 * pdf.Inch, Height: 2.5 * pdf.Inch,
+		Top: 0.5 * pdf.Inch, Left: 0.17 * pdf.Inch,
+		ColumnPitch: 4.16 * pdf.Inch, RowPitch: 2.5 * pdf.Inch,
+	}
+	if diff := cmp.Diff(want, l.Sheet(), cmp.Comparer(func(a, b float64) bool { return math.Abs(a-b) < 0.001 })); diff != "" {
+		t.Errorf("Sheet() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseLength(t *testing.T) {
+	tests := []struct {
+		In   string
+		Want Length
+	}{
+		{"72", 72},
+		{"1in", 72},
+		{" 2.5 IN ", 180},
+		{"25.4mm", 72},
+		{"2.54cm", 72},
+		{"10pt", 10},
+	}
+
+	for _, test := range tests {
+		got, err := ParseLength(test.In)
+		if err != nil {
+			t.Errorf("ParseLength(%q) unexpected error: %v", test.In, err)
+		}
+		if math.Abs(float64(got-test.Want)) > 0.001 {
+			t.Errorf("ParseLength(%q) got: %v, want: %v", test.In, got, test.Want)
+		}
+	}
+
+	if _, err := ParseLength("2 furlongs"); err == nil {
+		t.Errorf("expected an error for an unknown unit")
+	}
+}
+
+func TestParseLayoutErrors(t *testing.T) {
+	tests := []struct {
+		Name string
+		In   string
+		Want []string
+	}{
+		{
+			Name: "not json",
+			In:   `{"name": `,
+			Want: []string{"unexpected EOF"},
+		},
+		{
+			Name: "unknown field",
+			In:   `{"name": "x", "colums": 2}`,
+			Want: []string{`unknown field "colums"`},
+		},
+		{
+			Name: "bad length",
+			In:   `{"name": "x", "padding": "2 furlongs"}`,
+			Want: []string{`invalid length "2 furlongs"`},
+		},
+		{
+			Name: "empty",
+			In:   `{}`,
+			Want: []string{
+				"name is required",
+				"page width and height must be positive",
+				"columns and rows must be at least 1",
+				"label width and height must be positive",
+				"at least one line is required",
+			},
+		},
+		{
+			Name: "too big for the page",
+			In: `{"name": "x", "page": "a4", "columns": 4, "rows": 8, "label": {"width": "70mm", "height": "40mm"},
+				"font": "Comic Sans", "align": "justify", "lines": [{"text": "{nickname}"}, {"text": "Attn: {address}", "font_size": 100}]}`,
+			Want: []string{
+				"the labels are 11.02in wide with the left margin, wider than the 8.27in page",
+				"the labels are 12.60in tall with the top margin, taller than the 11.69in page",
+				`layout: unknown font "Comic Sans"`,
+				`align must be left, center or right, not "justify"`,
+				"line 1: unknown field {nickname}",
+				"line 2: font_size must be between 6 and 72",
+				"line 2: {address} must be alone on its line",
+			},
+		},
+		{
+			Name: "unbalanced braces",
+			In:   `{"name": "x", "page": "letter", "columns": 1, "rows": 1, "label": {"width": 100, "height": 100}, "padding": 50, "lines": [{"text": "{city"}, {"text": "city}"}]}`,
+			Want: []string{
+				"padding of 0.69in leaves no room on the label",
+				`line 1: unmatched '{' in "{city"`,
+				`line 2: unmatched '}' in "city}"`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := ParseLayout([]byte(test.In))
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("unexpected error, got: %v, want: a *ValidationError", err)
+			}
+			if len(verr.Problems) != len(test.Want) {
+				t.Fatalf("unexpected problems, got: %q, want: %q", verr.Problems, test.Want)
+			}
+			for i, want := range test.Want {
+				if !strings.Contains(verr.Problems[i], want) {
+					t.Errorf("problem %d got: %q, want it to contain: %q", i, verr.Problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteLayout(t *testing.T) {
+	l, err := ParseLayout([]byte(kraftLayout))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cards := []cohabitaters.XmasCard{{
+		Addressee:    "Ned Flanders",
+		Address:      cohabitaters.Address{StreetAddress: "744 Evergreen Terrace"},
+		AddressLines: []string{"744 Evergreen Terrace", "SPRINGFIELD IL 62701"},
+	}}
+
+	var b bytes.Buffer
+	if err := Write(&b, cards, Options{Layout: &l, Skip: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the second label's addressee is centered in bold, and the missing
+	// second street line is left out
+	x := 0.17*pdf.Inch + 4.16*pdf.Inch + (4*pdf.Inch-pdf.TimesBold.Width("Ned Flanders", 14))/2
+	for _, want := range []string{
+		"/F4 14 Tf " + strconv.FormatFloat(math.Round(x*100)/100, 'f', -1, 64) + " ",
+		"(Ned Flanders) Tj",
+		"/F3 12 Tf",
+		"(SPRINGFIELD IL 62701) Tj",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("output is missing %q", want)
+		}
+	}
+	if got := strings.Count(b.String(), " Tj ET"); got != 3 {
+		t.Errorf("unexpected line count, got: %v, want: %v", got, 3)
+	}
+}
+
+func TestExpandFields(t *testing.T) {
+	card := cohabitaters.XmasCard{
+		Names:   []string{"Homer Simpson", "Marge Simpson"},
+		Address: cohabitaters.Address{City: "Springfield", Region: "IL", CountryCode: "US"},
+	}
+	got := expandFields("{addressee} of {city}, {region} ({country})", card)
+	if want := "Homer Simpson & Marge Simpson of Springfield, IL (United States)"; got != want {
+		t.Errorf("expandFields() got: %q, want: %q", got, want)
+	}
+}
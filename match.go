@@ -0,0 +1,249 @@
+package cohabitaters
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/bfallik/cohabitaters/normalize"
+)
+
+const (
+	// DefaultThreshold is the minimum Score.Total for two addresses to merge.
+	DefaultThreshold = 0.85
+	// DefaultReviewMargin is how close to the threshold a score must be to
+	// be flagged for review.
+	DefaultReviewMargin = 0.1
+)
+
+// Score is the similarity of two addresses. Each field is in [0, 1] and is
+// symmetric: comparing a to b gives the same result as b to a.
+type Score struct {
+	Street float64
+	City   float64
+	// Conflict names a field, such as "unit" or "postal code", on which the
+	// addresses definitely differ. A conflict forces Total to 0.
+	Conflict string
+}
+
+// Total combines the field scores: addresses are only as similar as their
+// least similar field.
+func (s Score) Total() float64 {
+	if len(s.Conflict) > 0 {
+		return 0
+	}
+	return min(s.Street, s.City)
+}
+
+func (s Score) String() string {
+	if len(s.Conflict) > 0 {
+		return fmt.Sprintf("%s differs", s.Conflict)
+	}
+	return fmt.Sprintf("street %.2f, city %.2f", s.Street, s.City)
+}
+
+// Matcher decides whether two addresses belong to the same household. The
+// zero value uses DefaultThreshold and DefaultReviewMargin.
+type Matcher struct {
+	Threshold    float64
+	ReviewMargin float64
+}
+
+func (m Matcher) threshold() float64 {
+	if m.Threshold <= 0 {
+		return DefaultThreshold
+	}
+	return m.Threshold
+}
+
+func (m Matcher) reviewMargin() float64 {
+	if m.ReviewMargin <= 0 {
+		return DefaultReviewMargin
+	}
+	return m.ReviewMargin
+}
+
+// Compare scores the similarity of a and b.
+func (m Matcher) Compare(a, b Address) Score {
+	return compare(prepare(a), prepare(b))
+}
+
+// Match reports whether a and b should merge, and whether the decision was
+// close enough to the threshold to need a second look.
+func (m Matcher) Match(a, b Address) (merge, review bool) {
+	return m.decide(m.Compare(a, b))
+}
+
+func (m Matcher) decide(s Score) (merge, review bool) {
+	total := s.Total()
+	merge = total >= m.threshold()
+	review = len(s.Conflict) == 0 && total > 0 && abs(total-m.threshold()) < m.reviewMargin()
+	return merge, review
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
+
+// preparedAddress holds the normalized pieces of an Address that comparisons
+// need, so they're computed once per contact rather than once per pair.
+type preparedAddress struct {
+	houseNumber string
+	street      []string // street tokens after the house number
+	city        []string
+	unit        string
+	postalCode  string
+	region      string
+	country     string
+}
+
+func prepare(a Address) preparedAddress {
+	street, unit := normalize.SplitStreet(a.StreetAddress)
+	p := preparedAddress{
+		street:     strings.Fields(street),
+		city:       strings.Fields(normalize.City(a.City)),
+		unit:       normalize.UnitID(a.StreetAddress2),
+		postalCode: normalize.PostalCode(a.PostalCode),
+		region:     normalize.Region(a.Region),
+		country:    a.countryCode(),
+	}
+	if len(p.unit) == 0 {
+		p.unit = normalize.UnitID(unit)
+	}
+	if len(p.street) > 0 && unicode.IsDigit(rune(p.street[0][0])) {
+		p.houseNumber, p.street = p.street[0], p.street[1:]
+	}
+	return p
+}
+
+// postalCodesConflict reports whether two normalized postal codes can't
+// belong to the same address. A ZIP code is compatible with its ZIP+4.
+func postalCodesConflict(a, b string) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(a) < 5 {
+		return a != b
+	}
+	return !strings.HasPrefix(b, a)
+}
+
+// conflict names the first field on which a and b disagree in a way that
+// distinguishes households sharing a street address: the house number, unit,
+// postal code, region or country. Fields missing from either address are
+// ignored.
+func conflict(a, b preparedAddress) string {
+	present := func(x, y string) bool { return len(x) > 0 && len(y) > 0 }
+
+	switch {
+	case present(a.houseNumber, b.houseNumber) && a.houseNumber != b.houseNumber:
+		return "house number"
+	case present(a.unit, b.unit) && a.unit != b.unit:
+		return "unit"
+	case present(a.postalCode, b.postalCode) && postalCodesConflict(a.postalCode, b.postalCode):
+		return "postal code"
+	case present(a.region, b.region) && a.region != b.region:
+		return "region"
+	case present(a.country, b.country) && a.country != b.country:
+		return "country"
+	}
+	return ""
+}
+
+// compare scores a and b, leaving the field scores at 0 when a conflict
+// already rules the pair out. A street missing from either address scores 0:
+// unlike a missing city it leaves nothing to tell households apart, and an
+// address that is only a city would otherwise match every address there.
+func compare(a, b preparedAddress) Score {
+	if c := conflict(a, b); len(c) > 0 {
+		return Score{Conflict: c}
+	}
+	var street float64
+	if len(a.street) > 0 && len(b.street) > 0 {
+		street = tokenSetRatio(a.street, b.street)
+	}
+	return Score{
+		Street: street,
+		City:   tokenSetRatio(a.city, b.city),
+	}
+}
+
+// tokenSetRatio scores two token lists by comparing their shared tokens
+// against each side's remainder, so word order and extra words on one side
+// matter less than misspellings. An empty side is treated as unknown and
+// scores 1.
+func tokenSetRatio(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 1
+	}
+
+	setA, setB := map[string]bool{}, map[string]bool{}
+	for _, t := range a {
+		setA[t] = true
+	}
+	for _, t := range b {
+		setB[t] = true
+	}
+
+	var inter, onlyA, onlyB []string
+	for t := range setA {
+		if setB[t] {
+			inter = append(inter, t)
+		} else {
+			onlyA = append(onlyA, t)
+		}
+	}
+	for t := range setB {
+		if !setA[t] {
+			onlyB = append(onlyB, t)
+		}
+	}
+	sort.Strings(inter)
+	sort.Strings(onlyA)
+	sort.Strings(onlyB)
+
+	t0 := strings.Join(inter, " ")
+	t1 := strings.TrimSpace(t0 + " " + strings.Join(onlyA, " "))
+	t2 := strings.TrimSpace(t0 + " " + strings.Join(onlyB, " "))
+
+	best := ratio(t1, t2)
+	if len(inter) > 0 {
+		best = max(best, ratio(t0, t1), ratio(t0, t2))
+	}
+	return best
+}
+
+// ratio is the normalized edit similarity of a and b: 1 for identical
+// strings, 0 for strings with nothing in common.
+func ratio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
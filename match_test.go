@@ -0,0 +1,225 @@
+package cohabitaters
+
+import (
+	"testing"
+)
+
+func TestMatcherMatch(t *testing.T) {
+	tests := []struct {
+		Desc string
+		A, B Address
+		Want bool
+	}{
+		{
+			Desc: "street suffix abbreviation",
+			A:    Address{StreetAddress: "123 Main Street", City: "Springfield"},
+			B:    Address{StreetAddress: "123 Main St.", City: "springfield"},
+			Want: true,
+		},
+		{
+			Desc: "accents",
+			A:    Address{StreetAddress: "12 Rue de l'Église", City: "Montréal"},
+			B:    Address{StreetAddress: "12 rue de l'Eglise", City: "Montreal"},
+			Want: true,
+		},
+		{
+			Desc: "saint",
+			A:    Address{StreetAddress: "9 Elm Street", City: "Saint Paul"},
+			B:    Address{StreetAddress: "9 Elm St", City: "St. Paul"},
+			Want: true,
+		},
+		{
+			Desc: "different apartments",
+			A:    Address{StreetAddress: "10 Elm St", StreetAddress2: "Apt 4", City: "Springfield"},
+			B:    Address{StreetAddress: "10 Elm Street", StreetAddress2: "Apt 5", City: "Springfield"},
+		},
+		{
+			Desc: "same apartment written differently",
+			A:    Address{StreetAddress: "10 Elm St", StreetAddress2: "Apt 4", City: "Springfield"},
+			B:    Address{StreetAddress: "10 Elm Street #4", City: "Springfield"},
+			Want: true,
+		},
+		{
+			Desc: "unit missing on one side",
+			A:    Address{StreetAddress: "10 Elm St", StreetAddress2: "Apt 4", City: "Springfield"},
+			B:    Address{StreetAddress: "10 Elm Street", City: "Springfield"},
+			Want: true,
+		},
+		{
+			Desc: "care of line is not a unit",
+			A:    Address{StreetAddress: "10 Elm St", StreetAddress2: "c/o Jane Doe", City: "Springfield"},
+			B:    Address{StreetAddress: "10 Elm Street", StreetAddress2: "Apt 5", City: "Springfield"},
+			Want: true,
+		},
+		{
+			Desc: "different postal codes",
+			A:    Address{StreetAddress: "10 Elm St", City: "Springfield", PostalCode: "62701"},
+			B:    Address{StreetAddress: "10 Elm St", City: "Springfield", PostalCode: "62702"},
+		},
+		{
+			Desc: "zip and zip+4",
+			A:    Address{StreetAddress: "10 Elm St", City: "Springfield", PostalCode: "62701"},
+			B:    Address{StreetAddress: "10 Elm St", City: "Springfield", PostalCode: "62701-1234"},
+			Want: true,
+		},
+		{
+			Desc: "different states",
+			A:    Address{StreetAddress: "10 Elm St", City: "Springfield", Region: "IL"},
+			B:    Address{StreetAddress: "10 Elm St", City: "Springfield", Region: "Massachusetts"},
+		},
+		{
+			Desc: "same state spelled out",
+			A:    Address{StreetAddress: "10 Elm St", City: "Springfield", Region: "IL"},
+			B:    Address{StreetAddress: "10 Elm St", City: "Springfield", Region: "Illinois"},
+			Want: true,
+		},
+		{
+			Desc: "same state abbreviated",
+			A:    Address{StreetAddress: "10 Elm St", City: "Springfield", Region: "IL"},
+			B:    Address{StreetAddress: "10 Elm St", City: "Springfield", Region: "Ill."},
+			Want: true,
+		},
+		{
+			Desc: "different countries",
+			A:    Address{StreetAddress: "10 High Street", City: "Richmond", Country: "United Kingdom"},
+			B:    Address{StreetAddress: "10 High Street", City: "Richmond", CountryCode: "US"},
+		},
+		{
+			Desc: "country name and code",
+			A:    Address{StreetAddress: "10 High Street", City: "Richmond", Country: "USA"},
+			B:    Address{StreetAddress: "10 High Street", City: "Richmond", CountryCode: "us"},
+			Want: true,
+		},
+		{
+			Desc: "partial street name",
+			A:    Address{StreetAddress: "123 Main", City: "Springfield"},
+			B:    Address{StreetAddress: "123 Main Street", City: "Springfield"},
+			Want: true,
+		},
+		{
+			Desc: "typo",
+			A:    Address{StreetAddress: "742 Evergreen Terrace", City: "Springfield"},
+			B:    Address{StreetAddress: "742 Evergren Terrace", City: "Springfield"},
+			Want: true,
+		},
+		{
+			Desc: "different house numbers",
+			A:    Address{StreetAddress: "740 Evergreen Terrace", City: "Springfield"},
+			B:    Address{StreetAddress: "742 Evergreen Terrace", City: "Springfield"},
+		},
+		{
+			Desc: "street missing on one side",
+			A:    Address{City: "Boston", Region: "MA"},
+			B:    Address{StreetAddress: "123 Main St", City: "Boston", Region: "MA"},
+		},
+		{
+			Desc: "street missing on both sides",
+			A:    Address{City: "Boston"},
+			B:    Address{City: "Boston"},
+		},
+		{
+			Desc: "different street",
+			A:    Address{StreetAddress: "123 Main Street", City: "Springfield"},
+			B:    Address{StreetAddress: "9 Elm Street", City: "Springfield"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			var m Matcher
+			if got, _ := m.Match(test.A, test.B); got != test.Want {
+				t.Errorf("Match(a, b) = %v, want %v", got, test.Want)
+			}
+			if got, _ := m.Match(test.B, test.A); got != test.Want {
+				t.Errorf("Match(b, a) = %v, want %v", got, test.Want)
+			}
+		})
+	}
+}
+
+func TestMatcherThreshold(t *testing.T) {
+	a := Address{StreetAddress: "1 Brookhaven", City: "Springfield"}
+	b := Address{StreetAddress: "1 Brookhoven", City: "Springfield"} // street scores 0.9
+
+	tests := []struct {
+		Desc       string
+		Matcher    Matcher
+		WantMerge  bool
+		WantReview bool
+	}{
+		{Desc: "default", WantMerge: true, WantReview: true},
+		{Desc: "strict", Matcher: Matcher{Threshold: 0.95}, WantReview: true},
+		{Desc: "strict narrow margin", Matcher: Matcher{Threshold: 0.95, ReviewMargin: 0.01}},
+		{Desc: "lenient", Matcher: Matcher{Threshold: 0.5}, WantMerge: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			merge, review := test.Matcher.Match(a, b)
+			if merge != test.WantMerge || review != test.WantReview {
+				t.Errorf("Match() = (%v, %v), want (%v, %v)", merge, review, test.WantMerge, test.WantReview)
+			}
+		})
+	}
+}
+
+func TestCompareSymmetric(t *testing.T) {
+	addrs := []Address{
+		{StreetAddress: "Main", City: "Springfield"},
+		{StreetAddress: "123 Main Street", City: "Springfield"},
+		{StreetAddress: "123 Main St Apt 4", City: "Springfield", PostalCode: "62701"},
+		{StreetAddress: "Evergreen Terrace", City: "Springfeld"},
+		{StreetAddress: "742 Evergreen Terr.", City: "Springfield", Country: "USA"},
+	}
+
+	var m Matcher
+	for _, a := range addrs {
+		for _, b := range addrs {
+			if ab, ba := m.Compare(a, b), m.Compare(b, a); ab != ba {
+				t.Errorf("Compare(%v, %v) = %v, but reversed = %v", a, b, ab, ba)
+			}
+		}
+	}
+}
+
+func Test_tokenSetRatio(t *testing.T) {
+	tests := []struct {
+		A, B []string
+		Want float64
+	}{
+		{A: nil, B: []string{"MAIN"}, Want: 1},
+		{A: []string{"MAIN", "ST"}, B: []string{"MAIN", "ST"}, Want: 1},
+		{A: []string{"MAIN"}, B: []string{"MAIN", "ST"}, Want: 1},
+		{A: []string{"ST", "MAIN"}, B: []string{"MAIN", "ST"}, Want: 1},
+		{A: []string{"BROOKHAVEN"}, B: []string{"BROOKHOVEN"}, Want: 0.9},
+		{A: []string{"ABC"}, B: []string{"XYZ"}, Want: 0},
+	}
+
+	for _, test := range tests {
+		if got := tokenSetRatio(test.A, test.B); got != test.Want {
+			t.Errorf("tokenSetRatio(%v, %v) = %v, want %v", test.A, test.B, got, test.Want)
+		}
+		if got := tokenSetRatio(test.B, test.A); got != test.Want {
+			t.Errorf("tokenSetRatio(%v, %v) = %v, want %v", test.B, test.A, got, test.Want)
+		}
+	}
+}
+
+func Test_levenshtein(t *testing.T) {
+	tests := []struct {
+		A, B string
+		Want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"zürich", "zurich", 1},
+	}
+
+	for _, test := range tests {
+		if got := levenshtein([]rune(test.A), []rune(test.B)); got != test.Want {
+			t.Errorf("levenshtein(%q, %q) = %v, want %v", test.A, test.B, got, test.Want)
+		}
+	}
+}
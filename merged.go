@@ -0,0 +1,247 @@
+package cohabitaters
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Account is a ContactSource along with the name of the account it reads,
+// e.g. an email address.
+type Account struct {
+	Name   string
+	Source ContactSource
+}
+
+// accountPrefix starts the resource names MergedSource gives the contacts
+// and groups of every account but the first.
+const accountPrefix = "accounts/"
+
+// MergedSource reads several accounts as a single address book, so a
+// household kept partly in each still ends up on one card.
+//
+// Groups with the same name are one group, whose members are drawn from
+// every account. The first account's resource names are passed through
+// unchanged; those of the others are qualified with the account name. Each
+// contact's Account records where it came from, unless the account is itself
+// merged and already did. An account other than the
+// first whose groups can't be listed, e.g. because access to it was revoked,
+// is left out rather than failing every read.
+type MergedSource struct {
+	accounts []Account
+
+	mu     sync.Mutex
+	groups []mergedGroup
+}
+
+type mergedGroup struct {
+	ContactGroup
+	// parts are the resource names of the group in each account, empty
+	// where the account doesn't have it.
+	parts []string
+}
+
+var _ AllContactsSource = (*MergedSource)(nil)
+
+func NewMergedSource(accounts ...Account) *MergedSource {
+	return &MergedSource{accounts: accounts}
+}
+
+// QualifyResourceName returns the resource name MergedSource uses for a
+// contact or group of the i-th account.
+func QualifyResourceName(accounts []string, i int, resourceName string) string {
+	if i == 0 {
+		return resourceName
+	}
+	return accountPrefix + accounts[i] + "/" + resourceName
+}
+
+// MergeContactGroups combines the contact groups of several accounts by name,
+// in the order each name is first seen. A group's MemberCount is the sum
+// over the accounts, so it counts a contact kept in two accounts twice.
+func MergeContactGroups(accounts []string, groups [][]ContactGroup) []ContactGroup {
+	merged := mergeGroups(accounts, groups)
+	out := make([]ContactGroup, len(merged))
+	for i, g := range merged {
+		out[i] = g.ContactGroup
+	}
+	return out
+}
+
+func mergeGroups(accounts []string, groups [][]ContactGroup) []mergedGroup {
+	var out []mergedGroup
+	byName := map[string]int{}
+	for i, gs := range groups {
+		for _, g := range gs {
+			idx, ok := byName[g.Name]
+			if !ok {
+				idx = len(out)
+				byName[g.Name] = idx
+				mg := mergedGroup{ContactGroup: g, parts: make([]string, len(accounts))}
+				mg.ResourceName = QualifyResourceName(accounts, i, g.ResourceName)
+				mg.MemberCount = 0
+				out = append(out, mg)
+			}
+			out[idx].parts[i] = g.ResourceName
+			out[idx].MemberCount += g.MemberCount
+		}
+	}
+	return out
+}
+
+func (s *MergedSource) names() []string {
+	names := make([]string, len(s.accounts))
+	for i, a := range s.accounts {
+		names[i] = a.Name
+	}
+	return names
+}
+
+// split returns the account a qualified resource name belongs to along with
+// the account's own resource name.
+func (s *MergedSource) split(resourceName string) (int, string) {
+	if rest, ok := strings.CutPrefix(resourceName, accountPrefix); ok {
+		for i, a := range s.accounts[1:] {
+			if rn, ok := strings.CutPrefix(rest, a.Name+"/"); ok {
+				return i + 1, rn
+			}
+		}
+	}
+	return 0, resourceName
+}
+
+// contactGroups lists and merges the groups of every account, once. Only
+// the first account's groups must be listed; the others have no groups, and
+// so no members, when they can't be.
+func (s *MergedSource) contactGroups(ctx context.Context) ([]mergedGroup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.groups != nil {
+		return s.groups, nil
+	}
+
+	groups := make([][]ContactGroup, len(s.accounts))
+	for i, a := range s.accounts {
+		gs, err := a.Source.ContactGroups(ctx)
+		if err != nil && i == 0 {
+			return nil, fmt.Errorf("unable to list the contact groups of %s: %w", a.Name, err)
+		}
+		if err != nil {
+			continue
+		}
+		groups[i] = gs
+	}
+	s.groups = mergeGroups(s.names(), groups)
+	return s.groups, nil
+}
+
+func (s *MergedSource) ContactGroups(ctx context.Context) ([]ContactGroup, error) {
+	merged, err := s.contactGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ContactGroup, len(merged))
+	for i, g := range merged {
+		out[i] = g.ContactGroup
+	}
+	return out, nil
+}
+
+// AllContactsGroup returns the first account's group of all contacts, which
+// like any other group takes in the groups of the same name in the others.
+func (s *MergedSource) AllContactsGroup() string {
+	if len(s.accounts) == 0 {
+		return ""
+	}
+	if all, ok := s.accounts[0].Source.(AllContactsSource); ok {
+		return all.AllContactsGroup()
+	}
+	return ""
+}
+
+// GroupMembers returns the members of the group with the same name in every
+// account.
+func (s *MergedSource) GroupMembers(ctx context.Context, groupResourceName string) ([]string, error) {
+	merged, err := s.contactGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := s.names()
+	for _, g := range merged {
+		if g.ResourceName != groupResourceName {
+			continue
+		}
+		var members []string
+		for i, part := range g.parts {
+			if len(part) == 0 {
+				continue
+			}
+			rns, err := s.accounts[i].Source.GroupMembers(ctx, part)
+			if err != nil {
+				return nil, fmt.Errorf("unable to list the members of %s in %s: %w", g.Name, s.accounts[i].Name, err)
+			}
+			for _, rn := range rns {
+				members = append(members, QualifyResourceName(names, i, rn))
+			}
+		}
+		return members, nil
+	}
+	return nil, fmt.Errorf("unknown contact group %q", groupResourceName)
+}
+
+// Contacts fetches each contact from its own account. Memberships are
+// rewritten to the merged groups, so contacts in groups of the same name
+// share a group whichever account they came from.
+func (s *MergedSource) Contacts(ctx context.Context, resourceNames []string) ([]Contact, error) {
+	merged, err := s.contactGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := s.names()
+	groupOf := make([]map[string]string, len(s.accounts)) // account's group -> merged group
+	for i := range groupOf {
+		groupOf[i] = map[string]string{}
+	}
+	for _, g := range merged {
+		for i, part := range g.parts {
+			if len(part) > 0 {
+				groupOf[i][part] = g.ResourceName
+			}
+		}
+	}
+
+	byAccount := make([][]string, len(s.accounts))
+	for _, rn := range resourceNames {
+		i, own := s.split(rn)
+		byAccount[i] = append(byAccount[i], own)
+	}
+
+	var out []Contact
+	for i, rns := range byAccount {
+		if len(rns) == 0 {
+			continue
+		}
+		contacts, err := s.accounts[i].Source.Contacts(ctx, rns)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve people from %s: %w", s.accounts[i].Name, err)
+		}
+		for _, c := range contacts {
+			c.ResourceName = QualifyResourceName(names, i, c.ResourceName)
+			if len(c.Account) == 0 {
+				c.Account = s.accounts[i].Name
+			}
+			var memberships []string
+			for _, g := range c.Memberships {
+				if mg, ok := groupOf[i][g]; ok {
+					memberships = append(memberships, mg)
+				} else {
+					memberships = append(memberships, QualifyResourceName(names, i, g))
+				}
+			}
+			c.Memberships = memberships
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,145 @@
+package cohabitaters
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergedSource(t *testing.T) {
+	ctx := context.Background()
+	homers := fakeSource{
+		groups: map[string][]string{"Xmas Card": {"people/1", "people/2"}},
+		people: map[string]Contact{
+			"people/1": homeContact("people/1", "Homer Simpson", mainStreet),
+			"people/2": homeContact("people/2", "Marge Simpson", mainStreet),
+		},
+	}
+	marges := fakeSource{
+		groups: map[string][]string{"Xmas Card": {"people/1", "people/3"}, "Church": {"people/3"}},
+		people: map[string]Contact{
+			"people/1": homeContact("people/1", "Marge Simpson", mainStreet),
+			"people/3": homeContact("people/3", "Ned Flanders", elmStreet),
+		},
+	}
+	ned := marges.people["people/3"]
+	ned.Memberships = []string{"Xmas Card", "Church"}
+	marges.people["people/3"] = ned
+	src := NewMergedSource(Account{Name: "homer@example.com", Source: homers}, Account{Name: "marge@example.com", Source: marges})
+
+	groups, err := src.ContactGroups(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantGroups := map[string]ContactGroup{
+		"Xmas Card": {ResourceName: "Xmas Card", Name: "Xmas Card", MemberCount: 4},
+		"Church":    {ResourceName: "accounts/marge@example.com/Church", Name: "Church", MemberCount: 1},
+	}
+	gotGroups := map[string]ContactGroup{}
+	for _, g := range groups {
+		gotGroups[g.Name] = g
+	}
+	if diff := cmp.Diff(wantGroups, gotGroups); diff != "" {
+		t.Errorf("ContactGroups() mismatch (-want +got):\n%s", diff)
+	}
+
+	res, err := GetXmasCards(ctx, src, "Xmas Card", Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	type contact struct{ ResourceName, Account string }
+	var got [][]contact
+	for _, card := range res.Cards {
+		var cs []contact
+		for _, c := range card.Contacts {
+			cs = append(cs, contact{c.ResourceName, c.Account})
+		}
+		got = append(got, cs)
+	}
+	want := [][]contact{
+		{
+			{"accounts/marge@example.com/people/1", "marge@example.com"},
+			{"people/1", "homer@example.com"},
+			{"people/2", "homer@example.com"},
+		},
+		{{"accounts/marge@example.com/people/3", "marge@example.com"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetXmasCards() contacts mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"Marge Simpson", "Homer Simpson"}, res.Cards[0].Names); diff != "" {
+		t.Errorf("Names mismatch (-want +got):\n%s", diff)
+	}
+
+	contacts, err := src.Contacts(ctx, []string{"accounts/marge@example.com/people/3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(contacts) != 1 {
+		t.Fatalf("unexpected contact count, got: %v, want: 1", len(contacts))
+	}
+	if diff := cmp.Diff([]string{"Xmas Card", "accounts/marge@example.com/Church"}, contacts[0].Memberships); diff != "" {
+		t.Errorf("Memberships mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := src.GroupMembers(ctx, "contactGroups/unknown"); err == nil {
+		t.Errorf("missing expected error")
+	}
+}
+
+func TestMergedSourceUnreadable(t *testing.T) {
+	ctx := context.Background()
+	homers := fakeSource{
+		groups: map[string][]string{"Xmas Card": {"people/1"}},
+		people: map[string]Contact{"people/1": homeContact("people/1", "Homer Simpson", mainStreet)},
+	}
+	revoked := fakeSource{listErr: errors.New("token revoked")}
+
+	res, err := GetXmasCards(ctx, NewMergedSource(Account{Name: "homer@example.com", Source: homers}, Account{Name: "marge@example.com", Source: revoked}), "Xmas Card", Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Cards) != 1 {
+		t.Errorf("unexpected card count, got: %v, want: 1", len(res.Cards))
+	}
+
+	src := NewMergedSource(Account{Name: "marge@example.com", Source: revoked}, Account{Name: "homer@example.com", Source: homers})
+	if _, err := src.ContactGroups(ctx); err == nil {
+		t.Errorf("missing expected error for the first account")
+	}
+}
+
+func TestMergedSourceNested(t *testing.T) {
+	ctx := context.Background()
+	homers := fakeSource{
+		groups: map[string][]string{"Xmas Card": {"people/1"}},
+		people: map[string]Contact{"people/1": homeContact("people/1", "Homer Simpson", mainStreet)},
+	}
+	marges := fakeSource{
+		groups: map[string][]string{"Xmas Card": {"people/2"}},
+		people: map[string]Contact{"people/2": homeContact("people/2", "Marge Simpson", mainStreet)},
+	}
+	imported := fakeSource{
+		groups: map[string][]string{"Xmas Card": {"card-1"}},
+		people: map[string]Contact{"card-1": homeContact("card-1", "Ned Flanders", elmStreet)},
+	}
+	google := NewMergedSource(Account{Name: "homer@example.com", Source: homers}, Account{Name: "marge@example.com", Source: marges})
+	src := NewMergedSource(Account{Name: "Google", Source: google}, Account{Name: "contacts.vcf", Source: imported})
+
+	res, err := GetXmasCards(ctx, src, "Xmas Card", Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := map[string]string{}
+	for _, card := range res.Cards {
+		for _, c := range card.Contacts {
+			got[c.Name] = c.Account
+		}
+	}
+	want := map[string]string{"Homer Simpson": "homer@example.com", "Marge Simpson": "marge@example.com", "Ned Flanders": "contacts.vcf"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Account mismatch (-want +got):\n%s", diff)
+	}
+}
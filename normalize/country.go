@@ -0,0 +1,142 @@
+package normalize
+
+import "strings"
+
+// countryNames maps ISO 3166-1 alpha-2 codes to English short names.
+var countryNames = map[string]string{
+	"AD": "Andorra", "AE": "United Arab Emirates", "AF": "Afghanistan", "AG": "Antigua and Barbuda",
+	"AI": "Anguilla", "AL": "Albania", "AM": "Armenia", "AO": "Angola", "AQ": "Antarctica",
+	"AR": "Argentina", "AS": "American Samoa", "AT": "Austria", "AU": "Australia", "AW": "Aruba",
+	"AX": "Åland Islands", "AZ": "Azerbaijan", "BA": "Bosnia and Herzegovina", "BB": "Barbados",
+	"BD": "Bangladesh", "BE": "Belgium", "BF": "Burkina Faso", "BG": "Bulgaria", "BH": "Bahrain",
+	"BI": "Burundi", "BJ": "Benin", "BL": "Saint Barthélemy", "BM": "Bermuda", "BN": "Brunei",
+	"BO": "Bolivia", "BQ": "Caribbean Netherlands", "BR": "Brazil", "BS": "Bahamas", "BT": "Bhutan",
+	"BV": "Bouvet Island", "BW": "Botswana", "BY": "Belarus", "BZ": "Belize", "CA": "Canada",
+	"CC": "Cocos (Keeling) Islands", "CD": "Congo (DRC)", "CF": "Central African Republic",
+	"CG": "Congo (Republic)", "CH": "Switzerland", "CI": "Côte d'Ivoire", "CK": "Cook Islands",
+	"CL": "Chile", "CM": "Cameroon", "CN": "China", "CO": "Colombia", "CR": "Costa Rica", "CU": "Cuba",
+	"CV": "Cape Verde", "CW": "Curaçao", "CX": "Christmas Island", "CY": "Cyprus", "CZ": "Czechia",
+	"DE": "Germany", "DJ": "Djibouti", "DK": "Denmark", "DM": "Dominica", "DO": "Dominican Republic",
+	"DZ": "Algeria", "EC": "Ecuador", "EE": "Estonia", "EG": "Egypt", "EH": "Western Sahara",
+	"ER": "Eritrea", "ES": "Spain", "ET": "Ethiopia", "FI": "Finland", "FJ": "Fiji",
+	"FK": "Falkland Islands", "FM": "Micronesia", "FO": "Faroe Islands", "FR": "France", "GA": "Gabon",
+	"GB": "United Kingdom", "GD": "Grenada", "GE": "Georgia", "GF": "French Guiana", "GG": "Guernsey",
+	"GH": "Ghana", "GI": "Gibraltar", "GL": "Greenland", "GM": "Gambia", "GN": "Guinea",
+	"GP": "Guadeloupe", "GQ": "Equatorial Guinea", "GR": "Greece", "GS": "South Georgia",
+	"GT": "Guatemala", "GU": "Guam", "GW": "Guinea-Bissau", "GY": "Guyana", "HK": "Hong Kong",
+	"HM": "Heard and McDonald Islands", "HN": "Honduras", "HR": "Croatia", "HT": "Haiti",
+	"HU": "Hungary", "ID": "Indonesia", "IE": "Ireland", "IL": "Israel", "IM": "Isle of Man",
+	"IN": "India", "IO": "British Indian Ocean Territory", "IQ": "Iraq", "IR": "Iran", "IS": "Iceland",
+	"IT": "Italy", "JE": "Jersey", "JM": "Jamaica", "JO": "Jordan", "JP": "Japan", "KE": "Kenya",
+	"KG": "Kyrgyzstan", "KH": "Cambodia", "KI": "Kiribati", "KM": "Comoros", "KN": "Saint Kitts and Nevis",
+	"KP": "North Korea", "KR": "South Korea", "KW": "Kuwait", "KY": "Cayman Islands", "KZ": "Kazakhstan",
+	"LA": "Laos", "LB": "Lebanon", "LC": "Saint Lucia", "LI": "Liechtenstein", "LK": "Sri Lanka",
+	"LR": "Liberia", "LS": "Lesotho", "LT": "Lithuania", "LU": "Luxembourg", "LV": "Latvia",
+	"LY": "Libya", "MA": "Morocco", "MC": "Monaco", "MD": "Moldova", "ME": "Montenegro",
+	"MF": "Saint Martin", "MG": "Madagascar", "MH": "Marshall Islands", "MK": "North Macedonia",
+	"ML": "Mali", "MM": "Myanmar", "MN": "Mongolia", "MO": "Macao", "MP": "Northern Mariana Islands",
+	"MQ": "Martinique", "MR": "Mauritania", "MS": "Montserrat", "MT": "Malta", "MU": "Mauritius",
+	"MV": "Maldives", "MW": "Malawi", "MX": "Mexico", "MY": "Malaysia", "MZ": "Mozambique",
+	"NA": "Namibia", "NC": "New Caledonia", "NE": "Niger", "NF": "Norfolk Island", "NG": "Nigeria",
+	"NI": "Nicaragua", "NL": "Netherlands", "NO": "Norway", "NP": "Nepal", "NR": "Nauru", "NU": "Niue",
+	"NZ": "New Zealand", "OM": "Oman", "PA": "Panama", "PE": "Peru", "PF": "French Polynesia",
+	"PG": "Papua New Guinea", "PH": "Philippines", "PK": "Pakistan", "PL": "Poland",
+	"PM": "Saint Pierre and Miquelon", "PN": "Pitcairn Islands", "PR": "Puerto Rico", "PS": "Palestine",
+	"PT": "Portugal", "PW": "Palau", "PY": "Paraguay", "QA": "Qatar", "RE": "Réunion", "RO": "Romania",
+	"RS": "Serbia", "RU": "Russia", "RW": "Rwanda", "SA": "Saudi Arabia", "SB": "Solomon Islands",
+	"SC": "Seychelles", "SD": "Sudan", "SE": "Sweden", "SG": "Singapore", "SH": "Saint Helena",
+	"SI": "Slovenia", "SJ": "Svalbard and Jan Mayen", "SK": "Slovakia", "SL": "Sierra Leone",
+	"SM": "San Marino", "SN": "Senegal", "SO": "Somalia", "SR": "Suriname", "SS": "South Sudan",
+	"ST": "São Tomé and Príncipe", "SV": "El Salvador", "SX": "Sint Maarten", "SY": "Syria",
+	"SZ": "Eswatini", "TC": "Turks and Caicos Islands", "TD": "Chad", "TF": "French Southern Territories",
+	"TG": "Togo", "TH": "Thailand", "TJ": "Tajikistan", "TK": "Tokelau", "TL": "Timor-Leste",
+	"TM": "Turkmenistan", "TN": "Tunisia", "TO": "Tonga", "TR": "Türkiye", "TT": "Trinidad and Tobago",
+	"TV": "Tuvalu", "TW": "Taiwan", "TZ": "Tanzania", "UA": "Ukraine", "UG": "Uganda",
+	"UM": "U.S. Outlying Islands", "US": "United States", "UY": "Uruguay", "UZ": "Uzbekistan",
+	"VA": "Vatican City", "VC": "Saint Vincent and the Grenadines", "VE": "Venezuela",
+	"VG": "British Virgin Islands", "VI": "U.S. Virgin Islands", "VN": "Vietnam", "VU": "Vanuatu",
+	"WF": "Wallis and Futuna", "WS": "Samoa", "XK": "Kosovo", "YE": "Yemen", "YT": "Mayotte",
+	"ZA": "South Africa", "ZM": "Zambia", "ZW": "Zimbabwe",
+}
+
+// countryAliases maps other common spellings, in Text form, to alpha-2 codes.
+var countryAliases = map[string]string{
+	"USA":                      "US",
+	"UNITED STATES OF AMERICA": "US",
+	"AMERICA":                  "US",
+	"UK":                       "GB",
+	"GREAT BRITAIN":            "GB",
+	"BRITAIN":                  "GB",
+	"ENGLAND":                  "GB",
+	"SCOTLAND":                 "GB",
+	"WALES":                    "GB",
+	"NORTHERN IRELAND":         "GB",
+	"UNITED KINGDOM OF GREAT BRITAIN AND NORTHERN IRELAND": "GB",
+	"DEUTSCHLAND":               "DE",
+	"ESPANA":                    "ES",
+	"ITALIA":                    "IT",
+	"NIPPON":                    "JP",
+	"NIHON":                     "JP",
+	"SCHWEIZ":                   "CH",
+	"SUISSE":                    "CH",
+	"SVIZZERA":                  "CH",
+	"OSTERREICH":                "AT",
+	"NEDERLAND":                 "NL",
+	"HOLLAND":                   "NL",
+	"THE NETHERLANDS":           "NL",
+	"BELGIQUE":                  "BE",
+	"BELGIE":                    "BE",
+	"CZECH REPUBLIC":            "CZ",
+	"REPUBLIC OF KOREA":         "KR",
+	"KOREA":                     "KR",
+	"PEOPLES REPUBLIC OF CHINA": "CN",
+	"PRC":                       "CN",
+	"RUSSIAN FEDERATION":        "RU",
+	"TURKEY":                    "TR",
+	"IVORY COAST":               "CI",
+	"SWAZILAND":                 "SZ",
+	"MACEDONIA":                 "MK",
+	"BURMA":                     "MM",
+	"HOLY SEE":                  "VA",
+	"UAE":                       "AE",
+	"BRASIL":                    "BR",
+	"EIRE":                      "IE",
+	"SVERIGE":                   "SE",
+	"NORGE":                     "NO",
+	"DANMARK":                   "DK",
+	"SUOMI":                     "FI",
+	"POLSKA":                    "PL",
+}
+
+var countryCodes = func() map[string]string {
+	m := make(map[string]string, len(countryNames)+len(countryAliases))
+	for code, name := range countryNames {
+		m[Text(name)] = code
+	}
+	for alias, code := range countryAliases {
+		m[alias] = code
+	}
+	return m
+}()
+
+// CountryCode returns the ISO 3166-1 alpha-2 code for a country name or code
+// such as "United States", "U.S.A." or "us", or "" if s is not recognized.
+func CountryCode(s string) string {
+	t := Text(s)
+	if _, ok := countryNames[t]; ok {
+		return t
+	}
+	if code, ok := countryCodes[t]; ok {
+		return code
+	}
+	if code, ok := countryCodes[strings.ReplaceAll(t, " ", "")]; ok {
+		return code
+	}
+	return ""
+}
+
+// CountryName returns the English name for an ISO 3166-1 alpha-2 code, or ""
+// if the code is not recognized.
+func CountryName(code string) string {
+	return countryNames[strings.ToUpper(code)]
+}
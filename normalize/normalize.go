@@ -0,0 +1,254 @@
+// Package normalize canonicalizes postal address text so that equivalent
+// spellings ("123 Main Street" and "123 main st.") compare equal.
+//
+// The canonical form follows USPS Publication 28 conventions: uppercase,
+// no punctuation, standard street suffix, directional and secondary unit
+// abbreviations.
+package normalize
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// foldRunes maps letters that do not decompose into a base letter and a
+// combining mark.
+var foldRunes = map[rune]string{
+	'ß': "SS", 'ẞ': "SS",
+	'æ': "AE", 'Æ': "AE",
+	'œ': "OE", 'Œ': "OE",
+	'ø': "O", 'Ø': "O",
+	'ł': "L", 'Ł': "L",
+	'đ': "D", 'Đ': "D",
+	'ð': "D", 'Ð': "D",
+	'þ': "TH", 'Þ': "TH",
+	'ı': "I",
+}
+
+// Fold strips accents and uppercases s, e.g. "Zürich" becomes "ZURICH".
+func Fold(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if f, ok := foldRunes[r]; ok {
+			b.WriteString(f)
+			continue
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// tokens folds s and splits it into words. Periods and apostrophes are
+// dropped so abbreviations stay intact ("St." and "O'Neil" become "ST" and
+// "ONEIL"), a '#' is always its own token and any other punctuation
+// separates words.
+func tokens(s string) []string {
+	var b strings.Builder
+	for _, r := range Fold(s) {
+		switch {
+		case r == '.' || r == '\'' || r == '’':
+		case r == '#':
+			b.WriteString(" # ")
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case r == '/' || r == '-':
+			b.WriteRune(r) // keep fractions and hyphenated house numbers
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	var out []string
+	for _, f := range strings.Fields(b.String()) {
+		if f = strings.Trim(f, "/-"); len(f) > 0 {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Text folds case and accents, removes punctuation and collapses whitespace.
+func Text(s string) string {
+	return strings.Join(tokens(s), " ")
+}
+
+// Street canonicalizes a street address line. Street suffixes, directionals
+// and secondary unit designators are replaced by their standard
+// abbreviations, e.g. "123 North Main Street, Apartment 4" becomes
+// "123 N MAIN ST APT 4".
+func Street(s string) string {
+	street, unit := SplitStreet(s)
+	if len(unit) == 0 {
+		return street
+	}
+	return strings.TrimSpace(street + " " + unit)
+}
+
+// SplitStreet canonicalizes a street address line like Street but returns
+// any trailing secondary unit ("APT 4", "#4") separately from the street.
+func SplitStreet(s string) (street, unit string) {
+	toks := tokens(s)
+	var out, unitOut []string
+
+	for i := 0; i < len(toks); i++ {
+		tok := toks[i]
+
+		if i > 0 && startsUnit(toks, i) || tok == "#" && i+1 < len(toks) {
+			unitOut = unitTokens(toks[i:])
+			break
+		}
+
+		if abbr, ok := directionals[tok]; ok && !isStreetName(toks, i) {
+			out = append(out, abbr)
+			continue
+		}
+
+		if abbr, ok := streetSuffixes[tok]; ok && isSuffixPosition(toks, i) {
+			out = append(out, abbr)
+			continue
+		}
+
+		out = append(out, tok)
+	}
+
+	return strings.Join(out, " "), strings.Join(unitOut, " ")
+}
+
+// unitTokens canonicalizes the secondary unit at the start of toks:
+// "APARTMENT # 4" becomes "APT 4" and "# 4" becomes "#4".
+func unitTokens(toks []string) []string {
+	var out []string
+	for i := 0; i < len(toks); i++ {
+		switch tok := toks[i]; {
+		case startsUnit(toks, i):
+			out = append(out, unitDesignators[tok])
+			if i+1 < len(toks) && toks[i+1] == "#" {
+				i++ // "Apt #4" is just "APT 4"
+			}
+		case tok == "#" && i+1 < len(toks):
+			out = append(out, "#"+toks[i+1])
+			i++
+		default:
+			out = append(out, tok)
+		}
+	}
+	return out
+}
+
+// startsUnit reports whether toks[i] introduces a secondary unit, i.e. it is
+// a designator followed by something that looks like a unit identifier.
+func startsUnit(toks []string, i int) bool {
+	if _, ok := unitDesignators[toks[i]]; !ok || i+1 >= len(toks) {
+		return false
+	}
+	return isUnitID(toks[i+1])
+}
+
+func isUnitID(tok string) bool {
+	if tok == "#" || len(tok) == 1 {
+		return true
+	}
+	return strings.IndexFunc(tok, unicode.IsDigit) >= 0
+}
+
+// isStreetName reports whether the directional at toks[i] is the name of the
+// street itself, as in "North Street", rather than a qualifier.
+func isStreetName(toks []string, i int) bool {
+	if i+1 >= len(toks) {
+		return i == 0 || isHouseNumber(toks[i-1]) // "North" on its own
+	}
+	if _, ok := streetSuffixes[toks[i+1]]; !ok {
+		return false
+	}
+	return i == 0 || isHouseNumber(toks[i-1])
+}
+
+// isSuffixPosition reports whether toks[i] ends the street name: it is
+// followed by nothing, a directional or a unit designator, and is preceded by
+// at least one other word.
+func isSuffixPosition(toks []string, i int) bool {
+	if i == 0 || isHouseNumber(toks[i-1]) && i == 1 {
+		return false
+	}
+	if i+1 == len(toks) {
+		return true
+	}
+	if _, ok := directionals[toks[i+1]]; ok {
+		return true
+	}
+	return toks[i+1] == "#" || startsUnit(toks, i+1)
+}
+
+func isHouseNumber(tok string) bool {
+	return len(tok) > 0 && unicode.IsDigit(rune(tok[0]))
+}
+
+// Secondary canonicalizes a secondary address line, e.g. "Apartment #4"
+// becomes "APT 4" and "# 4" becomes "#4".
+func Secondary(s string) string {
+	return strings.Join(unitTokens(tokens(s)), " ")
+}
+
+// Unit splits a secondary address line such as "Apt. 4B" or "#4B" into the
+// standard designator ("APT", "#") and the unit identifier ("4B"). A bare
+// identifier returns an empty designator.
+func Unit(s string) (designator, id string) {
+	toks := tokens(s)
+	if len(toks) == 0 {
+		return "", ""
+	}
+
+	if startsUnit(toks, 0) {
+		designator = unitDesignators[toks[0]]
+		toks = toks[1:]
+	}
+	if len(toks) > 0 && toks[0] == "#" {
+		if len(designator) == 0 {
+			designator = "#"
+		}
+		toks = toks[1:]
+	}
+	return designator, strings.Join(toks, " ")
+}
+
+// UnitID returns just the unit identifier of a secondary address line, e.g.
+// "4B" for both "Apt 4B" and "#4B". Lines that don't look like a unit, such
+// as "c/o Jane Doe", return "".
+func UnitID(s string) string {
+	designator, id := Unit(s)
+	if len(designator) == 0 && (!isUnitID(id) || strings.Contains(id, " ")) {
+		return ""
+	}
+	return id
+}
+
+// City canonicalizes a city name, e.g. "Saint Paul" and "St. Paul" both
+// become "ST PAUL".
+func City(s string) string {
+	toks := tokens(s)
+	for i, tok := range toks {
+		if abbr, ok := cityWords[tok]; ok {
+			toks[i] = abbr
+		}
+	}
+	return strings.Join(toks, " ")
+}
+
+// PostalCode uppercases a postal code and removes spaces and punctuation, so
+// "sw1a 1aa" becomes "SW1A1AA" and "62701-1234" becomes "627011234".
+func PostalCode(s string) string {
+	return strings.Join(tokensNoDash(s), "")
+}
+
+func tokensNoDash(s string) []string {
+	var out []string
+	for _, tok := range tokens(s) {
+		out = append(out, strings.ReplaceAll(tok, "-", ""))
+	}
+	return out
+}
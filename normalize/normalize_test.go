@@ -0,0 +1,247 @@
+package normalize
+
+import "testing"
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		In, Want string
+	}{
+		{"", ""},
+		{"main", "MAIN"},
+		{"Zürich", "ZURICH"},
+		{"Façade", "FACADE"},
+		{"São Paulo", "SAO PAULO"},
+		{"Straße", "STRASSE"},
+		{"Ærøskøbing", "AEROSKOBING"},
+		{"Łódź", "LODZ"},
+		{"ｍａｉｎ", "MAIN"}, // fullwidth
+	}
+
+	for _, test := range tests {
+		if got := Fold(test.In); got != test.Want {
+			t.Errorf("Fold(%q) = %q, want %q", test.In, got, test.Want)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		In, Want string
+	}{
+		{"", ""},
+		{"  Springfield  ", "SPRINGFIELD"},
+		{"Winston-Salem", "WINSTON-SALEM"},
+		{"O'Fallon", "OFALLON"},
+		{"Coeur d’Alene", "COEUR DALENE"},
+		{"Washington, D.C.", "WASHINGTON DC"},
+		{"a\tb\n c", "A B C"},
+	}
+
+	for _, test := range tests {
+		if got := Text(test.In); got != test.Want {
+			t.Errorf("Text(%q) = %q, want %q", test.In, got, test.Want)
+		}
+	}
+}
+
+func TestStreet(t *testing.T) {
+	tests := []struct {
+		In, Want string
+	}{
+		{"", ""},
+		{"123 Main Street", "123 MAIN ST"},
+		{"123 Main St.", "123 MAIN ST"},
+		{"123 main str", "123 MAIN ST"},
+		{"123 Park Avenue", "123 PARK AVE"},
+		{"123 Avenue Road", "123 AVENUE RD"},
+		{"123 North Main Street", "123 N MAIN ST"},
+		{"123 Main Street North", "123 MAIN ST N"},
+		{"123 Main St. NW", "123 MAIN ST NW"},
+		{"45 North Street", "45 NORTH ST"},
+		{"45 N. Street", "45 N ST"},
+		{"10 Front Street", "10 FRONT ST"},
+		{"10 Lot Road", "10 LOT RD"},
+		{"123 Main Street, Apartment 4", "123 MAIN ST APT 4"},
+		{"123 Main St Apt #4", "123 MAIN ST APT 4"},
+		{"123 Main St #4", "123 MAIN ST #4"},
+		{"123 Main St Suite 200", "123 MAIN ST STE 200"},
+		{"1600 Pennsylvania Ave., N.W.", "1600 PENNSYLVANIA AVE NW"},
+		{"12 Rue de l'Église", "12 RUE DE LEGLISE"},
+		{"221B Baker Street", "221B BAKER ST"},
+		{"123 1/2 Elm Blvd", "123 1/2 ELM BLVD"},
+	}
+
+	for _, test := range tests {
+		if got := Street(test.In); got != test.Want {
+			t.Errorf("Street(%q) = %q, want %q", test.In, got, test.Want)
+		}
+	}
+}
+
+func TestSplitStreet(t *testing.T) {
+	tests := []struct {
+		In, Street, Unit string
+	}{
+		{"123 Main Street", "123 MAIN ST", ""},
+		{"123 Main Street Apt 4", "123 MAIN ST", "APT 4"},
+		{"123 Main Street #4", "123 MAIN ST", "#4"},
+		{"123 Main Street, Unit B", "123 MAIN ST", "UNIT B"},
+		{"500 Suite Road", "500 SUITE RD", ""},
+	}
+
+	for _, test := range tests {
+		street, unit := SplitStreet(test.In)
+		if street != test.Street || unit != test.Unit {
+			t.Errorf("SplitStreet(%q) = (%q, %q), want (%q, %q)", test.In, street, unit, test.Street, test.Unit)
+		}
+	}
+}
+
+func TestSecondary(t *testing.T) {
+	tests := []struct {
+		In, Want string
+	}{
+		{"", ""},
+		{"Apartment 4", "APT 4"},
+		{"Apt. #4", "APT 4"},
+		{"# 4", "#4"},
+		{"Suite 200", "STE 200"},
+		{"c/o Jane Doe", "C/O JANE DOE"},
+	}
+
+	for _, test := range tests {
+		if got := Secondary(test.In); got != test.Want {
+			t.Errorf("Secondary(%q) = %q, want %q", test.In, got, test.Want)
+		}
+	}
+}
+
+func TestUnit(t *testing.T) {
+	tests := []struct {
+		In, Designator, ID string
+	}{
+		{"", "", ""},
+		{"Apt 4", "APT", "4"},
+		{"Apartment 4", "APT", "4"},
+		{"apt. #4", "APT", "4"},
+		{"#4", "#", "4"},
+		{"# 4B", "#", "4B"},
+		{"Suite 200", "STE", "200"},
+		{"4", "", "4"},
+		{"Unit", "", "UNIT"},
+	}
+
+	for _, test := range tests {
+		designator, id := Unit(test.In)
+		if designator != test.Designator || id != test.ID {
+			t.Errorf("Unit(%q) = (%q, %q), want (%q, %q)", test.In, designator, id, test.Designator, test.ID)
+		}
+	}
+}
+
+func TestUnitID(t *testing.T) {
+	tests := []struct {
+		In, Want string
+	}{
+		{"", ""},
+		{"Apt 4B", "4B"},
+		{"#4B", "4B"},
+		{"4B", "4B"},
+		{"Suite 200", "200"},
+		{"c/o Jane Doe", ""},
+		{"Rear House", ""},
+	}
+
+	for _, test := range tests {
+		if got := UnitID(test.In); got != test.Want {
+			t.Errorf("UnitID(%q) = %q, want %q", test.In, got, test.Want)
+		}
+	}
+}
+
+func TestCity(t *testing.T) {
+	tests := []struct {
+		In, Want string
+	}{
+		{"Springfield", "SPRINGFIELD"},
+		{"Saint Paul", "ST PAUL"},
+		{"St. Paul", "ST PAUL"},
+		{"Mount Vernon", "MT VERNON"},
+		{"Fort Worth", "FT WORTH"},
+		{"Montréal", "MONTREAL"},
+	}
+
+	for _, test := range tests {
+		if got := City(test.In); got != test.Want {
+			t.Errorf("City(%q) = %q, want %q", test.In, got, test.Want)
+		}
+	}
+}
+
+func TestPostalCode(t *testing.T) {
+	tests := []struct {
+		In, Want string
+	}{
+		{"", ""},
+		{"62701", "62701"},
+		{"62701-1234", "627011234"},
+		{"sw1a 1aa", "SW1A1AA"},
+		{"100-0001", "1000001"},
+	}
+
+	for _, test := range tests {
+		if got := PostalCode(test.In); got != test.Want {
+			t.Errorf("PostalCode(%q) = %q, want %q", test.In, got, test.Want)
+		}
+	}
+}
+
+func TestRegion(t *testing.T) {
+	tests := []struct {
+		In, Want string
+	}{
+		{"", ""},
+		{"IL", "IL"},
+		{"Illinois", "IL"},
+		{"new york", "NY"},
+		{"Québec", "QC"},
+		{"Ill.", "IL"},
+		{"Calif.", "CA"},
+		{"Mass.", "MA"},
+		{"N. Dak.", "ND"},
+		{"W. Va.", "WV"},
+		{"N.Y.", "NY"},
+		{"Ont.", "ON"},
+		{"Bayern", "BAYERN"},
+	}
+
+	for _, test := range tests {
+		if got := Region(test.In); got != test.Want {
+			t.Errorf("Region(%q) = %q, want %q", test.In, got, test.Want)
+		}
+	}
+}
+
+func TestCountryCode(t *testing.T) {
+	tests := []struct {
+		In, Want string
+	}{
+		{"", ""},
+		{"us", "US"},
+		{"USA", "US"},
+		{"U.S.A.", "US"},
+		{"United States", "US"},
+		{"United Kingdom", "GB"},
+		{"England", "GB"},
+		{"Deutschland", "DE"},
+		{"Österreich", "AT"},
+		{"Japan", "JP"},
+		{"Atlantis", ""},
+	}
+
+	for _, test := range tests {
+		if got := CountryCode(test.In); got != test.Want {
+			t.Errorf("CountryCode(%q) = %q, want %q", test.In, got, test.Want)
+		}
+	}
+}
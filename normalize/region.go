@@ -0,0 +1,57 @@
+package normalize
+
+// regionCodes maps US state and Canadian province names, in Text form, to
+// their postal abbreviations.
+var regionCodes = map[string]string{
+	"ALABAMA": "AL", "ALASKA": "AK", "ARIZONA": "AZ", "ARKANSAS": "AR", "CALIFORNIA": "CA",
+	"COLORADO": "CO", "CONNECTICUT": "CT", "DELAWARE": "DE", "DISTRICT OF COLUMBIA": "DC",
+	"FLORIDA": "FL", "GEORGIA": "GA", "HAWAII": "HI", "IDAHO": "ID", "ILLINOIS": "IL",
+	"INDIANA": "IN", "IOWA": "IA", "KANSAS": "KS", "KENTUCKY": "KY", "LOUISIANA": "LA",
+	"MAINE": "ME", "MARYLAND": "MD", "MASSACHUSETTS": "MA", "MICHIGAN": "MI", "MINNESOTA": "MN",
+	"MISSISSIPPI": "MS", "MISSOURI": "MO", "MONTANA": "MT", "NEBRASKA": "NE", "NEVADA": "NV",
+	"NEW HAMPSHIRE": "NH", "NEW JERSEY": "NJ", "NEW MEXICO": "NM", "NEW YORK": "NY",
+	"NORTH CAROLINA": "NC", "NORTH DAKOTA": "ND", "OHIO": "OH", "OKLAHOMA": "OK", "OREGON": "OR",
+	"PENNSYLVANIA": "PA", "RHODE ISLAND": "RI", "SOUTH CAROLINA": "SC", "SOUTH DAKOTA": "SD",
+	"TENNESSEE": "TN", "TEXAS": "TX", "UTAH": "UT", "VERMONT": "VT", "VIRGINIA": "VA",
+	"WASHINGTON": "WA", "WEST VIRGINIA": "WV", "WISCONSIN": "WI", "WYOMING": "WY",
+	"AMERICAN SAMOA": "AS", "GUAM": "GU", "NORTHERN MARIANA ISLANDS": "MP", "PUERTO RICO": "PR",
+	"VIRGIN ISLANDS": "VI",
+
+	"ALBERTA": "AB", "BRITISH COLUMBIA": "BC", "MANITOBA": "MB", "NEW BRUNSWICK": "NB",
+	"NEWFOUNDLAND AND LABRADOR": "NL", "NEWFOUNDLAND": "NL", "NOVA SCOTIA": "NS",
+	"NORTHWEST TERRITORIES": "NT", "NUNAVUT": "NU", "ONTARIO": "ON", "PRINCE EDWARD ISLAND": "PE",
+	"QUEBEC": "QC", "SASKATCHEWAN": "SK", "YUKON": "YT",
+}
+
+// regionAbbreviations maps the traditional abbreviations of US states and
+// Canadian provinces, e.g. "Ill." and "N. Dak.", in Text form to their postal
+// abbreviations.
+var regionAbbreviations = map[string]string{
+	"ALA": "AL", "ARIZ": "AZ", "ARK": "AR", "CAL": "CA", "CALIF": "CA", "COLO": "CO",
+	"CONN": "CT", "D C": "DC", "DEL": "DE", "FLA": "FL", "ILL": "IL", "IND": "IN",
+	"KAN": "KS", "KANS": "KS", "MASS": "MA", "MICH": "MI", "MINN": "MN", "MISS": "MS",
+	"MONT": "MT", "NEB": "NE", "NEBR": "NE", "NEV": "NV", "N H": "NH", "N J": "NJ",
+	"N M": "NM", "N MEX": "NM", "N Y": "NY", "N C": "NC", "N D": "ND", "N DAK": "ND",
+	"OKLA": "OK", "ORE": "OR", "OREG": "OR", "PENN": "PA", "PENNA": "PA", "R I": "RI",
+	"S C": "SC", "S D": "SD", "S DAK": "SD", "TENN": "TN", "TEX": "TX", "WASH": "WA",
+	"W VA": "WV", "WIS": "WI", "WISC": "WI", "WYO": "WY", "P R": "PR",
+
+	"ALTA": "AB", "B C": "BC", "MAN": "MB", "N B": "NB", "NFLD": "NL", "N S": "NS",
+	"N W T": "NT", "ONT": "ON", "P E I": "PE", "QUE": "QC", "P Q": "QC", "SASK": "SK",
+	"Y T": "YT",
+}
+
+// Region canonicalizes a state or province, giving US states and Canadian
+// provinces their postal abbreviations whether written out or abbreviated
+// the traditional way, e.g. "Illinois", "Ill." and "IL" all become "IL".
+// Other regions are returned in Text form.
+func Region(s string) string {
+	t := Text(s)
+	if code, ok := regionCodes[t]; ok {
+		return code
+	}
+	if code, ok := regionAbbreviations[t]; ok {
+		return code
+	}
+	return t
+}
@@ -0,0 +1,171 @@
+package normalize
+
+// Abbreviation tables from USPS Publication 28, appendices B and C. Keys are
+// every spelling we accept, values are the standard abbreviation.
+
+var directionals = map[string]string{
+	"NORTH": "N", "N": "N",
+	"SOUTH": "S", "S": "S",
+	"EAST": "E", "E": "E",
+	"WEST": "W", "W": "W",
+	"NORTHEAST": "NE", "NE": "NE",
+	"NORTHWEST": "NW", "NW": "NW",
+	"SOUTHEAST": "SE", "SE": "SE",
+	"SOUTHWEST": "SW", "SW": "SW",
+}
+
+var unitDesignators = map[string]string{
+	"APARTMENT": "APT", "APT": "APT",
+	"BASEMENT": "BSMT", "BSMT": "BSMT",
+	"BUILDING": "BLDG", "BLDG": "BLDG",
+	"DEPARTMENT": "DEPT", "DEPT": "DEPT",
+	"FLOOR": "FL", "FL": "FL",
+	"FRONT": "FRNT", "FRNT": "FRNT",
+	"HANGAR": "HNGR", "HNGR": "HNGR",
+	"KEY":   "KEY",
+	"LOBBY": "LBBY", "LBBY": "LBBY",
+	"LOT":   "LOT",
+	"LOWER": "LOWR", "LOWR": "LOWR",
+	"OFFICE": "OFC", "OFC": "OFC",
+	"PENTHOUSE": "PH", "PH": "PH",
+	"PIER": "PIER",
+	"REAR": "REAR",
+	"ROOM": "RM", "RM": "RM",
+	"SIDE":  "SIDE",
+	"SLIP":  "SLIP",
+	"SPACE": "SPC", "SPC": "SPC",
+	"STOP":  "STOP",
+	"SUITE": "STE", "STE": "STE",
+	"TRAILER": "TRLR", "TRLR": "TRLR",
+	"UNIT":  "UNIT",
+	"UPPER": "UPPR", "UPPR": "UPPR",
+}
+
+var streetSuffixes = map[string]string{
+	"ALLEY": "ALY", "ALLEE": "ALY", "ALLY": "ALY", "ALY": "ALY",
+	"ANNEX": "ANX", "ANEX": "ANX", "ANNX": "ANX", "ANX": "ANX",
+	"ARCADE": "ARC", "ARC": "ARC",
+	"AVENUE": "AVE", "AV": "AVE", "AVE": "AVE", "AVEN": "AVE", "AVENU": "AVE", "AVN": "AVE", "AVNUE": "AVE",
+	"BAYOU": "BYU", "BAYOO": "BYU", "BYU": "BYU",
+	"BEACH": "BCH", "BCH": "BCH",
+	"BEND": "BND", "BND": "BND",
+	"BLUFF": "BLF", "BLUF": "BLF", "BLF": "BLF",
+	"BOTTOM": "BTM", "BOT": "BTM", "BOTTM": "BTM", "BTM": "BTM",
+	"BOULEVARD": "BLVD", "BOUL": "BLVD", "BOULV": "BLVD", "BLVD": "BLVD",
+	"BRANCH": "BR", "BRNCH": "BR", "BR": "BR",
+	"BRIDGE": "BRG", "BRDGE": "BRG", "BRG": "BRG",
+	"BROOK": "BRK", "BRK": "BRK",
+	"BYPASS": "BYP", "BYPA": "BYP", "BYPAS": "BYP", "BYPS": "BYP", "BYP": "BYP",
+	"CAMP": "CP", "CMP": "CP", "CP": "CP",
+	"CANYON": "CYN", "CANYN": "CYN", "CNYN": "CYN", "CYN": "CYN",
+	"CAUSEWAY": "CSWY", "CAUSWA": "CSWY", "CSWY": "CSWY",
+	"CENTER": "CTR", "CEN": "CTR", "CENT": "CTR", "CENTR": "CTR", "CENTRE": "CTR", "CNTER": "CTR", "CNTR": "CTR", "CTR": "CTR",
+	"CIRCLE": "CIR", "CIRC": "CIR", "CIRCL": "CIR", "CRCL": "CIR", "CRCLE": "CIR", "CIR": "CIR",
+	"CLIFF": "CLF", "CLF": "CLF",
+	"CLUB": "CLB", "CLB": "CLB",
+	"COMMON": "CMN", "CMN": "CMN",
+	"CORNER": "COR", "COR": "COR",
+	"COURSE": "CRSE", "CRSE": "CRSE",
+	"COURT": "CT", "CT": "CT",
+	"COVE": "CV", "CV": "CV",
+	"CREEK": "CRK", "CRK": "CRK",
+	"CRESCENT": "CRES", "CRSENT": "CRES", "CRSNT": "CRES", "CRES": "CRES",
+	"CROSSING": "XING", "CRSSNG": "XING", "XING": "XING",
+	"DALE": "DL", "DL": "DL",
+	"DAM": "DM", "DM": "DM",
+	"DIVIDE": "DV", "DIV": "DV", "DVD": "DV", "DV": "DV",
+	"DRIVE": "DR", "DRIV": "DR", "DRV": "DR", "DR": "DR",
+	"ESTATE": "EST", "EST": "EST",
+	"EXPRESSWAY": "EXPY", "EXP": "EXPY", "EXPR": "EXPY", "EXPRESS": "EXPY", "EXPW": "EXPY", "EXPY": "EXPY",
+	"EXTENSION": "EXT", "EXTN": "EXT", "EXTNSN": "EXT", "EXT": "EXT",
+	"FALLS": "FLS", "FLS": "FLS",
+	"FERRY": "FRY", "FRRY": "FRY", "FRY": "FRY",
+	"FIELD": "FLD", "FLD": "FLD",
+	"FIELDS": "FLDS", "FLDS": "FLDS",
+	"FLAT": "FLT", "FLT": "FLT",
+	"FORD": "FRD", "FRD": "FRD",
+	"FOREST": "FRST", "FORESTS": "FRST", "FRST": "FRST",
+	"FORGE": "FRG", "FORG": "FRG", "FRG": "FRG",
+	"FORK": "FRK", "FRK": "FRK",
+	"FORT": "FT", "FRT": "FT", "FT": "FT",
+	"FREEWAY": "FWY", "FREEWY": "FWY", "FRWAY": "FWY", "FRWY": "FWY", "FWY": "FWY",
+	"GARDEN": "GDN", "GARDN": "GDN", "GRDEN": "GDN", "GRDN": "GDN", "GDN": "GDN",
+	"GARDENS": "GDNS", "GRDNS": "GDNS", "GDNS": "GDNS",
+	"GATEWAY": "GTWY", "GATEWY": "GTWY", "GATWAY": "GTWY", "GTWAY": "GTWY", "GTWY": "GTWY",
+	"GLEN": "GLN", "GLN": "GLN",
+	"GREEN": "GRN", "GRN": "GRN",
+	"GROVE": "GRV", "GROV": "GRV", "GRV": "GRV",
+	"HARBOR": "HBR", "HARB": "HBR", "HARBR": "HBR", "HRBOR": "HBR", "HBR": "HBR",
+	"HAVEN": "HVN", "HVN": "HVN",
+	"HEIGHTS": "HTS", "HT": "HTS", "HTS": "HTS",
+	"HIGHWAY": "HWY", "HIGHWY": "HWY", "HIWAY": "HWY", "HIWY": "HWY", "HWAY": "HWY", "HWY": "HWY",
+	"HILL": "HL", "HL": "HL",
+	"HILLS": "HLS", "HLS": "HLS",
+	"HOLLOW": "HOLW", "HLLW": "HOLW", "HOLLOWS": "HOLW", "HOLWS": "HOLW", "HOLW": "HOLW",
+	"ISLAND": "IS", "ISLND": "IS", "IS": "IS",
+	"JUNCTION": "JCT", "JCTION": "JCT", "JCTN": "JCT", "JUNCTN": "JCT", "JUNCTON": "JCT", "JCT": "JCT",
+	"KNOLL": "KNL", "KNOL": "KNL", "KNL": "KNL",
+	"LAKE": "LK", "LK": "LK",
+	"LAKES": "LKS", "LKS": "LKS",
+	"LANDING": "LNDG", "LNDNG": "LNDG", "LNDG": "LNDG",
+	"LANE": "LN", "LN": "LN",
+	"LOOP": "LOOP", "LOOPS": "LOOP",
+	"MALL":  "MALL",
+	"MANOR": "MNR", "MNR": "MNR",
+	"MEADOW": "MDW", "MDW": "MDW",
+	"MEADOWS": "MDWS", "MEDOWS": "MDWS", "MDWS": "MDWS",
+	"MEWS": "MEWS",
+	"MILL": "ML", "ML": "ML",
+	"MOUNT": "MT", "MNT": "MT", "MT": "MT",
+	"MOUNTAIN": "MTN", "MNTAIN": "MTN", "MNTN": "MTN", "MOUNTIN": "MTN", "MTIN": "MTN", "MTN": "MTN",
+	"ORCHARD": "ORCH", "ORCHRD": "ORCH", "ORCH": "ORCH",
+	"OVAL": "OVAL", "OVL": "OVAL",
+	"PARK": "PARK", "PRK": "PARK",
+	"PARKWAY": "PKWY", "PARKWY": "PKWY", "PKWAY": "PKWY", "PKY": "PKWY", "PKWY": "PKWY",
+	"PASS": "PASS",
+	"PATH": "PATH", "PATHS": "PATH",
+	"PIKE": "PIKE", "PIKES": "PIKE",
+	"PINES": "PNES", "PNES": "PNES",
+	"PLACE": "PL", "PL": "PL",
+	"PLAIN": "PLN", "PLN": "PLN",
+	"PLAINS": "PLNS", "PLNS": "PLNS",
+	"PLAZA": "PLZ", "PLZA": "PLZ", "PLZ": "PLZ",
+	"POINT": "PT", "PT": "PT",
+	"PORT": "PRT", "PRT": "PRT",
+	"PRAIRIE": "PR", "PRR": "PR", "PR": "PR",
+	"RANCH": "RNCH", "RANCHES": "RNCH", "RNCHS": "RNCH", "RNCH": "RNCH",
+	"RIDGE": "RDG", "RDGE": "RDG", "RDG": "RDG",
+	"RIVER": "RIV", "RVR": "RIV", "RIVR": "RIV", "RIV": "RIV",
+	"ROAD": "RD", "RD": "RD",
+	"ROUTE": "RTE", "RTE": "RTE",
+	"ROW":   "ROW",
+	"RUN":   "RUN",
+	"SHORE": "SHR", "SHOAR": "SHR", "SHR": "SHR",
+	"SPRING": "SPG", "SPNG": "SPG", "SPRNG": "SPG", "SPG": "SPG",
+	"SQUARE": "SQ", "SQR": "SQ", "SQRE": "SQ", "SQU": "SQ", "SQ": "SQ",
+	"STATION": "STA", "STATN": "STA", "STN": "STA", "STA": "STA",
+	"STREAM": "STRM", "STREME": "STRM", "STRM": "STRM",
+	"STREET": "ST", "STRT": "ST", "STR": "ST", "ST": "ST",
+	"SUMMIT": "SMT", "SUMIT": "SMT", "SUMITT": "SMT", "SMT": "SMT",
+	"TERRACE": "TER", "TERR": "TER", "TER": "TER",
+	"TRACE": "TRCE", "TRACES": "TRCE", "TRCE": "TRCE",
+	"TRAIL": "TRL", "TRAILS": "TRL", "TRLS": "TRL", "TRL": "TRL",
+	"TUNNEL": "TUNL", "TUNEL": "TUNL", "TUNLS": "TUNL", "TUNNELS": "TUNL", "TUNNL": "TUNL", "TUNL": "TUNL",
+	"TURNPIKE": "TPKE", "TRNPK": "TPKE", "TURNPK": "TPKE", "TPKE": "TPKE",
+	"UNION": "UN", "UN": "UN",
+	"VALLEY": "VLY", "VALLY": "VLY", "VLLY": "VLY", "VLY": "VLY",
+	"VIEW": "VW", "VW": "VW",
+	"VILLAGE": "VLG", "VILL": "VLG", "VILLAG": "VLG", "VILLG": "VLG", "VLG": "VLG",
+	"VISTA": "VIS", "VIST": "VIS", "VST": "VIS", "VSTA": "VIS", "VIS": "VIS",
+	"WALK": "WALK", "WALKS": "WALK",
+	"WAY": "WAY", "WY": "WAY",
+	"WELLS": "WLS", "WLS": "WLS",
+}
+
+// cityWords are words commonly abbreviated in place names.
+var cityWords = map[string]string{
+	"SAINT": "ST", "ST": "ST",
+	"SAINTE": "STE", "STE": "STE",
+	"MOUNT": "MT", "MT": "MT",
+	"FORT": "FT", "FT": "FT",
+}
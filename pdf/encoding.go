@@ -0,0 +1,76 @@
+package pdf
+
+import "golang.org/x/text/unicode/norm"
+
+// winAnsiHigh maps the characters WinAnsiEncoding places in 0x80-0x9F, where
+// Latin-1 has control codes. 0xA0-0xFF match Latin-1.
+var winAnsiHigh = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// foldRunes maps letters WinAnsiEncoding lacks that do not decompose into a
+// base letter and a combining mark.
+var foldRunes = map[rune]byte{
+	'ł': 'l', 'Ł': 'L',
+	'đ': 'd', 'Đ': 'D',
+	'ı': 'i',
+	'ẞ': 0xDF, // ß
+}
+
+func encodeRune(r rune) (byte, bool) {
+	switch {
+	case r >= ' ' && r <= '~':
+		return byte(r), true
+	case r >= 0xA0 && r <= 0xFF:
+		return byte(r), true
+	}
+	if b, ok := winAnsiHigh[r]; ok {
+		return b, true
+	}
+	if b, ok := foldRunes[r]; ok {
+		return b, true
+	}
+	return 0, false
+}
+
+// encodeWinAnsi converts s to WinAnsiEncoding, the single byte encoding of
+// the standard fonts. Characters it lacks lose their accents, e.g. 'ő'
+// becomes 'o', and anything else becomes '?'. Whitespace becomes a space.
+func encodeWinAnsi(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range norm.NFC.String(s) {
+		switch r {
+		case '\t', '\n', '\r':
+			r = ' '
+		}
+		if b, ok := encodeRune(r); ok {
+			out = append(out, b)
+			continue
+		}
+		if base := []rune(norm.NFD.String(string(r)))[0]; base != r {
+			if b, ok := encodeRune(base); ok {
+				out = append(out, b)
+				continue
+			}
+		}
+		out = append(out, '?')
+	}
+	return out
+}
+
+// decodeWinAnsi returns the character encoded as b, or 0 if b is unused.
+func decodeWinAnsi(b byte) rune {
+	switch {
+	case b >= ' ' && b <= '~', b >= 0xA0:
+		return rune(b)
+	}
+	for r, enc := range winAnsiHigh {
+		if enc == b {
+			return r
+		}
+	}
+	return 0
+}
@@ -0,0 +1,125 @@
+package pdf
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Font is one of the standard Type 1 fonts every PDF reader provides, so
+// documents never need to embed font files.
+type Font string
+
+const (
+	Helvetica     Font = "Helvetica"
+	HelveticaBold Font = "Helvetica-Bold"
+	TimesRoman    Font = "Times-Roman"
+	TimesBold     Font = "Times-Bold"
+	Courier       Font = "Courier"
+)
+
+// Fonts lists the supported fonts, default first.
+var Fonts = []Font{Helvetica, HelveticaBold, TimesRoman, TimesBold, Courier}
+
+// ParseFont returns the font named s, or Helvetica if s is empty.
+func ParseFont(s string) (Font, error) {
+	if len(s) == 0 {
+		return Helvetica, nil
+	}
+	for _, f := range Fonts {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown font %q", s)
+}
+
+// widths holds the advance width, in thousandths of the font size, of the
+// printable ASCII characters ' ' through '~' taken from each font's Adobe
+// font metrics.
+var widths = map[Font][95]int{
+	Helvetica: {
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	},
+	HelveticaBold: {
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	},
+	TimesRoman: {
+		250, 333, 408, 500, 500, 833, 778, 180, 333, 333, 500, 564, 250, 333, 250, 278,
+		500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 278, 278, 564, 564, 564, 444,
+		921, 722, 667, 667, 722, 611, 556, 722, 722, 333, 389, 722, 611, 889, 722, 722,
+		556, 722, 667, 556, 611, 722, 722, 944, 722, 722, 611, 333, 278, 333, 469, 500,
+		333, 444, 500, 444, 500, 444, 333, 500, 500, 278, 278, 500, 278, 778, 500, 500,
+		500, 500, 333, 389, 278, 500, 500, 722, 500, 500, 444, 480, 200, 480, 541,
+	},
+	TimesBold: {
+		250, 333, 555, 500, 500, 1000, 833, 278, 333, 333, 500, 570, 250, 333, 250, 278,
+		500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 333, 333, 570, 570, 570, 500,
+		930, 722, 667, 722, 722, 667, 611, 778, 778, 389, 500, 778, 667, 944, 722, 778,
+		611, 778, 722, 556, 667, 722, 722, 1000, 722, 722, 667, 333, 278, 333, 581, 500,
+		333, 500, 556, 444, 556, 444, 333, 500, 556, 278, 333, 556, 278, 833, 556, 500,
+		556, 556, 444, 389, 333, 556, 500, 722, 500, 500, 444, 394, 220, 394, 520,
+	},
+}
+
+// courierWidth is the advance width of every Courier character.
+const courierWidth = 600
+
+// glyphWidth returns the width of the WinAnsi encoded character b. Characters
+// outside ASCII are measured as their unaccented letter, or as an 'M' when
+// they have none, which errs on the side of too wide.
+func (f Font) glyphWidth(b byte) int {
+	if f == Courier {
+		return courierWidth
+	}
+	table, ok := widths[f]
+	if !ok {
+		table = widths[Helvetica]
+	}
+	if b >= ' ' && b <= '~' {
+		return table[b-' ']
+	}
+	if r := decodeWinAnsi(b); r != 0 {
+		if base := []rune(norm.NFD.String(string(r)))[0]; base >= ' ' && base <= '~' {
+			return table[base-' ']
+		}
+	}
+	return table['M'-' ']
+}
+
+// Width returns the width, in points, of s set in f at the given size.
+func (f Font) Width(s string, size float64) float64 {
+	var total int
+	for _, b := range encodeWinAnsi(s) {
+		total += f.glyphWidth(b)
+	}
+	return float64(total) * size / 1000
+}
+
+// FitSize returns the biggest font size, up to largest, at which every line
+// of lines fits within width, and all of them, spaced leading times the size
+// apart, fit within height.
+func (f Font) FitSize(largest float64, lines []string, width, height, leading float64) float64 {
+	size := largest
+	for _, line := range lines {
+		if w := f.Width(line, 1); w > 0 {
+			size = math.Min(size, width/w)
+		}
+	}
+	if len(lines) > 0 {
+		size = math.Min(size, height/(leading*float64(len(lines))))
+	}
+	return size
+}
@@ -0,0 +1,183 @@
+// Package pdf writes simple PDF documents: pages of text set in the standard
+// fonts. It covers what printing labels and envelopes needs and nothing more.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Points per unit of length; PDF measures everything in points.
+const (
+	Inch       = 72.0
+	Millimeter = Inch / 25.4
+)
+
+// Size is a page size in points.
+type Size struct {
+	Width, Height float64
+}
+
+var (
+	Letter = Size{8.5 * Inch, 11 * Inch}
+	A4     = Size{210 * Millimeter, 297 * Millimeter}
+)
+
+// Document is a PDF under construction.
+type Document struct {
+	pages []*Page
+}
+
+// Page is a single page of a Document. Coordinates are in points from the
+// bottom left corner.
+type Page struct {
+	size    Size
+	content bytes.Buffer
+}
+
+// AddPage appends a blank page of the given size.
+func (d *Document) AddPage(size Size) *Page {
+	p := &Page{size: size}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Pages returns the number of pages added so far.
+func (d *Document) Pages() int {
+	return len(d.pages)
+}
+
+// Text draws s with its baseline starting at x, y. Fonts not listed in Fonts
+// fall back to Helvetica.
+func (p *Page) Text(x, y float64, font Font, size float64, s string) {
+	fmt.Fprintf(&p.content, "BT /%s %s Tf %s %s Td ", fontResource(font), num(size), num(x), num(y))
+	writeString(&p.content, encodeWinAnsi(s))
+	p.content.WriteString(" Tj ET\n")
+}
+
+// fontResource names f in a page's resource dictionary.
+func fontResource(f Font) string {
+	for i, known := range Fonts {
+		if f == known {
+			return "F" + strconv.Itoa(i+1)
+		}
+	}
+	return "F1"
+}
+
+// num formats a length rounded to two decimals, which is far finer than any
+// printer resolves.
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+// writeString writes s as a PDF literal string, escaping delimiters and
+// anything outside printable ASCII.
+func writeString(b *bytes.Buffer, s []byte) {
+	b.WriteByte('(')
+	for _, c := range s {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(')')
+}
+
+// countingWriter records the offset of each object for the cross-reference
+// table.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) printf(format string, args ...any) {
+	if cw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(cw.w, format, args...)
+	cw.n += int64(n)
+	cw.err = err
+}
+
+func (cw *countingWriter) write(p []byte) {
+	if cw.err != nil {
+		return
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+}
+
+// WriteTo writes the finished document to w.
+//
+// Objects are numbered: 1 the catalog, 2 the page tree, then one per font in
+// Fonts, then a page and its content stream for each page.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	var offsets []int64
+	begin := func() int {
+		offsets = append(offsets, cw.n)
+		id := len(offsets)
+		cw.printf("%d 0 obj\n", id)
+		return id
+	}
+	end := func() {
+		cw.printf("endobj\n")
+	}
+
+	// the binary comment marks the file as binary for transfer programs
+	cw.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	firstPage := 3 + len(Fonts)
+	begin()
+	cw.printf("<< /Type /Catalog /Pages 2 0 R >>\n")
+	end()
+
+	begin()
+	cw.printf("<< /Type /Pages /Count %d /Kids [", len(d.pages))
+	for i := range d.pages {
+		cw.printf(" %d 0 R", firstPage+2*i)
+	}
+	cw.printf(" ] >>\n")
+	end()
+
+	var fontRefs bytes.Buffer
+	for _, f := range Fonts {
+		id := begin()
+		cw.printf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>\n", f)
+		end()
+		fmt.Fprintf(&fontRefs, " /%s %d 0 R", fontResource(f), id)
+	}
+
+	for _, p := range d.pages {
+		id := begin()
+		cw.printf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font <<%s >> >> /Contents %d 0 R >>\n",
+			num(p.size.Width), num(p.size.Height), fontRefs.String(), id+1)
+		end()
+
+		begin()
+		cw.printf("<< /Length %d >>\nstream\n", p.content.Len())
+		cw.write(p.content.Bytes())
+		cw.printf("endstream\n")
+		end()
+	}
+
+	xref := cw.n
+	cw.printf("xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		cw.printf("%010d 00000 n \n", off)
+	}
+	cw.printf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return cw.n, cw.err
+}
@@ -0,0 +1,119 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEncodeWinAnsi(t *testing.T) {
+	tests := []struct {
+		In   string
+		Want []byte
+	}{
+		{"", []byte{}},
+		{"Main St", []byte("Main St")},
+		{"Zürich", []byte("Z\xfcrich")},
+		{"Zu\u0308rich", []byte("Z\xfcrich")}, // decomposed
+		{"O’Neil", []byte("O\x92Neil")},
+		{"Łódź", []byte("L\xf3dz")},
+		{"Győr", []byte("Gyor")},
+		{"東京", []byte("??")},
+		{"a\tb", []byte("a b")},
+	}
+
+	for _, test := range tests {
+		if got := encodeWinAnsi(test.In); !bytes.Equal(got, test.Want) {
+			t.Errorf("encodeWinAnsi(%q) = %q, want %q", test.In, got, test.Want)
+		}
+	}
+}
+
+func TestWidth(t *testing.T) {
+	tests := []struct {
+		Font Font
+		In   string
+		Want float64
+	}{
+		{Helvetica, "", 0},
+		{Helvetica, "Hello", 11.39},
+		{Helvetica, "Zürich", 13.89},
+		{HelveticaBold, "Hello", 12.225},
+		{TimesRoman, "Hello", 11.11},
+		{Courier, "Hello", 15},
+	}
+
+	for _, test := range tests {
+		got := test.Font.Width(test.In, 5)
+		if math.Abs(got-test.Want) > 0.001 {
+			t.Errorf("%s.Width(%q) = %.2f, want %.2f", test.Font, test.In, got, test.Want)
+		}
+	}
+}
+
+func TestParseFont(t *testing.T) {
+	if got, err := ParseFont(""); err != nil || got != Helvetica {
+		t.Errorf("ParseFont(\"\") got: %v, %v, want: %v", got, err, Helvetica)
+	}
+	if got, err := ParseFont("times-roman"); err != nil || got != TimesRoman {
+		t.Errorf("ParseFont(\"times-roman\") got: %v, %v, want: %v", got, err, TimesRoman)
+	}
+	if _, err := ParseFont("Comic Sans"); err == nil {
+		t.Errorf("expected an error for an unknown font")
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	var d Document
+	d.AddPage(Letter).Text(72, 700, Helvetica, 12, "Hello (world)")
+	d.AddPage(A4).Text(72, 700, Courier, 10, `C:\Zürich`)
+
+	var b bytes.Buffer
+	n, err := d.WriteTo(&b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != int64(b.Len()) {
+		t.Errorf("WriteTo() returned %d, wrote %d bytes", n, b.Len())
+	}
+
+	out := b.String()
+	if !strings.HasPrefix(out, "%PDF-1.4\n") || !strings.HasSuffix(out, "%%EOF\n") {
+		t.Fatalf("missing PDF header or trailer:\n%s", out)
+	}
+	for _, want := range []string{
+		"/Count 2",
+		"/MediaBox [0 0 612 792]",
+		"/MediaBox [0 0 595.28 841.89]",
+		"BT /F1 12 Tf 72 700 Td (Hello \\(world\\)) Tj ET",
+		"BT /F5 10 Tf 72 700 Td (C:\\\\Z\\374rich) Tj ET",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q", want)
+		}
+	}
+
+	// every cross-reference entry must point at its object
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(out)
+	if m == nil {
+		t.Fatalf("missing startxref")
+	}
+	xref, _ := strconv.Atoi(m[1])
+	if !strings.HasPrefix(out[xref:], "xref\n") {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(out[xref:], -1)
+	if len(entries) != 2+len(Fonts)+2*2 {
+		t.Errorf("got %d objects, want %d", len(entries), 2+len(Fonts)+2*2)
+	}
+	for i, e := range entries {
+		off, _ := strconv.Atoi(e[1])
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !strings.HasPrefix(out[off:], want) {
+			t.Errorf("xref entry %d points at %q, want %q", i+1, out[off:off+len(want)], want)
+		}
+	}
+}
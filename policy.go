@@ -0,0 +1,102 @@
+package cohabitaters
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bfallik/cohabitaters/normalize"
+)
+
+// ErrAmbiguousAddress means a policy could not decide which of a contact's
+// addresses to mail to.
+var ErrAmbiguousAddress = errors.New("ambiguous address")
+
+// AddressPolicy chooses the one address a contact's card should go to.
+type AddressPolicy interface {
+	// PickAddress returns the chosen address, nil if the contact has no
+	// addresses, or ErrAmbiguousAddress if it can't choose.
+	PickAddress(c Contact) (*ContactAddress, error)
+}
+
+// Key identifies an address by its normalized contents so a choice survives
+// the contact's addresses being reordered or reformatted.
+func (a Address) Key() string {
+	n := a.Normalized()
+	return strings.Join([]string{n.StreetAddress, n.StreetAddress2, n.City, n.Region, normalize.PostalCode(n.PostalCode), n.CountryCode}, "|")
+}
+
+// SelectionPolicy picks, in order of preference:
+//
+//  1. the address recorded in Overrides for the contact
+//  2. the only address
+//  3. the only address typed "home"
+//  4. the address marked primary
+//  5. the only address of the first type in TypeOrder that has exactly one
+type SelectionPolicy struct {
+	// TypeOrder lists address types, most preferred first, e.g. "work".
+	TypeOrder []string
+	// Overrides maps a contact resource name to the Key of the address the
+	// user chose for that contact.
+	Overrides map[string]string
+}
+
+var _ AddressPolicy = SelectionPolicy{}
+
+func ofType(in []ContactAddress, typ string) []int {
+	var idxs []int
+	for idx, addr := range in {
+		if strings.EqualFold(strings.TrimSpace(addr.Type), typ) {
+			idxs = append(idxs, idx)
+		}
+	}
+	return idxs
+}
+
+func (p SelectionPolicy) PickAddress(c Contact) (*ContactAddress, error) {
+	in := c.Addresses
+
+	if key, ok := p.Overrides[c.ResourceName]; ok {
+		for idx, addr := range in {
+			if addr.Key() == key {
+				return &in[idx], nil
+			}
+		}
+		// the chosen address is gone; fall through to the rules
+	}
+
+	switch len(in) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &in[0], nil
+	}
+
+	if homes := ofType(in, "home"); len(homes) == 1 {
+		return &in[homes[0]], nil
+	}
+
+	for idx, addr := range in {
+		if addr.Primary {
+			return &in[idx], nil
+		}
+	}
+
+	for _, typ := range p.TypeOrder {
+		if idxs := ofType(in, strings.TrimSpace(typ)); len(idxs) == 1 {
+			return &in[idxs[0]], nil
+		}
+	}
+
+	return nil, ErrAmbiguousAddress
+}
+
+// ParseTypeOrder splits a comma separated list of address types.
+func ParseTypeOrder(s string) []string {
+	var out []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); len(t) > 0 {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package cohabitaters
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSelectionPolicy(t *testing.T) {
+	home := ContactAddress{Address: mainStreet, Type: "Home"}
+	work := ContactAddress{Address: elmStreet, Type: "work"}
+	other := ContactAddress{Address: Address{StreetAddress: "57 Walnut Street", City: "Springfield"}, Type: "other"}
+	primaryWork := work
+	primaryWork.Primary = true
+
+	tests := []struct {
+		Desc    string
+		Policy  SelectionPolicy
+		In      []ContactAddress
+		Want    *ContactAddress
+		WantErr error
+	}{
+		{Desc: "no addresses"},
+		{Desc: "single address", In: []ContactAddress{work}, Want: &work},
+		{Desc: "home among several", In: []ContactAddress{work, home}, Want: &home},
+		{Desc: "primary", In: []ContactAddress{other, primaryWork}, Want: &primaryWork},
+		{Desc: "home beats primary", In: []ContactAddress{primaryWork, home}, Want: &home},
+		{Desc: "two homes", In: []ContactAddress{home, home}, WantErr: ErrAmbiguousAddress},
+		{Desc: "no home among several", In: []ContactAddress{work, other}, WantErr: ErrAmbiguousAddress},
+		{
+			Desc:   "type order",
+			Policy: SelectionPolicy{TypeOrder: []string{"vacation", "other", "work"}},
+			In:     []ContactAddress{work, other},
+			Want:   &other,
+		},
+		{
+			Desc:   "type order skips repeated types",
+			Policy: SelectionPolicy{TypeOrder: []string{"work", "other"}},
+			In:     []ContactAddress{work, work, other},
+			Want:   &other,
+		},
+		{
+			Desc:   "override",
+			Policy: SelectionPolicy{Overrides: map[string]string{"people/1": work.Key()}},
+			In:     []ContactAddress{home, work},
+			Want:   &work,
+		},
+		{
+			Desc:   "stale override",
+			Policy: SelectionPolicy{Overrides: map[string]string{"people/1": "gone"}},
+			In:     []ContactAddress{home, work},
+			Want:   &home,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			got, err := test.Policy.PickAddress(Contact{ResourceName: "people/1", Addresses: test.In})
+			if !errors.Is(err, test.WantErr) {
+				t.Fatalf("unexpected error, got: %v, want: %v", err, test.WantErr)
+			}
+			if diff := cmp.Diff(test.Want, got); diff != "" {
+				t.Errorf("PickAddress() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAddressKey(t *testing.T) {
+	a := Address{StreetAddress: "123 Main Street", City: "Springfield", PostalCode: "62701"}
+	b := Address{StreetAddress: "123 main st.", City: "SPRINGFIELD", PostalCode: "62701"}
+	if a.Key() != b.Key() {
+		t.Errorf("expected equal keys, got: %q and %q", a.Key(), b.Key())
+	}
+	if a.Key() == elmStreet.Key() {
+		t.Errorf("unexpected equal keys for different addresses")
+	}
+}
+
+func TestParseTypeOrder(t *testing.T) {
+	want := []string{"work", "other"}
+	if diff := cmp.Diff(want, ParseTypeOrder(" work, ,other ")); diff != "" {
+		t.Errorf("ParseTypeOrder() mismatch (-want +got):\n%s", diff)
+	}
+}
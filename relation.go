@@ -0,0 +1,89 @@
+package cohabitaters
+
+import (
+	"strings"
+
+	"github.com/bfallik/cohabitaters/normalize"
+)
+
+// householdRelations are the relation types, lowercased, that imply two
+// contacts live together. Children are left out since grown children often
+// live on their own.
+var householdRelations = map[string]bool{
+	"spouse":          true,
+	"partner":         true,
+	"domesticpartner": true,
+}
+
+// relationOutweighs reports whether a household relation may merge two
+// contacts despite the conflict between their addresses. A different house
+// number, unit, postal code or country means they live apart, whatever the
+// relation says.
+func relationOutweighs(conflict string) bool {
+	switch conflict {
+	case "house number", "unit", "postal code", "country":
+		return false
+	}
+	return true
+}
+
+// relatedBy returns the household relation one of a or b declares to the
+// other, e.g. "spouse", or "" if there is none.
+func relatedBy(a, b Contact) string {
+	if rel := declaredRelation(a, b); len(rel) > 0 {
+		return rel
+	}
+	return declaredRelation(b, a)
+}
+
+// declaredRelation returns the type of the first household relation in from
+// that names to.
+func declaredRelation(from, to Contact) string {
+	for _, r := range from.Relations {
+		typ := strings.ToLower(strings.TrimSpace(r.Type))
+		if householdRelations[typ] && refersTo(r.Person, from, to) {
+			return typ
+		}
+	}
+	return ""
+}
+
+// refersTo reports whether person, the free text name recorded in one of
+// from's relations, names to. A bare given name counts when both contacts
+// share a family name, so "Marge" on Homer Simpson refers to Marge Simpson.
+func refersTo(person string, from, to Contact) bool {
+	p := normalize.Text(person)
+	if len(p) == 0 {
+		return false
+	}
+
+	var fromFamily string
+	if len(from.Names) > 0 {
+		fromFamily = normalize.Text(from.Names[0].FamilyName)
+	}
+
+	for _, n := range to.Names {
+		family := normalize.Text(n.FamilyName)
+		switch p {
+		case normalize.Text(n.DisplayName), normalize.Text(n.GivenName + " " + n.FamilyName):
+			return true
+		case normalize.Text(n.GivenName):
+			if len(family) > 0 && family == fromFamily {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sharesGroup reports whether a and b are both members of some contact group.
+func sharesGroup(a, b Contact) bool {
+	for _, ga := range a.Memberships {
+		for _, gb := range b.Memberships {
+			if ga == gb {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package cohabitaters
+
+import "testing"
+
+func Test_relatedBy(t *testing.T) {
+	homer := Contact{
+		ResourceName: "people/1",
+		Names:        []Name{{DisplayName: "Homer Simpson", GivenName: "Homer", FamilyName: "Simpson"}},
+	}
+	marge := Contact{
+		ResourceName: "people/2",
+		Names:        []Name{{DisplayName: "Marge Simpson", GivenName: "Marge", FamilyName: "Simpson"}},
+	}
+	patty := Contact{
+		ResourceName: "people/3",
+		Names:        []Name{{DisplayName: "Patty Bouvier", GivenName: "Patty", FamilyName: "Bouvier"}},
+	}
+	with := func(c Contact, rels ...Relation) Contact {
+		c.Relations = rels
+		return c
+	}
+
+	tests := []struct {
+		Desc string
+		A, B Contact
+		Want string
+	}{
+		{Desc: "none", A: homer, B: marge},
+		{Desc: "full name", A: with(homer, Relation{Person: "Marge Simpson", Type: "spouse"}), B: marge, Want: "spouse"},
+		{Desc: "declared by the other", A: homer, B: with(marge, Relation{Person: "homer simpson", Type: "Spouse"}), Want: "spouse"},
+		{Desc: "given name, same family", A: with(homer, Relation{Person: "Marge", Type: "spouse"}), B: marge, Want: "spouse"},
+		{Desc: "given name, other family", A: with(homer, Relation{Person: "Patty", Type: "partner"}), B: patty},
+		{Desc: "not a household relation", A: with(marge, Relation{Person: "Patty Bouvier", Type: "sister"}), B: patty},
+		{Desc: "child", A: with(homer, Relation{Person: "Marge Simpson", Type: "child"}), B: marge},
+		{Desc: "someone else", A: with(homer, Relation{Person: "Marge Bouvier", Type: "spouse"}), B: marge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			if got := relatedBy(test.A, test.B); got != test.Want {
+				t.Errorf("relatedBy() got: %q, want: %q", got, test.Want)
+			}
+		})
+	}
+}
+
+func Test_sharesGroup(t *testing.T) {
+	a := Contact{Memberships: []string{"contactGroups/1", "contactGroups/2"}}
+	b := Contact{Memberships: []string{"contactGroups/2"}}
+	c := Contact{Memberships: []string{"contactGroups/3"}}
+
+	if !sharesGroup(a, b) {
+		t.Errorf("expected a and b to share a group")
+	}
+	if sharesGroup(a, c) || sharesGroup(c, Contact{}) {
+		t.Errorf("unexpected shared group")
+	}
+}
@@ -0,0 +1,108 @@
+package cohabitaters
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SalutationStyle selects how a household's names are combined into the
+// addressee line of its card.
+type SalutationStyle string
+
+const (
+	// SalutationNames shares a family name where possible, e.g.
+	// "John & Jane Smith" or "Jane Doe & John Smith".
+	SalutationNames SalutationStyle = "names"
+	// SalutationFamily addresses a household sharing a family name as
+	// "The Smith Family" and otherwise falls back to SalutationNames.
+	SalutationFamily SalutationStyle = "family"
+	// SalutationFullNames lists everyone's full name, e.g.
+	// "John Smith & Jane Smith".
+	SalutationFullNames SalutationStyle = "full"
+)
+
+// SalutationStyles lists the supported styles, default first.
+var SalutationStyles = []SalutationStyle{SalutationNames, SalutationFamily, SalutationFullNames}
+
+// ParseSalutationStyle returns the style named s, or SalutationNames if s is
+// empty.
+func ParseSalutationStyle(s string) (SalutationStyle, error) {
+	if len(s) == 0 {
+		return SalutationNames, nil
+	}
+	for _, style := range SalutationStyles {
+		if strings.EqualFold(s, string(style)) {
+			return style, nil
+		}
+	}
+	return "", fmt.Errorf("unknown salutation style %q", s)
+}
+
+// Salutation combines the names of a household's contacts into a single
+// addressee line. Contacts without both a given and a family name are
+// written using their display name.
+func Salutation(style SalutationStyle, contacts []CardContact) string {
+	if len(contacts) == 0 {
+		return ""
+	}
+
+	family, shared := sharedFamilyName(contacts)
+	switch {
+	case style == SalutationFamily && shared && len(contacts) > 1:
+		return "The " + family + " Family"
+	case style == SalutationFullNames || !shared:
+		names := make([]string, len(contacts))
+		for i, c := range contacts {
+			names[i] = c.fullName()
+		}
+		return joinNames(dedupe(names))
+	default:
+		givens := make([]string, len(contacts))
+		for i, c := range contacts {
+			givens[i] = c.GivenName
+		}
+		return joinNames(dedupe(givens)) + " " + family
+	}
+}
+
+// sharedFamilyName reports whether every contact has a given name and the
+// same family name.
+func sharedFamilyName(contacts []CardContact) (string, bool) {
+	family := contacts[0].FamilyName
+	for _, c := range contacts {
+		if len(c.GivenName) == 0 || len(c.FamilyName) == 0 || !strings.EqualFold(c.FamilyName, family) {
+			return "", false
+		}
+	}
+	return family, true
+}
+
+func (c CardContact) fullName() string {
+	if len(c.GivenName) == 0 || len(c.FamilyName) == 0 {
+		return c.Name
+	}
+	return c.GivenName + " " + c.FamilyName
+}
+
+func dedupe(in []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, s := range in {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// joinNames joins names as "A", "A & B" or "A, B & C".
+func joinNames(names []string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " & " + names[len(names)-1]
+}
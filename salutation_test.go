@@ -0,0 +1,50 @@
+package cohabitaters
+
+import "testing"
+
+func TestSalutation(t *testing.T) {
+	john := CardContact{Name: "John Smith", GivenName: "John", FamilyName: "Smith"}
+	jane := CardContact{Name: "Jane Smith", GivenName: "Jane", FamilyName: "Smith"}
+	jimmy := CardContact{Name: "Jimmy Smith", GivenName: "Jimmy", FamilyName: "Smith"}
+	doe := CardContact{Name: "Jane Doe", GivenName: "Jane", FamilyName: "Doe"}
+	cher := CardContact{Name: "Cher"}
+
+	tests := []struct {
+		Desc     string
+		Style    SalutationStyle
+		Contacts []CardContact
+		Want     string
+	}{
+		{Desc: "empty", Want: ""},
+		{Desc: "single", Contacts: []CardContact{john}, Want: "John Smith"},
+		{Desc: "couple", Contacts: []CardContact{john, jane}, Want: "John & Jane Smith"},
+		{Desc: "three", Contacts: []CardContact{john, jane, jimmy}, Want: "John, Jane & Jimmy Smith"},
+		{Desc: "different surnames", Contacts: []CardContact{doe, john}, Want: "Jane Doe & John Smith"},
+		{Desc: "display name only", Contacts: []CardContact{cher, john}, Want: "Cher & John Smith"},
+		{Desc: "family", Style: SalutationFamily, Contacts: []CardContact{john, jane}, Want: "The Smith Family"},
+		{Desc: "family of one", Style: SalutationFamily, Contacts: []CardContact{john}, Want: "John Smith"},
+		{Desc: "family with different surnames", Style: SalutationFamily, Contacts: []CardContact{doe, john}, Want: "Jane Doe & John Smith"},
+		{Desc: "full names", Style: SalutationFullNames, Contacts: []CardContact{john, jane}, Want: "John Smith & Jane Smith"},
+		{Desc: "duplicate contact", Contacts: []CardContact{john, john}, Want: "John Smith"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			if got := Salutation(test.Style, test.Contacts); got != test.Want {
+				t.Errorf("Salutation() got: %q, want: %q", got, test.Want)
+			}
+		})
+	}
+}
+
+func TestParseSalutationStyle(t *testing.T) {
+	for in, want := range map[string]SalutationStyle{"": SalutationNames, "Family": SalutationFamily, "full": SalutationFullNames} {
+		got, err := ParseSalutationStyle(in)
+		if err != nil || got != want {
+			t.Errorf("ParseSalutationStyle(%q) got: %v, %v, want: %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseSalutationStyle("formal"); err == nil {
+		t.Errorf("missing expected error")
+	}
+}
@@ -0,0 +1,202 @@
+package cohabitaters
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/bfallik/cohabitaters/normalize"
+)
+
+// SortOrder selects the order of a Result's cards.
+type SortOrder string
+
+const (
+	// SortNone leaves the cards in the order their contacts were found.
+	SortNone SortOrder = ""
+	// SortAddressee orders the cards alphabetically by addressee.
+	SortAddressee SortOrder = "addressee"
+	// SortPostal presorts the cards for mailing: domestic cards first, then
+	// by country, region, postal code and street.
+	SortPostal SortOrder = "postal"
+)
+
+// SortOrders lists the supported orders, default first.
+var SortOrders = []SortOrder{SortNone, SortAddressee, SortPostal}
+
+// ParseSortOrder returns the order named s, or SortNone if s is empty or
+// "none".
+func ParseSortOrder(s string) (SortOrder, error) {
+	if strings.EqualFold(s, "none") {
+		return SortNone, nil
+	}
+	for _, order := range SortOrders {
+		if strings.EqualFold(s, string(order)) {
+			return order, nil
+		}
+	}
+	return "", fmt.Errorf("unknown sort order %q", s)
+}
+
+// Destination names the country a card is mailed to.
+type Destination struct {
+	// CountryCode is the ISO 3166-1 alpha-2 code, or "" for a country that
+	// isn't recognized.
+	CountryCode string
+	// Country is the country's English name, or the name as written when it
+	// isn't recognized.
+	Country  string
+	Domestic bool
+}
+
+// Destination returns where a is mailed to from senderCountry. As with
+// Lines, an address with no country is domestic.
+func (a Address) Destination(senderCountry string) Destination {
+	d := Destination{CountryCode: a.countryCode()}
+	switch {
+	case len(d.CountryCode) > 0:
+		d.Domestic = strings.EqualFold(d.CountryCode, senderCountry)
+	case len(strings.TrimSpace(a.Country)) == 0:
+		d.CountryCode, d.Domestic = strings.ToUpper(senderCountry), true
+	}
+	d.Country = normalize.CountryName(d.CountryCode)
+	if len(d.Country) == 0 {
+		d.Country = oneLine(a.Country)
+	}
+	return d
+}
+
+// CountryCount is the number of cards going to one country.
+type CountryCount struct {
+	Destination
+	Cards int
+}
+
+// Subtotals counts cards by destination.
+type Subtotals struct {
+	Domestic      int
+	International int
+	// Countries counts the cards per country, domestic first and then by
+	// country name.
+	Countries []CountryCount
+}
+
+// Subtotal counts cards by destination when mailed from senderCountry.
+func Subtotal(cards []XmasCard, senderCountry string) Subtotals {
+	var out Subtotals
+	idx := map[Destination]int{}
+	for _, card := range cards {
+		d := card.Address.Destination(senderCountry)
+		if d.Domestic {
+			out.Domestic++
+		} else {
+			out.International++
+		}
+		i, ok := idx[d]
+		if !ok {
+			i = len(out.Countries)
+			idx[d] = i
+			out.Countries = append(out.Countries, CountryCount{Destination: d})
+		}
+		out.Countries[i].Cards++
+	}
+	sort.SliceStable(out.Countries, func(i, j int) bool {
+		return destinationLess(out.Countries[i].Destination, out.Countries[j].Destination)
+	})
+	return out
+}
+
+func destinationLess(a, b Destination) bool {
+	if a.Domestic != b.Domestic {
+		return a.Domestic
+	}
+	if ac, bc := strings.ToUpper(a.Country), strings.ToUpper(b.Country); ac != bc {
+		return ac < bc
+	}
+	return a.CountryCode < b.CountryCode
+}
+
+// presortKey holds the fields cards are presorted by, each in a form that
+// compares the way a postal clerk would expect.
+type presortKey struct {
+	dest      Destination
+	region    string
+	postal    string
+	street    string
+	number    int
+	address   string
+	addressee string
+}
+
+func newPresortKey(card XmasCard, senderCountry string) presortKey {
+	a := card.Address
+	n := a.Normalized()
+	street, number := splitHouseNumber(n.StreetAddress)
+	return presortKey{
+		dest:      a.Destination(senderCountry),
+		region:    n.Region,
+		postal:    normalize.PostalCode(n.PostalCode),
+		street:    street,
+		number:    number,
+		address:   n.StreetAddress + " " + n.StreetAddress2,
+		addressee: strings.ToUpper(card.Addressee),
+	}
+}
+
+func (k presortKey) less(o presortKey) bool {
+	switch {
+	case k.dest != o.dest:
+		return destinationLess(k.dest, o.dest)
+	case k.region != o.region:
+		return k.region < o.region
+	case k.postal != o.postal:
+		return k.postal < o.postal
+	case k.street != o.street:
+		return k.street < o.street
+	case k.number != o.number:
+		return k.number < o.number
+	case k.address != o.address:
+		return k.address < o.address
+	}
+	return k.addressee < o.addressee
+}
+
+// splitHouseNumber separates a leading house number from a normalized street
+// line so "9 MAIN ST" sorts before "10 MAIN ST". Lines without one get
+// number 0.
+func splitHouseNumber(street string) (string, int) {
+	first, rest, _ := strings.Cut(street, " ")
+	digits := strings.IndexFunc(first, func(r rune) bool { return !unicode.IsDigit(r) })
+	if digits < 0 {
+		digits = len(first)
+	}
+	n, err := strconv.Atoi(first[:digits])
+	if err != nil {
+		return street, 0
+	}
+	return rest, n
+}
+
+// SortCards orders cards in place.
+func SortCards(cards []XmasCard, order SortOrder, senderCountry string) {
+	switch order {
+	case SortAddressee:
+		sort.SliceStable(cards, func(i, j int) bool {
+			return strings.ToUpper(cards[i].Addressee) < strings.ToUpper(cards[j].Addressee)
+		})
+	case SortPostal:
+		keyed := make([]struct {
+			key  presortKey
+			card XmasCard
+		}, len(cards))
+		for i, card := range cards {
+			keyed[i].key, keyed[i].card = newPresortKey(card, senderCountry), card
+		}
+		sort.SliceStable(keyed, func(i, j int) bool { return keyed[i].key.less(keyed[j].key) })
+		for i := range keyed {
+			cards[i] = keyed[i].card
+		}
+	}
+}
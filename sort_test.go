@@ -0,0 +1,94 @@
+package cohabitaters
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSortCards(t *testing.T) {
+	cards := []XmasCard{
+		{Addressee: "Ned Flanders", Address: Address{StreetAddress: "744 Evergreen Terrace", City: "Springfield", Region: "IL", PostalCode: "62701"}},
+		{Addressee: "Krusty", Address: Address{StreetAddress: "24 Sussex Drive", City: "Ottawa", Region: "Ontario", PostalCode: "K1M 1M4", Country: "Canada"}},
+		{Addressee: "Homer Simpson", Address: Address{StreetAddress: "742 Evergreen Terr.", City: "Springfield", Region: "Illinois", PostalCode: "62701", Country: "USA"}},
+		{Addressee: "Sideshow Bob", Address: Address{StreetAddress: "1 Main St", City: "Capital City", Region: "IL", PostalCode: "62704"}},
+		{Addressee: "Apu", Address: Address{StreetAddress: "99 Main St", City: "Springfield", Region: "IL", PostalCode: "62701"}},
+		{Addressee: "Moe", Address: Address{StreetAddress: "100 Main St", City: "Springfield", Region: "IL", PostalCode: "62701"}},
+		{Addressee: "Mr. Burns", Address: Address{StreetAddress: "1000 Mammon Ln", City: "Springfield", Region: "OR", PostalCode: "97403"}},
+		{Addressee: "Sherlock Holmes", Address: Address{StreetAddress: "221B Baker Street", City: "London", PostalCode: "NW1 6XE", CountryCode: "GB"}},
+		{Addressee: "Lisa", Address: Address{StreetAddress: "1 Nowhere", Country: "Atlantis"}},
+	}
+
+	tests := []struct {
+		Order SortOrder
+		Want  []string
+	}{
+		{SortNone, []string{"Ned Flanders", "Krusty", "Homer Simpson", "Sideshow Bob", "Apu", "Moe", "Mr. Burns", "Sherlock Holmes", "Lisa"}},
+		{SortAddressee, []string{"Apu", "Homer Simpson", "Krusty", "Lisa", "Moe", "Mr. Burns", "Ned Flanders", "Sherlock Holmes", "Sideshow Bob"}},
+		{SortPostal, []string{"Homer Simpson", "Ned Flanders", "Apu", "Moe", "Sideshow Bob", "Mr. Burns", "Lisa", "Krusty", "Sherlock Holmes"}},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.Order), func(t *testing.T) {
+			sorted := make([]XmasCard, len(cards))
+			copy(sorted, cards)
+			SortCards(sorted, test.Order, "US")
+
+			var got []string
+			for _, card := range sorted {
+				got = append(got, card.Addressee)
+			}
+			if diff := cmp.Diff(test.Want, got); diff != "" {
+				t.Errorf("SortCards() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseSortOrder(t *testing.T) {
+	tests := []struct {
+		In      string
+		Want    SortOrder
+		WantErr bool
+	}{
+		{"", SortNone, false},
+		{"none", SortNone, false},
+		{"Postal", SortPostal, false},
+		{"addressee", SortAddressee, false},
+		{"zip", "", true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseSortOrder(test.In)
+		if (err != nil) != test.WantErr {
+			t.Errorf("ParseSortOrder(%q) unexpected error: %v", test.In, err)
+		}
+		if got != test.Want {
+			t.Errorf("ParseSortOrder(%q) got: %v, want: %v", test.In, got, test.Want)
+		}
+	}
+}
+
+func TestSubtotal(t *testing.T) {
+	cards := []XmasCard{
+		{Address: Address{Country: "Canada"}},
+		{Address: Address{City: "Springfield"}},
+		{Address: Address{CountryCode: "us"}},
+		{Address: Address{Country: "Atlantis"}},
+		{Address: Address{Country: "canada"}},
+	}
+
+	got := Subtotal(cards, "US")
+	want := Subtotals{
+		Domestic:      2,
+		International: 3,
+		Countries: []CountryCount{
+			{Destination{CountryCode: "US", Country: "United States", Domestic: true}, 2},
+			{Destination{Country: "Atlantis"}, 1},
+			{Destination{CountryCode: "CA", Country: "Canada"}, 2},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Subtotal() mismatch (-want +got):\n%s", diff)
+	}
+}
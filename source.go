@@ -0,0 +1,63 @@
+package cohabitaters
+
+import "context"
+
+// ContactGroup is a named set of contacts, e.g. a Google Contacts label.
+type ContactGroup struct {
+	ResourceName  string
+	Name          string
+	FormattedName string
+	MemberCount   int
+}
+
+// Name is one of the names recorded for a contact.
+type Name struct {
+	DisplayName string
+	GivenName   string
+	FamilyName  string
+}
+
+// ContactAddress is one of the postal addresses recorded for a contact.
+type ContactAddress struct {
+	Address
+	Type    string
+	Primary bool
+}
+
+// Relation is another person a contact is related to, e.g. a spouse.
+type Relation struct {
+	// Person is the related person's name as free text.
+	Person string
+	Type   string
+}
+
+// Contact is a single address book entry, independent of where it came from.
+type Contact struct {
+	ResourceName string
+	Names        []Name
+	Addresses    []ContactAddress
+	Relations    []Relation
+	// Memberships are the resource names of the user-created groups the
+	// contact belongs to.
+	Memberships []string
+	// FetchError describes why the source couldn't return this contact, in
+	// which case only ResourceName is set.
+	FetchError string
+	// Account names the account the contact came from when contacts from
+	// several accounts are read together.
+	Account string
+}
+
+// ContactSource is an address book that GetXmasCards can read from.
+type ContactSource interface {
+	// ContactGroups lists the groups available in the address book.
+	ContactGroups(ctx context.Context) ([]ContactGroup, error)
+
+	// GroupMembers returns the resource names of every contact in the group.
+	GroupMembers(ctx context.Context, groupResourceName string) ([]string, error)
+
+	// Contacts fetches the names and addresses of the given contacts. A
+	// contact the source fails to return on its own is reported with
+	// FetchError set rather than failing the whole call.
+	Contacts(ctx context.Context, resourceNames []string) ([]Contact, error)
+}
@@ -0,0 +1,274 @@
+package vcard
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// Decoder reads vCards from a stream.
+type Decoder struct {
+	s *bufio.Scanner
+	// peeked holds a physical line read ahead to check for a continuation.
+	peeked *string
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return &Decoder{s: s}
+}
+
+func (d *Decoder) next() (string, bool) {
+	if d.peeked != nil {
+		l := *d.peeked
+		d.peeked = nil
+		return l, true
+	}
+	if !d.s.Scan() {
+		return "", false
+	}
+	return strings.TrimRight(d.s.Text(), "\r"), true
+}
+
+// readLine returns the next non-empty logical line, joining any folded
+// continuation lines.
+func (d *Decoder) readLine() (string, bool) {
+	var line string
+	for {
+		l, ok := d.next()
+		if !ok {
+			return "", false
+		}
+		if len(strings.TrimSpace(l)) > 0 {
+			line = l
+			break
+		}
+	}
+
+	for {
+		l, ok := d.next()
+		if !ok {
+			break
+		}
+		if len(l) > 0 && (l[0] == ' ' || l[0] == '\t') {
+			line += l[1:]
+			continue
+		}
+		// a quoted-printable value ending in '=' is soft broken and goes on
+		// at the start of the next line
+		if strings.HasSuffix(line, "=") && isQuotedPrintable(line) {
+			line = line[:len(line)-1] + l
+			continue
+		}
+		d.peeked = &l
+		break
+	}
+	return line, true
+}
+
+// Decode reads the next vCard, returning io.EOF when there are no more.
+func (d *Decoder) Decode() (Card, error) {
+	line, ok := d.readLine()
+	if !ok {
+		if err := d.s.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	if p, err := parseLine(line); err != nil || p.Name != "BEGIN" || !strings.EqualFold(p.Value, "VCARD") {
+		return nil, fmt.Errorf("vcard: expected BEGIN:VCARD, got %q", line)
+	}
+
+	var card Card
+	depth := 0
+	for {
+		line, ok := d.readLine()
+		if !ok {
+			if err := d.s.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("vcard: missing END:VCARD: %w", io.ErrUnexpectedEOF)
+		}
+		p, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("vcard: %w", err)
+		}
+		decodeValue(&p)
+
+		// skip cards nested inside this one, e.g. a vCard 2.1 AGENT
+		switch {
+		case p.Name == "BEGIN" && strings.EqualFold(p.Value, "VCARD"):
+			depth++
+			continue
+		case p.Name == "END" && strings.EqualFold(p.Value, "VCARD"):
+			if depth == 0 {
+				return card, nil
+			}
+			depth--
+			continue
+		case depth > 0:
+			continue
+		}
+		card = append(card, p)
+	}
+}
+
+// isQuotedPrintable reports whether the parameters of a content line say its
+// value is quoted-printable, either as ENCODING=QUOTED-PRINTABLE or, in vCard
+// 2.1, as a bare QUOTED-PRINTABLE.
+func isQuotedPrintable(line string) bool {
+	header, _, _ := strings.Cut(line, ":")
+	return strings.Contains(strings.ToUpper(header), "QUOTED-PRINTABLE")
+}
+
+// decodeValue decodes a quoted-printable value and converts a value in a
+// CHARSET other than UTF-8, both of which older vCards use, and drops the
+// parameters that described them so the property reads like any other.
+func decodeValue(p *Property) {
+	var qp bool
+	if strings.EqualFold(p.Param("ENCODING"), "QUOTED-PRINTABLE") {
+		qp = true
+		delete(p.Params, "ENCODING")
+	}
+	if types := p.Params["TYPE"]; len(types) > 0 {
+		kept := types[:0]
+		for _, t := range types {
+			if strings.EqualFold(t, "QUOTED-PRINTABLE") {
+				qp = true
+				continue
+			}
+			kept = append(kept, t)
+		}
+		p.Params["TYPE"] = kept
+		if len(kept) == 0 {
+			delete(p.Params, "TYPE")
+		}
+	}
+	if qp {
+		p.Value = strings.ReplaceAll(decodeQuotedPrintable(p.Value), "\r\n", "\n")
+	}
+
+	if charset := p.Param("CHARSET"); len(charset) > 0 {
+		if strings.EqualFold(charset, "UTF-8") {
+			delete(p.Params, "CHARSET")
+		} else if enc, err := ianaindex.IANA.Encoding(charset); err == nil && enc != nil {
+			if v, err := enc.NewDecoder().String(p.Value); err == nil {
+				p.Value = v
+				delete(p.Params, "CHARSET")
+			}
+		}
+	}
+	if len(p.Params) == 0 {
+		p.Params = nil
+	}
+}
+
+// decodeQuotedPrintable decodes each "=XX" in s. Unlike mime/quotedprintable
+// it leaves malformed sequences as they are rather than failing, since
+// address book exports aren't always careful.
+func decodeQuotedPrintable(s string) string {
+	if !strings.Contains(s, "=") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '=' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			v, _ := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			b.WriteByte(byte(v))
+			i += 2
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// parseLine splits a content line into its group, name, parameters and
+// value.
+func parseLine(line string) (Property, error) {
+	var p Property
+
+	nameEnd := strings.IndexAny(line, ";:")
+	if nameEnd < 0 {
+		return p, fmt.Errorf("missing ':' in %q", line)
+	}
+	p.Name = strings.ToUpper(strings.TrimSpace(line[:nameEnd]))
+	if dot := strings.LastIndexByte(p.Name, '.'); dot >= 0 {
+		p.Group, p.Name = line[:dot], p.Name[dot+1:]
+	}
+	if len(p.Name) == 0 {
+		return p, fmt.Errorf("missing property name in %q", line)
+	}
+
+	rest := line[nameEnd:]
+	for len(rest) > 0 && rest[0] == ';' {
+		rest = rest[1:]
+		name, values, remaining, err := parseParam(rest)
+		if err != nil {
+			return p, err
+		}
+		if p.Params == nil {
+			p.Params = map[string][]string{}
+		}
+		p.Params[name] = append(p.Params[name], values...)
+		rest = remaining
+	}
+	if len(rest) == 0 || rest[0] != ':' {
+		return p, fmt.Errorf("missing ':' in %q", line)
+	}
+	p.Value = rest[1:]
+	return p, nil
+}
+
+// parseParam reads one "NAME=value,value" parameter from the start of s. A
+// parameter without a name, as vCard 2.1 writes "ADR;HOME:", is a TYPE.
+func parseParam(s string) (name string, values []string, rest string, err error) {
+	end := strings.IndexAny(s, "=;:")
+	if end < 0 {
+		return "", nil, "", fmt.Errorf("unterminated parameter %q", s)
+	}
+	if s[end] != '=' {
+		return "TYPE", []string{s[:end]}, s[end:], nil
+	}
+	name = strings.ToUpper(strings.TrimSpace(s[:end]))
+	s = s[end+1:]
+
+	for {
+		var v string
+		if len(s) > 0 && s[0] == '"' {
+			closing := strings.IndexByte(s[1:], '"')
+			if closing < 0 {
+				return "", nil, "", fmt.Errorf("unterminated quote in parameter %s", name)
+			}
+			v, s = s[1:closing+1], s[closing+2:]
+		} else {
+			end := strings.IndexAny(s, ",;:")
+			if end < 0 {
+				return "", nil, "", fmt.Errorf("unterminated parameter %s", name)
+			}
+			v, s = s[:end], s[end:]
+		}
+		values = append(values, unescapeParam(v))
+		if len(s) == 0 || s[0] != ',' {
+			return name, values, s, nil
+		}
+		s = s[1:]
+	}
+}
+
+// unescapeParam undoes RFC 6868 escaping.
+func unescapeParam(v string) string {
+	if !strings.Contains(v, "^") {
+		return v
+	}
+	return strings.NewReplacer("^^", "^", "^n", "\n", "^N", "\n", "^'", `"`).Replace(v)
+}
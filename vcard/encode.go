@@ -0,0 +1,91 @@
+package vcard
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLineOctets is the longest line RFC 6350 allows before folding.
+const maxLineOctets = 75
+
+// Encoder writes vCards to a stream.
+type Encoder struct {
+	w io.Writer
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes card between BEGIN:VCARD and END:VCARD, folding long lines.
+// The card should start with its VERSION property.
+func (e *Encoder) Encode(card Card) error {
+	var b strings.Builder
+	b.WriteString("BEGIN:VCARD\r\n")
+	for _, p := range card {
+		writeFolded(&b, contentLine(p))
+	}
+	b.WriteString("END:VCARD\r\n")
+	_, err := io.WriteString(e.w, b.String())
+	return err
+}
+
+func contentLine(p Property) string {
+	var b strings.Builder
+	if len(p.Group) > 0 {
+		b.WriteString(p.Group)
+		b.WriteByte('.')
+	}
+	b.WriteString(strings.ToUpper(p.Name))
+
+	names := make([]string, 0, len(p.Params))
+	for name := range p.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteByte(';')
+		b.WriteString(strings.ToUpper(name))
+		b.WriteByte('=')
+		for i, v := range p.Params[name] {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(paramValue(v))
+		}
+	}
+
+	b.WriteByte(':')
+	b.WriteString(p.Value)
+	return b.String()
+}
+
+// paramValue escapes a parameter value as RFC 6868 describes, and quotes it
+// if it holds a delimiter.
+func paramValue(v string) string {
+	v = strings.NewReplacer("^", "^^", "\n", "^n", `"`, "^'").Replace(v)
+	if strings.ContainsAny(v, ":;,") {
+		return `"` + v + `"`
+	}
+	return v
+}
+
+// writeFolded writes line, breaking it into lines of at most maxLineOctets
+// without splitting a character. Each continuation starts with a space.
+func writeFolded(b *strings.Builder, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = maxLineOctets - 1 // the leading space counts
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
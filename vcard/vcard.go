@@ -0,0 +1,163 @@
+// Package vcard reads and writes vCard files (RFC 6350) at the level of
+// properties: it handles line folding, escaping and the quoted-printable and
+// non-UTF-8 values of older versions, and leaves the meaning of each property
+// to its callers.
+package vcard
+
+import (
+	"strings"
+)
+
+// Property is one content line of a vCard, e.g.
+// "ADR;TYPE=home:;;742 Evergreen Terrace;Springfield;IL;62701;".
+type Property struct {
+	// Group is the optional prefix grouping related properties, e.g. "item1"
+	// in "item1.TEL".
+	Group string
+	// Name is the property name in upper case.
+	Name string
+	// Params maps upper case parameter names to their values.
+	Params map[string][]string
+	// Value is the value as written, with any escaping still in place; use
+	// Text or Components to read it.
+	Value string
+}
+
+// Text returns the value unescaped as a single text value.
+func (p Property) Text() string {
+	return unescape(p.Value)
+}
+
+// Components splits a structured value, such as N or ADR, at its unescaped
+// semicolons and unescapes each component.
+func (p Property) Components() []string {
+	parts := splitUnescaped(p.Value, ';')
+	for i := range parts {
+		parts[i] = unescape(parts[i])
+	}
+	return parts
+}
+
+// List splits a multi-valued value, such as CATEGORIES, at its unescaped
+// commas and unescapes each value.
+func (p Property) List() []string {
+	parts := splitUnescaped(p.Value, ',')
+	for i := range parts {
+		parts[i] = unescape(parts[i])
+	}
+	return parts
+}
+
+// Param returns the first value of the named parameter, or "".
+func (p Property) Param(name string) string {
+	if v := p.Params[strings.ToUpper(name)]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// Card is a single vCard: its properties in the order they appear, without
+// BEGIN and END.
+type Card []Property
+
+// Get returns the first property named name, or nil.
+func (c Card) Get(name string) *Property {
+	name = strings.ToUpper(name)
+	for i := range c {
+		if c[i].Name == name {
+			return &c[i]
+		}
+	}
+	return nil
+}
+
+// All returns every property named name.
+func (c Card) All(name string) []Property {
+	name = strings.ToUpper(name)
+	var out []Property
+	for _, p := range c {
+		if p.Name == name {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Value returns the unescaped text of the first property named name, or "".
+func (c Card) Value(name string) string {
+	if p := c.Get(name); p != nil {
+		return p.Text()
+	}
+	return ""
+}
+
+// AddText appends a property with a text value, escaping it.
+func (c *Card) AddText(name, value string, params map[string][]string) {
+	*c = append(*c, Property{Name: name, Params: params, Value: EscapeText(value)})
+}
+
+// AddComponents appends a property with a structured value, escaping each
+// component.
+func (c *Card) AddComponents(name string, components []string, params map[string][]string) {
+	escaped := make([]string, len(components))
+	for i, v := range components {
+		escaped[i] = EscapeText(v)
+	}
+	*c = append(*c, Property{Name: name, Params: params, Value: strings.Join(escaped, ";")})
+}
+
+// EscapeText escapes backslashes, commas, semicolons and newlines in a text
+// value.
+func EscapeText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', ',', ';':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// splitUnescaped splits s at each sep not preceded by a backslash.
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
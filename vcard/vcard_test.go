@@ -0,0 +1,164 @@
+package vcard
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRoundTrip(t *testing.T) {
+	var card Card
+	card.AddText("VERSION", "4.0", nil)
+	card.AddText("FN", "Krusty, \"The Clown\"; Esq.", nil)
+	card.AddComponents("ADR", []string{"", "Suite 5", "1 Studio Lane", "Zürich", "", "8001", "Switzerland"},
+		map[string][]string{"TYPE": {"home", "work"}, "LABEL": {"1 Studio Lane\n8001 Zürich\n\"CH\""}})
+	card.AddText("NOTE", strings.Repeat("ü", 60), nil)
+	card = append(card, Property{Group: "item1", Name: "EMAIL", Value: "krusty@example.com"})
+
+	var b bytes.Buffer
+	enc := NewEncoder(&b)
+	for i := 0; i < 2; i++ {
+		if err := enc.Encode(card); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for _, line := range strings.Split(b.String(), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line is %d octets long: %q", len(line), line)
+		}
+	}
+
+	dec := NewDecoder(&b)
+	for i := 0; i < 2; i++ {
+		got, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if diff := cmp.Diff(card, got); diff != "" {
+			t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+		}
+	}
+	if _, err := dec.Decode(); !errors.Is(err, io.EOF) {
+		t.Errorf("unexpected error, got: %v, want: %v", err, io.EOF)
+	}
+
+	got := card.Get("adr")
+	if got == nil {
+		t.Fatalf("missing ADR")
+	}
+	if diff := cmp.Diff([]string{"", "Suite 5", "1 Studio Lane", "Zürich", "", "8001", "Switzerland"}, got.Components()); diff != "" {
+		t.Errorf("Components() mismatch (-want +got):\n%s", diff)
+	}
+	if want := "Krusty, \"The Clown\"; Esq."; card.Value("FN") != want {
+		t.Errorf("unexpected FN, got: %v, want: %v", card.Value("FN"), want)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	var card Card
+	card.AddText("VERSION", "4.0", nil)
+	card.AddText("FN", "Homer & Marge; Simpson", nil)
+	card.AddText("ADR", "x", map[string][]string{"TYPE": {"home"}, "LABEL": {"742 Evergreen Terrace\nSPRINGFIELD, IL"}})
+
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(card); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "BEGIN:VCARD\r\n" +
+		"VERSION:4.0\r\n" +
+		"FN:Homer & Marge\\; Simpson\r\n" +
+		"ADR;LABEL=\"742 Evergreen Terrace^nSPRINGFIELD, IL\";TYPE=home:x\r\n" +
+		"END:VCARD\r\n"
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("Encode() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	in := "BEGIN:VCARD\n" +
+		"VERSION:2.1\n" +
+		"N:Simpson;Homer\n" +
+		"ADR;HOME:;;742 Evergreen\n" +
+		"  Terrace;Springfield\n" +
+		"\n" +
+		"AGENT:\n" +
+		"BEGIN:VCARD\n" +
+		"FN:Smithers\n" +
+		"END:VCARD\n" +
+		"CATEGORIES:Family,Xmas\\, 2023\n" +
+		"end:vcard\n"
+
+	got, err := NewDecoder(strings.NewReader(in)).Decode()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Card{
+		{Name: "VERSION", Value: "2.1"},
+		{Name: "N", Value: "Simpson;Homer"},
+		{Name: "ADR", Params: map[string][]string{"TYPE": {"HOME"}}, Value: ";;742 Evergreen Terrace;Springfield"},
+		{Name: "AGENT", Value: ""},
+		{Name: "CATEGORIES", Value: "Family,Xmas\\, 2023"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"Family", "Xmas, 2023"}, got.Get("CATEGORIES").List()); diff != "" {
+		t.Errorf("List() mismatch (-want +got):\n%s", diff)
+	}
+	if got := got.Get("ADR").Param("type"); got != "HOME" {
+		t.Errorf("unexpected TYPE, got: %v, want: %v", got, "HOME")
+	}
+}
+
+func TestDecodeQuotedPrintable(t *testing.T) {
+	in := "BEGIN:VCARD\r\n" +
+		"VERSION:2.1\r\n" +
+		"N;CHARSET=UTF-8;ENCODING=QUOTED-PRINTABLE:M=C3=BCller;J=C3=BCrgen\r\n" +
+		"ADR;HOME;QUOTED-PRINTABLE:;;Hauptstra=C3=9Fe 1=0D=0A=\r\n" +
+		"Hinterhaus;Z=C3=BCrich;;8001;=\r\n" +
+		"Schweiz\r\n" +
+		"NOTE;CHARSET=ISO-8859-1:Gr\xfc\xdfe = 100%\r\n" +
+		"END:VCARD\r\n"
+
+	got, err := NewDecoder(strings.NewReader(in)).Decode()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Card{
+		{Name: "VERSION", Value: "2.1"},
+		{Name: "N", Value: "Müller;Jürgen"},
+		{Name: "ADR", Params: map[string][]string{"TYPE": {"HOME"}}, Value: ";;Hauptstraße 1\nHinterhaus;Zürich;;8001;Schweiz"},
+		{Name: "NOTE", Value: "Grüße = 100%"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		Name string
+		In   string
+	}{
+		{"not a vcard", "hello\n"},
+		{"missing end", "BEGIN:VCARD\nFN:Homer\n"},
+		{"missing colon", "BEGIN:VCARD\nFN\nEND:VCARD\n"},
+		{"unterminated quote", "BEGIN:VCARD\nADR;LABEL=\"742:x\nEND:VCARD\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if _, err := NewDecoder(strings.NewReader(test.In)).Decode(); err == nil || errors.Is(err, io.EOF) {
+				t.Errorf("unexpected error, got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+// Package xlsx writes simple Office Open XML spreadsheets: text and number
+// cells, a bold header row that stays in view when scrolling, and column
+// widths. It covers what exporting a table needs and nothing more.
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// MaxSheetName is the longest sheet name Excel accepts.
+	MaxSheetName = 31
+	// DefaultMaxWidth caps the column widths worked out from their contents.
+	DefaultMaxWidth = 60.0
+	minWidth        = 8.0
+)
+
+// Workbook is a spreadsheet file of one or more sheets.
+type Workbook struct {
+	sheets []*Sheet
+}
+
+// Sheet is one tab of a Workbook.
+type Sheet struct {
+	name   string
+	header []string
+	rows   [][]any
+	// Widths sets column widths, in characters, by column index. Columns
+	// without one are sized to fit their contents, up to DefaultMaxWidth.
+	Widths map[int]float64
+}
+
+// AddSheet appends a sheet called name. Characters Excel doesn't allow in
+// sheet names are replaced and long names are shortened.
+func (wb *Workbook) AddSheet(name string) *Sheet {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, name)
+	if utf8.RuneCountInString(name) > MaxSheetName {
+		name = string([]rune(name)[:MaxSheetName])
+	}
+	s := &Sheet{name: name}
+	wb.sheets = append(wb.sheets, s)
+	return s
+}
+
+// SetHeader sets the sheet's first row, which is set in bold and frozen so
+// it stays in view.
+func (s *Sheet) SetHeader(names ...string) {
+	s.header = names
+}
+
+// AddRow appends a row. Cells may be strings, ints or float64s; strings with
+// line breaks are wrapped.
+func (s *Sheet) AddRow(cells ...any) {
+	s.rows = append(s.rows, cells)
+}
+
+// Name returns the sheet's name as it appears on its tab.
+func (s *Sheet) Name() string {
+	return s.name
+}
+
+// cell styles, indexes into cellXfs in styles.xml
+const (
+	styleNormal = iota
+	styleHeader
+	styleWrap
+)
+
+const stylesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="3">
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0" applyAlignment="1"><alignment vertical="top" wrapText="1"/></xf>
+</cellXfs>
+<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>
+</styleSheet>
+`
+
+const rootRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>
+`
+
+// WriteTo writes the workbook as an .xlsx file.
+func (wb *Workbook) WriteTo(w io.Writer) (int64, error) {
+	if len(wb.sheets) == 0 {
+		wb.AddSheet("Sheet1")
+	}
+
+	var b bytes.Buffer
+	z := zip.NewWriter(&b)
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"[Content_Types].xml", wb.contentTypes()},
+		{"_rels/.rels", []byte(rootRelsXML)},
+		{"xl/workbook.xml", wb.workbook()},
+		{"xl/_rels/workbook.xml.rels", wb.workbookRels()},
+		{"xl/styles.xml", []byte(stylesXML)},
+	}
+	for i, s := range wb.sheets {
+		files = append(files, struct {
+			name string
+			data []byte
+		}{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), s.xml()})
+	}
+	for _, f := range files {
+		// a fixed time keeps the output the same from one run to the next
+		fw, err := z.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)})
+		if err != nil {
+			return 0, err
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			return 0, err
+		}
+	}
+	if err := z.Close(); err != nil {
+		return 0, err
+	}
+	return b.WriteTo(w)
+}
+
+func (wb *Workbook) contentTypes() []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range wb.sheets {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	b.WriteString("</Types>\n")
+	return b.Bytes()
+}
+
+func (wb *Workbook) workbook() []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, s := range wb.sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(s.name), i+1, i+1)
+	}
+	b.WriteString("</sheets></workbook>\n")
+	return b.Bytes()
+}
+
+func (wb *Workbook) workbookRels() []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range wb.sheets {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(wb.sheets)+1)
+	b.WriteString("</Relationships>\n")
+	return b.Bytes()
+}
+
+// widths returns the width of every column, from Widths or else from the
+// longest line in it.
+func (s *Sheet) widths() []float64 {
+	var out []float64
+	fit := func(col int, v any) {
+		for len(out) <= col {
+			out = append(out, minWidth)
+		}
+		for _, line := range strings.Split(cellText(v), "\n") {
+			w := float64(utf8.RuneCountInString(line)) + 2
+			out[col] = min(max(out[col], w), DefaultMaxWidth)
+		}
+	}
+	for i, h := range s.header {
+		fit(i, h)
+	}
+	for _, row := range s.rows {
+		for i, v := range row {
+			fit(i, v)
+		}
+	}
+	for col, w := range s.Widths {
+		for len(out) <= col {
+			out = append(out, minWidth)
+		}
+		out[col] = w
+	}
+	return out
+}
+
+func (s *Sheet) xml() []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(s.header) > 0 {
+		b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+	if widths := s.widths(); len(widths) > 0 {
+		b.WriteString("<cols>")
+		for i, w := range widths {
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%s" customWidth="1"/>`, i+1, i+1, strconv.FormatFloat(w, 'f', -1, 64))
+		}
+		b.WriteString("</cols>")
+	}
+
+	b.WriteString("<sheetData>")
+	row := 0
+	writeRow := func(cells []any, style int) {
+		row++
+		fmt.Fprintf(&b, `<row r="%d">`, row)
+		for i, v := range cells {
+			writeCell(&b, CellName(i, row), v, style)
+		}
+		b.WriteString("</row>")
+	}
+	if len(s.header) > 0 {
+		cells := make([]any, len(s.header))
+		for i, h := range s.header {
+			cells[i] = h
+		}
+		writeRow(cells, styleHeader)
+	}
+	for _, cells := range s.rows {
+		writeRow(cells, styleNormal)
+	}
+	b.WriteString("</sheetData></worksheet>\n")
+	return b.Bytes()
+}
+
+func writeCell(b *bytes.Buffer, ref string, v any, style int) {
+	switch v := v.(type) {
+	case nil:
+		return
+	case int:
+		fmt.Fprintf(b, `<c r="%s"><v>%d</v></c>`, ref, v)
+		return
+	case float64:
+		fmt.Fprintf(b, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'g', -1, 64))
+		return
+	}
+
+	text := cellText(v)
+	if len(text) == 0 {
+		return
+	}
+	if style == styleNormal && strings.Contains(text, "\n") {
+		style = styleWrap
+	}
+	fmt.Fprintf(b, `<c r="%s"`, ref)
+	if style != styleNormal {
+		fmt.Fprintf(b, ` s="%d"`, style)
+	}
+	fmt.Fprintf(b, ` t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, escape(text))
+}
+
+func cellText(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	// EscapeText writes line breaks as character references, which Excel
+	// reads back as line breaks all the same
+	return b.String()
+}
+
+// CellName returns the A1 style name of the cell in column col, counting
+// from 0, and row, counting from 1.
+func CellName(col, row int) string {
+	var name []byte
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = append([]byte{byte('A' + (col-1)%26)}, name...)
+	}
+	return string(name) + strconv.Itoa(row)
+}
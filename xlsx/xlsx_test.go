@@ -0,0 +1,121 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestCellName(t *testing.T) {
+	tests := []struct {
+		Col, Row int
+		Want     string
+	}{
+		{0, 1, "A1"},
+		{25, 2, "Z2"},
+		{26, 3, "AA3"},
+		{27, 10, "AB10"},
+		{701, 1, "ZZ1"},
+		{702, 1, "AAA1"},
+	}
+
+	for _, test := range tests {
+		if got := CellName(test.Col, test.Row); got != test.Want {
+			t.Errorf("CellName(%d, %d) got: %v, want: %v", test.Col, test.Row, got, test.Want)
+		}
+	}
+}
+
+// readZip returns the contents of every file in an xlsx file.
+func readZip(t *testing.T, b []byte) map[string]string {
+	t.Helper()
+	z, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		files[f.Name] = string(data)
+	}
+	return files
+}
+
+func TestWriteTo(t *testing.T) {
+	var wb Workbook
+	s := wb.AddSheet("Households")
+	s.SetHeader("Name", "Count", "Address")
+	s.AddRow("Homer & Marge <Simpson>", 2, "742 Evergreen Terrace\nSPRINGFIELD, IL 62701")
+	s.AddRow("Krusty", 1.5, nil, "")
+	s.Widths = map[int]float64{2: 30}
+	if got := wb.AddSheet("Skipped: contacts / [all of them] and then some").Name(); got != "Skipped- contacts - -all of the" {
+		t.Errorf("unexpected sheet name, got: %q", got)
+	}
+
+	var b bytes.Buffer
+	if _, err := wb.WriteTo(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files := readZip(t, b.Bytes())
+
+	var names []string
+	for name, data := range files {
+		names = append(names, name)
+		if err := xml.Unmarshal([]byte(data), new(struct{})); err != nil {
+			t.Errorf("%s is not well formed: %v", name, err)
+		}
+	}
+	want := []string{
+		"[Content_Types].xml",
+		"_rels/.rels",
+		"xl/_rels/workbook.xml.rels",
+		"xl/styles.xml",
+		"xl/workbook.xml",
+		"xl/worksheets/sheet1.xml",
+		"xl/worksheets/sheet2.xml",
+	}
+	if diff := cmp.Diff(want, names, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+		t.Errorf("files mismatch (-want +got):\n%s", diff)
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`,
+		`<col min="1" max="1" width="25" customWidth="1"/>`,
+		`<col min="2" max="2" width="8" customWidth="1"/>`,
+		`<col min="3" max="3" width="30" customWidth="1"/>`,
+		`<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">Name</t></is></c>`,
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">Homer &amp; Marge &lt;Simpson&gt;</t></is></c>`,
+		`<c r="B2"><v>2</v></c>`,
+		`<c r="C2" s="2" t="inlineStr"><is><t xml:space="preserve">742 Evergreen Terrace&#xA;SPRINGFIELD, IL 62701</t></is></c>`,
+		`<row r="3"><c r="A3" t="inlineStr"><is><t xml:space="preserve">Krusty</t></is></c><c r="B3"><v>1.5</v></c></row>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet1.xml is missing %s", want)
+		}
+	}
+	if !strings.Contains(files["xl/workbook.xml"], `<sheet name="Households" sheetId="1" r:id="rId1"/>`) {
+		t.Errorf("workbook.xml is missing the Households sheet")
+	}
+
+	var again bytes.Buffer
+	if _, err := wb.WriteTo(&again); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(b.Bytes(), again.Bytes()) {
+		t.Errorf("output differs from one run to the next")
+	}
+}